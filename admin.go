@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// maxImportSize caps the file an Import can upload, however it is chunked.
+const maxImportSize = 64 << 20
+
+// adminServer implements the Admin gRPC service: the operational controls
+// that are otherwise only available over telnet.
+type adminServer struct {
+	queue      *farmQueue
+	statsQueue chan farmResult
+	redisdb    zAddNXer
+}
+
+func newAdminServer(queue *farmQueue, statsQueue chan farmResult, redisdb zAddNXer) *adminServer {
+	return &adminServer{queue: queue, statsQueue: statsQueue, redisdb: redisdb}
+}
+
+// Enqueue queues each valid farm id, reporting the ones it rejected. If the
+// queue is full and ctx ends before every farm is queued it fails, naming
+// the farms that made it.
+func (a *adminServer) Enqueue(ctx context.Context, req *pb.EnqueueRequest) (*pb.EnqueueResponse, error) {
+	res := &pb.EnqueueResponse{}
+	for _, id := range req.Ids {
+		farmID, err := parseFarmInput(id)
+		if err != nil {
+			res.Rejected = append(res.Rejected, &pb.RejectedFarmID{Id: id, Reason: err.Error()})
+			continue
+		}
+		if err := enqueue(ctx, a.queue, laneInteractive, farmID); err != nil {
+			code := codes.Unavailable
+			if err == context.DeadlineExceeded {
+				code = codes.DeadlineExceeded
+			}
+			retry := tracker.retryAfter(a.queue.ahead(laneInteractive))
+			st := grpcstatus.Newf(code, "queue is full, queued %v before giving up at %s, retry after %v", res.Queued, farmID, retry)
+			return nil, withDetails(st, retryInfo(retry))
+		}
+		res.Queued = append(res.Queued, string(farmID))
+	}
+	return res, nil
+}
+
+func (a *adminServer) FetchRecents(ctx context.Context, req *pb.FetchRecentsRequest) (*pb.EnqueueResponse, error) {
+	farmIDs, err := fetchRecents(ctx, a.queue)
+	if err != nil {
+		return nil, grpcstatus.Errorf(codes.Unavailable, "cannot fetch recent farms: %v", err)
+	}
+	res := &pb.EnqueueResponse{}
+	for _, farmID := range farmIDs {
+		res.Queued = append(res.Queued, string(farmID))
+	}
+	return res, nil
+}
+
+// Import queues the farms in a file uploaded in chunks, streaming progress
+// once the whole file has arrived.
+func (a *adminServer) Import(stream pb.Admin_ImportServer) error {
+	var data bytes.Buffer
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if data.Len()+len(req.Data) > maxImportSize {
+			return grpcstatus.Errorf(codes.ResourceExhausted, "imports are limited to %d bytes", maxImportSize)
+		}
+		data.Write(req.Data)
+	}
+
+	entries, err := parseImport(data.Bytes())
+	if err != nil {
+		return grpcstatus.Error(codes.InvalidArgument, err.Error())
+	}
+	var sendErr error
+	_, err = importFarms(stream.Context(), a.queue, entries, func(p importProgress, rejected []rejectedEntry, done bool) {
+		if sendErr != nil {
+			return
+		}
+		msg := &pb.ImportProgress{
+			Entries:    uint32(p.Entries),
+			Processed:  uint32(p.Processed),
+			Queued:     uint32(p.Queued),
+			Duplicates: uint32(p.Duplicates),
+			Done:       done,
+		}
+		for _, r := range rejected {
+			msg.Rejected = append(msg.Rejected, &pb.RejectedFarmID{Id: r.Entry, Reason: r.Reason})
+		}
+		sendErr = stream.Send(msg)
+	})
+	if err != nil {
+		return grpcstatus.FromContextError(err).Err()
+	}
+	return sendErr
+}
+
+// StartSpider starts a spider in the background and returns straight away.
+// Spiders outlive the request, so they get a context of their own.
+func (a *adminServer) StartSpider(ctx context.Context, req *pb.StartSpiderRequest) (*pb.SpiderStatus, error) {
+	spiderCtx := withReqID(context.Background(), reqIDFromContext(ctx))
+	switch req.Mode {
+	case pb.StartSpiderRequest_HOMEPAGE:
+		go fetchMany(spiderCtx, a.queue, a.redisdb)
+	case pb.StartSpiderRequest_PAGE:
+		go spiderPage(spiderCtx, a.queue, a.redisdb, int(req.Page))
+	case pb.StartSpiderRequest_ALL:
+		startSpiderAll(spiderCtx, a.redisdb, int(req.Page))
+	default:
+		return nil, grpcstatus.Errorf(codes.InvalidArgument, "unknown spider mode %d", req.Mode)
+	}
+	return a.GetSpiderStatus(ctx, &pb.SpiderStatusRequest{})
+}
+
+func (a *adminServer) StopSpiders(ctx context.Context, req *pb.StopSpidersRequest) (*pb.SpiderStatus, error) {
+	status.requestStop()
+	return a.GetSpiderStatus(ctx, &pb.SpiderStatusRequest{})
+}
+
+func (a *adminServer) GetSpiderStatus(ctx context.Context, req *pb.SpiderStatusRequest) (*pb.SpiderStatus, error) {
+	running, stopping := status.running()
+	return &pb.SpiderStatus{Running: uint32(running), Stopping: stopping}, nil
+}
+
+func (a *adminServer) GetQueueStatus(ctx context.Context, req *pb.QueueStatusRequest) (*pb.QueueStatus, error) {
+	st := &pb.QueueStatus{
+		Farms:         uint32(a.queue.len()),
+		FarmsCapacity: uint32(a.queue.cap()),
+		Stats:         uint32(len(a.statsQueue)),
+		StatsCapacity: uint32(cap(a.statsQueue)),
+	}
+	for l := lane(0); l < numLanes; l++ {
+		st.Lanes = append(st.Lanes, &pb.QueueLane{
+			Name:     l.String(),
+			Farms:    uint32(len(a.queue.lane(l))),
+			Capacity: uint32(cap(a.queue.lane(l))),
+			Weight:   uint32(laneWeights[l]),
+		})
+	}
+	return st, nil
+}
+
+func (a *adminServer) GetWorkers(ctx context.Context, req *pb.WorkersRequest) (*pb.WorkerPool, error) {
+	if workers == nil {
+		return nil, grpcstatus.Error(codes.Unavailable, "worker pool not running")
+	}
+	return workerPoolProto(workers), nil
+}
+
+// SetWorkers pins the pool at req.Size workers, or with req.Auto lets it
+// autoscale again.
+func (a *adminServer) SetWorkers(ctx context.Context, req *pb.SetWorkersRequest) (*pb.WorkerPool, error) {
+	if workers == nil {
+		return nil, grpcstatus.Error(codes.Unavailable, "worker pool not running")
+	}
+	if req.Auto {
+		workers.autoscale()
+	} else if err := workers.resize(int(req.Size)); err != nil {
+		return nil, grpcstatus.Error(codes.InvalidArgument, err.Error())
+	}
+	return workerPoolProto(workers), nil
+}
+
+func workerPoolProto(p *workerPool) *pb.WorkerPool {
+	min, max, pinned := p.bounds()
+	res := &pb.WorkerPool{
+		Min:       uint32(min),
+		Max:       uint32(max),
+		Pinned:    pinned,
+		PerMinute: uint32(p.throughput()),
+	}
+	for _, info := range p.infos() {
+		res.Workers = append(res.Workers, &pb.Worker{
+			Id:        uint32(info.ID),
+			State:     info.State.String(),
+			FarmId:    string(info.FarmID),
+			Since:     info.Since.Unix(),
+			Processed: uint32(info.Processed),
+			Failed:    uint32(info.Failed),
+			PerMinute: info.PerMinute,
+		})
+	}
+	res.Size = uint32(len(res.Workers))
+	return res
+}
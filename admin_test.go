@@ -0,0 +1,147 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAdmin(t *testing.T) {
+	Convey("Given an admin server", t, func() {
+		queue := newFarmQueue(10)
+		admin := newAdminServer(queue, make(chan farmResult, 5), nil)
+
+		Convey("valid farm ids are queued and the rest rejected", func() {
+			res, err := admin.Enqueue(context.Background(), &pb.EnqueueRequest{Ids: []string{"1AAAAA", "2BBBBB", "https://upload.farm/1CCCCC", "1CCCCCxyz"}})
+			So(err, ShouldBeNil)
+			So(res.Queued, ShouldResemble, []string{"1AAAAA", "1CCCCC"})
+			So(len(res.Rejected), ShouldEqual, 2)
+			So(res.Rejected[0].Id, ShouldEqual, "2BBBBB")
+			So(res.Rejected[0].Reason, ShouldContainSubstring, "must start with 1")
+			So(res.Rejected[1].Reason, ShouldContainSubstring, "must be 6 characters")
+
+			Convey("...and show up in the queue status", func() {
+				st, err := admin.GetQueueStatus(context.Background(), &pb.QueueStatusRequest{})
+				So(err, ShouldBeNil)
+				So(st.Farms, ShouldEqual, 2)
+				So(st.FarmsCapacity, ShouldEqual, 40)
+				So(st.Lanes[0].Name, ShouldEqual, "interactive")
+				So(st.Lanes[0].Farms, ShouldEqual, 2)
+				So(st.Lanes[0].Capacity, ShouldEqual, 10)
+				So(st.StatsCapacity, ShouldEqual, 5)
+			})
+		})
+
+		Convey("the worker pool can be listed and resized", func() {
+			_, err := admin.GetWorkers(context.Background(), &pb.WorkersRequest{})
+			So(grpcstatus.Code(err), ShouldEqual, codes.Unavailable)
+
+			pool, _ := newWorkerPool(queue, make(chan farmResult, 1), workersConfig{Min: 1, Max: 3})
+			workers = pool
+			defer func() {
+				pool.mu.Lock()
+				pool.resizeLocked(0)
+				pool.mu.Unlock()
+				workers = nil
+			}()
+			res, err := admin.SetWorkers(context.Background(), &pb.SetWorkersRequest{Size: 2})
+			So(err, ShouldBeNil)
+			So(res.Size, ShouldEqual, 2)
+			So(res.Pinned, ShouldBeTrue)
+			So(res.Workers[0].State, ShouldEqual, "idle")
+
+			_, err = admin.SetWorkers(context.Background(), &pb.SetWorkersRequest{Size: 0})
+			So(grpcstatus.Code(err), ShouldEqual, codes.InvalidArgument)
+
+			res, err = admin.SetWorkers(context.Background(), &pb.SetWorkersRequest{Auto: true})
+			So(err, ShouldBeNil)
+			So(res.Pinned, ShouldBeFalse)
+			So(res.Min, ShouldEqual, 1)
+			So(res.Max, ShouldEqual, 3)
+		})
+
+		Convey("stopping spiders is reported in their status", func() {
+			status.numRunning, status.stop = 2, false
+			defer func() { status.numRunning, status.stop = 0, false }()
+
+			st, err := admin.StopSpiders(context.Background(), &pb.StopSpidersRequest{})
+			So(err, ShouldBeNil)
+			So(st.Running, ShouldEqual, 2)
+			So(st.Stopping, ShouldBeTrue)
+		})
+
+		Convey("unknown spider modes are rejected", func() {
+			_, err := admin.StartSpider(context.Background(), &pb.StartSpiderRequest{Mode: 7})
+			So(grpcstatus.Code(err), ShouldEqual, codes.InvalidArgument)
+		})
+
+		Convey("enqueueing onto a full queue fails once ctx ends", func() {
+			full := newAdminServer(newFarmQueue(1), nil, nil)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			_, err := full.Enqueue(ctx, &pb.EnqueueRequest{Ids: []string{"1AAAAA", "1BBBBB"}})
+			So(grpcstatus.Code(err), ShouldEqual, codes.DeadlineExceeded)
+			So(err.Error(), ShouldContainSubstring, "queued [1AAAAA] before giving up at 1BBBBB")
+
+			ctx, cancel = context.WithCancel(context.Background())
+			cancel()
+			_, err = full.Enqueue(ctx, &pb.EnqueueRequest{Ids: []string{"1CCCCC"}})
+			So(grpcstatus.Code(err), ShouldEqual, codes.Unavailable)
+		})
+
+		Convey("imports stream their progress", func() {
+			stream := &fakeImportStream{ctx: context.Background(), chunks: []string{"1AAAAA\n2BB", "BBB\n1AA", "AAA\n"}}
+			err := admin.Import(stream)
+			So(err, ShouldBeNil)
+			So(stream.sent, ShouldHaveLength, 1)
+			progress := stream.sent[0]
+			So(progress.Done, ShouldBeTrue)
+			So(progress.Queued, ShouldEqual, 1)
+			So(progress.Duplicates, ShouldEqual, 1)
+			So(progress.Rejected[0].Id, ShouldEqual, "2BBBBB")
+
+			err = admin.Import(&fakeImportStream{ctx: context.Background(), chunks: []string{"[1AAAAA"}})
+			So(grpcstatus.Code(err), ShouldEqual, codes.InvalidArgument)
+
+			big := strings.Repeat("1AAAAA\n", maxImportSize/7+1)
+			err = admin.Import(&fakeImportStream{ctx: context.Background(), chunks: []string{big}})
+			So(grpcstatus.Code(err), ShouldEqual, codes.ResourceExhausted)
+		})
+	})
+}
+
+// fakeImportStream uploads chunks and collects the progress an import
+// sends.
+type fakeImportStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	chunks []string
+	sent   []*pb.ImportProgress
+}
+
+func (s *fakeImportStream) Recv() (*pb.ImportRequest, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return &pb.ImportRequest{Data: []byte(chunk)}, nil
+}
+
+func (s *fakeImportStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeImportStream) Send(p *pb.ImportProgress) error {
+	s.sent = append(s.sent, p)
+	return nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// role is what a caller may do. Each role can do everything the roles below
+// it can.
+type role int
+
+const (
+	roleNone role = iota
+	roleRead
+	roleAdmin
+)
+
+func (r role) String() string {
+	switch r {
+	case roleRead:
+		return "read"
+	case roleAdmin:
+		return "admin"
+	}
+	return "none"
+}
+
+func parseRole(name string) (role, error) {
+	switch name {
+	case "read":
+		return roleRead, nil
+	case "admin":
+		return roleAdmin, nil
+	}
+	return roleNone, fmt.Errorf("unknown role [%s]", name)
+}
+
+// tokenConfig names an API token and its role. Only the token's sha256 hash
+// is kept in the config; `farmstats -hash-token <token>` prints it.
+type tokenConfig struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+	Role string `json:"role"`
+}
+
+// authConfig lists the API tokens. With none, authentication is off and
+// every caller is an admin.
+type authConfig struct {
+	Tokens []tokenConfig `json:"tokens"`
+}
+
+// principal is who a caller authenticated as.
+type principal struct {
+	Name string
+	Role role
+}
+
+var anonymous = principal{Name: "anonymous"}
+
+type authenticator struct {
+	tokens map[string]principal
+}
+
+// auth starts out disabled so that tests, and servers without tokens, are
+// open.
+var auth = &authenticator{}
+
+func hashToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
+
+func newAuthenticator(cfg authConfig) (*authenticator, error) {
+	a := &authenticator{tokens: make(map[string]principal)}
+	for _, t := range cfg.Tokens {
+		r, err := parseRole(t.Role)
+		if err != nil {
+			return nil, fmt.Errorf("token %s: %v", t.Name, err)
+		}
+		hash := strings.ToLower(t.Hash)
+		if len(hash) != sha256.Size*2 {
+			return nil, fmt.Errorf("token %s: hash should be %d hex digits", t.Name, sha256.Size*2)
+		}
+		a.tokens[hash] = principal{Name: t.Name, Role: r}
+	}
+	return a, nil
+}
+
+func (a *authenticator) enabled() bool {
+	return len(a.tokens) > 0
+}
+
+// authenticate returns who token belongs to. An empty token is anonymous;
+// an unknown one is an error.
+func (a *authenticator) authenticate(token string) (principal, error) {
+	if !a.enabled() {
+		return principal{Name: anonymous.Name, Role: roleAdmin}, nil
+	}
+	if token == "" {
+		return anonymous, nil
+	}
+	p, ok := a.tokens[hashToken(token)]
+	if !ok {
+		return anonymous, fmt.Errorf("invalid token")
+	}
+	return p, nil
+}
+
+// authorize reports whether p may perform action, which needs the given
+// role. Admin actions and refusals are written to the audit log.
+func authorize(reqID string, p principal, action string, need role) bool {
+	allowed := p.Role >= need
+	if need == roleAdmin || !allowed {
+		audit(reqID, p, action, allowed)
+	}
+	return allowed
+}
+
+func audit(reqID string, p principal, action string, allowed bool) {
+	entry := log.WithFields(log.Fields{
+		"audit":     true,
+		"reqID":     reqID,
+		"principal": p.Name,
+		"role":      p.Role.String(),
+		"action":    action,
+		"allowed":   allowed,
+	})
+	if allowed {
+		entry.Info("admin action")
+	} else {
+		entry.Warn("action refused")
+	}
+}
+
+type principalKey struct{}
+
+func withPrincipal(ctx context.Context, p principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func principalFromContext(ctx context.Context) principal {
+	p, ok := ctx.Value(principalKey{}).(principal)
+	if !ok {
+		return anonymous
+	}
+	return p
+}
+
+// bearerToken takes the token from an "Authorization: Bearer <token>" value.
+func bearerToken(header string) string {
+	const prefix = "bearer "
+	if len(header) < len(prefix) || strings.ToLower(header[:len(prefix)]) != prefix {
+		return ""
+	}
+	return strings.TrimSpace(header[len(prefix):])
+}
+
+// grpcMethodRole is the role needed to call a gRPC method: the Admin
+// service needs admin, health checks need nothing, and the rest need read.
+func grpcMethodRole(method string) role {
+	switch {
+	case strings.HasPrefix(method, "/farmstats.Admin/"):
+		return roleAdmin
+	case strings.HasPrefix(method, "/grpc.health.v1.Health/"):
+		return roleNone
+	}
+	return roleRead
+}
+
+func grpcAuthorize(ctx context.Context, method string) (context.Context, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = bearerToken(values[0])
+		}
+	}
+	p, err := auth.authenticate(token)
+	if err != nil {
+		audit(reqIDFromContext(ctx), p, method, false)
+		return ctx, grpcstatus.Error(codes.Unauthenticated, err.Error())
+	}
+	if !authorize(reqIDFromContext(ctx), p, method, grpcMethodRole(method)) {
+		if p == anonymous {
+			return ctx, grpcstatus.Error(codes.Unauthenticated, "token required")
+		}
+		return ctx, grpcstatus.Errorf(codes.PermissionDenied, "%s needs the %s role", method, grpcMethodRole(method))
+	}
+	return withPrincipal(ctx, p), nil
+}
+
+// grpcAuth checks the bearer token in the authorization metadata.
+func grpcAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := grpcAuthorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// grpcStreamAuth is grpcAuth for streaming calls.
+func grpcStreamAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := grpcAuthorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, withStreamContext(ss, ctx))
+}
+
+// contextStream is a server stream whose handler sees a different context,
+// so stream interceptors can pass values on as unary ones do.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func withStreamContext(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &contextStream{ServerStream: ss, ctx: ctx}
+}
+
+// requireRole raises the role apiAuth asks for on routes that need more
+// than their method implies, such as reads of the whole dataset.
+func requireRole(need role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := principalFromContext(r.Context())
+			if !authorize(reqIDFromContext(r.Context()), p, r.Method+" "+r.URL.Path, need) {
+				http.Error(w, fmt.Sprintf("needs the %s role", need), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// apiAuth protects the HTTP API: reads need the read role and anything else
+// needs admin.
+func apiAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		need := roleAdmin
+		if r.Method == "GET" || r.Method == "HEAD" {
+			need = roleRead
+		}
+		reqID := reqIDFromContext(r.Context())
+		action := r.Method + " " + r.URL.Path
+
+		p, err := auth.authenticate(bearerToken(r.Header.Get("Authorization")))
+		if err != nil {
+			audit(reqID, p, action, false)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !authorize(reqID, p, action, need) {
+			if p == anonymous {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "token required", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, fmt.Sprintf("needs the %s role", need), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), p)))
+	})
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/sirupsen/logrus/hooks/test"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func testAuthenticator() *authenticator {
+	a, err := newAuthenticator(authConfig{Tokens: []tokenConfig{
+		{Name: "dashboard", Hash: hashToken("read-secret"), Role: "read"},
+		{Name: "ops", Hash: hashToken("admin-secret"), Role: "admin"},
+	}})
+	So(err, ShouldBeNil)
+	return a
+}
+
+func TestAuth(t *testing.T) {
+	Convey("Tokens are checked against their hashes", t, func() {
+		_, err := newAuthenticator(authConfig{Tokens: []tokenConfig{{Name: "x", Hash: hashToken("x"), Role: "root"}}})
+		So(err, ShouldNotBeNil)
+		_, err = newAuthenticator(authConfig{Tokens: []tokenConfig{{Name: "x", Hash: "abc", Role: "read"}}})
+		So(err, ShouldNotBeNil)
+
+		open := &authenticator{}
+		p, err := open.authenticate("")
+		So(err, ShouldBeNil)
+		So(p.Role, ShouldEqual, roleAdmin)
+
+		a := testAuthenticator()
+		p, err = a.authenticate("")
+		So(err, ShouldBeNil)
+		So(p, ShouldResemble, anonymous)
+		_, err = a.authenticate("guess")
+		So(err, ShouldNotBeNil)
+		p, err = a.authenticate("read-secret")
+		So(err, ShouldBeNil)
+		So(p, ShouldResemble, principal{Name: "dashboard", Role: roleRead})
+	})
+
+	Convey("Given authentication is on", t, func() {
+		auth = testAuthenticator()
+		defer func() { auth = &authenticator{} }()
+		hook := test.NewGlobal()
+		defer hook.Reset()
+
+		Convey("the HTTP API needs read for GET and admin for the rest", func() {
+			h := apiAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, principalFromContext(r.Context()).Name)
+			}))
+			call := func(method, token string) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(method, "/api/v1/loglevel", nil)
+				if token != "" {
+					r.Header.Set("Authorization", "Bearer "+token)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				return w
+			}
+
+			So(call("GET", "").Code, ShouldEqual, http.StatusUnauthorized)
+			So(call("GET", "wrong").Code, ShouldEqual, http.StatusUnauthorized)
+			So(call("GET", "read-secret").Body.String(), ShouldEqual, "dashboard")
+			So(call("PUT", "read-secret").Code, ShouldEqual, http.StatusForbidden)
+			So(hook.LastEntry().Data["allowed"], ShouldEqual, false)
+
+			So(call("PUT", "admin-secret").Code, ShouldEqual, http.StatusOK)
+			So(hook.LastEntry().Data["audit"], ShouldEqual, true)
+			So(hook.LastEntry().Data["principal"], ShouldEqual, "ops")
+			So(hook.LastEntry().Data["action"], ShouldEqual, "PUT /api/v1/loglevel")
+		})
+
+		Convey("some reads need more than the read role", func() {
+			h := apiAuth(requireRole(roleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+			call := func(token string) int {
+				r := httptest.NewRequest("GET", "/api/v1/snapshot", nil)
+				r.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				return w.Code
+			}
+			So(call("read-secret"), ShouldEqual, http.StatusForbidden)
+			So(call("admin-secret"), ShouldEqual, http.StatusOK)
+			So(hook.LastEntry().Data["action"], ShouldEqual, "GET /api/v1/snapshot")
+		})
+
+		Convey("gRPC methods need a token with the right role", func() {
+			call := func(method, token string) error {
+				ctx := context.Background()
+				if token != "" {
+					ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+				}
+				info := &grpc.UnaryServerInfo{FullMethod: method}
+				_, err := grpcAuth(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return nil, nil
+				})
+				return err
+			}
+
+			So(grpcstatus.Code(call("/farmstats.FarmStats/GetStats", "")), ShouldEqual, codes.Unauthenticated)
+			So(call("/farmstats.FarmStats/GetStats", "read-secret"), ShouldBeNil)
+			So(grpcstatus.Code(call("/farmstats.Admin/Enqueue", "read-secret")), ShouldEqual, codes.PermissionDenied)
+			So(call("/farmstats.Admin/Enqueue", "admin-secret"), ShouldBeNil)
+			So(call("/grpc.health.v1.Health/Check", ""), ShouldBeNil)
+		})
+
+		Convey("streaming calls get the caller, a request ID and a span", func() {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+				"authorization", "Bearer read-secret",
+				"x-request-id", "req-1",
+			))
+			ss := &fakeHeaderStream{ctx: ctx}
+			info := &grpc.StreamServerInfo{FullMethod: "/farmstats.FarmStats/WatchFarms", IsServerStream: true}
+			var seen context.Context
+			handler := func(srv interface{}, stream grpc.ServerStream) error {
+				seen = stream.Context()
+				return nil
+			}
+			chain := []grpc.StreamServerInterceptor{grpcStreamRequestID, grpcStreamTracing, grpcStreamMetrics, grpcStreamAuth}
+			for i := len(chain) - 1; i >= 0; i-- {
+				interceptor, next := chain[i], handler
+				handler = func(srv interface{}, stream grpc.ServerStream) error {
+					return interceptor(srv, stream, info, next)
+				}
+			}
+
+			So(handler(nil, ss), ShouldBeNil)
+			So(principalFromContext(seen).Name, ShouldEqual, "dashboard")
+			So(reqIDFromContext(seen), ShouldEqual, "req-1")
+			So(spanFromContext(seen).TraceID, ShouldNotBeEmpty)
+			So(ss.header.Get("x-request-id"), ShouldResemble, []string{"req-1"})
+			So(ss.header.Get(traceparentHeader), ShouldHaveLength, 1)
+		})
+
+		Convey("telnet commands need a /login first", func() {
+			commands := newTelnet(nil, nil).commands
+			So(commands["ping"].needs(nil), ShouldEqual, roleNone)
+			So(commands["qsize"].needs(nil), ShouldEqual, roleRead)
+			So(commands["spiderall"].needs([]string{"9999"}), ShouldEqual, roleAdmin)
+			So(commands["loglevel"].needs(nil), ShouldEqual, roleRead)
+			So(commands["loglevel"].needs([]string{"debug"}), ShouldEqual, roleAdmin)
+
+			queue := newFarmQueue(10)
+			go telnetServer("3339", queue, redis.NewClient(&redis.Options{Addr: ":6379"}))
+			time.Sleep(100 * time.Millisecond)
+			conn, err := net.Dial("tcp", "127.0.0.1:3339")
+			So(err, ShouldBeNil)
+			defer conn.Close()
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			r := bufio.NewReader(conn)
+			send := func(line string) string {
+				fmt.Fprintf(conn, "%s\n", line)
+				reply, _ := r.ReadString('\n')
+				return reply
+			}
+			r.ReadString('\n')
+
+			So(send("/qsize"), ShouldEqual, "/qsize needs the read role (/login <token>)\n")
+			So(send("/login nope"), ShouldEqual, "login failed\n")
+			So(send("/login read-secret"), ShouldEqual, "logged in as dashboard (read)\n")
+			So(send("/qsize"), ShouldEqual, "queue size is [0]: interactive 0, recents 0, backfill 0, refresh 0\n")
+			So(send("1AAAAA"), ShouldEqual, "1AAAAA needs the admin role (/login <token>)\n")
+			So(send("/login admin-secret"), ShouldEqual, "logged in as ops (admin)\n")
+			So(send("1AAAAA"), ShouldEqual, "queued farm id 1AAAAA\n")
+		})
+	})
+}
+
+// fakeHeaderStream records the headers interceptors set on a stream.
+type fakeHeaderStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *fakeHeaderStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeHeaderStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultBackfillInterval  = time.Second
+	defaultBackfillBatchSize = 100
+	backfillCursorKey        = "backfill:cursor:"
+)
+
+// backfillStore is the subset of redis used by the backfill worker, so tests
+// can supply their own `spidered` set.
+type backfillStore interface {
+	ZRangeByScoreWithScores(key string, opt redis.ZRangeBy) *redis.ZSliceCmd
+	ZRevRangeByScoreWithScores(key string, opt redis.ZRangeBy) *redis.ZSliceCmd
+	Get(key string) *redis.StringCmd
+	Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+}
+
+// backfiller walks the `spidered` sorted set and queues every farm we have
+// not stored yet. Its position is kept in redis so a restart carries on where
+// the last run stopped.
+type backfiller struct {
+	redisdb     backfillStore
+	queue       *farmQueue
+	newestFirst bool
+	interval    time.Duration
+	batchSize   int64
+
+	mu       sync.Mutex
+	running  bool
+	stop     chan struct{}
+	done     chan struct{}
+	lastErr  error
+	queued   int
+	skipped  int
+	lastSeen string
+}
+
+func newBackfiller(redisdb backfillStore, queue *farmQueue) *backfiller {
+	return &backfiller{
+		redisdb:   redisdb,
+		queue:     queue,
+		interval:  defaultBackfillInterval,
+		batchSize: defaultBackfillBatchSize,
+	}
+}
+
+func (b *backfiller) cursorKey() string {
+	if b.newestFirst {
+		return backfillCursorKey + "newest"
+	}
+	return backfillCursorKey + "oldest"
+}
+
+// cursor returns the score of the last farm handled, or "" if we have never
+// run in this direction.
+func (b *backfiller) cursor() (string, error) {
+	cursor, err := b.redisdb.Get(b.cursorKey()).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return cursor, err
+}
+
+// nextBatch reads the next farms after the cursor, in the configured order.
+func (b *backfiller) nextBatch(cursor string) ([]redis.Z, error) {
+	if b.newestFirst {
+		max := "+inf"
+		if cursor != "" {
+			max = "(" + cursor
+		}
+		return b.redisdb.ZRevRangeByScoreWithScores("spidered", redis.ZRangeBy{
+			Min: "-inf", Max: max, Count: b.batchSize,
+		}).Result()
+	}
+
+	min := "-inf"
+	if cursor != "" {
+		min = "(" + cursor
+	}
+	return b.redisdb.ZRangeByScoreWithScores("spidered", redis.ZRangeBy{
+		Min: min, Max: "+inf", Count: b.batchSize,
+	}).Result()
+}
+
+// start runs the backfill in the background. It returns an error if a
+// backfill is already running.
+func (b *backfiller) start(newestFirst bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.running {
+		return fmt.Errorf("backfill already running")
+	}
+	b.running = true
+	b.newestFirst = newestFirst
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	go func(stop, done chan struct{}) {
+		err := b.run(stop)
+		if err != nil {
+			log.Warnf("backfill stopped: %v", err)
+		}
+		b.mu.Lock()
+		b.running = false
+		b.lastErr = err
+		b.mu.Unlock()
+		close(done)
+	}(b.stop, b.done)
+	return nil
+}
+
+// job runs an oldest-first backfill to completion for the scheduler.
+func (b *backfiller) job(stop <-chan struct{}) error {
+	err := b.start(false)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	done := b.done
+	b.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-stop:
+		b.halt()
+		<-done
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastErr
+}
+
+// halt asks a running backfill to stop, returning false if none was running.
+func (b *backfiller) halt() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.running {
+		return false
+	}
+	select {
+	case <-b.stop:
+	default:
+		close(b.stop)
+	}
+	return true
+}
+
+// run queues farms until the set is exhausted or stop is closed.
+func (b *backfiller) run(stop chan struct{}) error {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	reqID := newReqID("backfill")
+	logger := log.WithField("reqID", reqID)
+	ctx := withReqID(context.Background(), reqID)
+
+	for {
+		cursor, err := b.cursor()
+		if err != nil {
+			return err
+		}
+		batch, err := b.nextBatch(cursor)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			logger.Infof("backfill finished at [%s]", cursor)
+			return nil
+		}
+
+		for _, z := range batch {
+			member, _ := z.Member.(string)
+			farmID, err := ParseFarmID(member)
+			if err != nil {
+				logger.Warnf("skipping spidered farm: %v", err)
+			}
+
+			allFarms.mu.Lock()
+			_, known := allFarms.stats[member]
+			allFarms.mu.Unlock()
+
+			if known || err != nil {
+				b.mu.Lock()
+				b.skipped++
+				b.mu.Unlock()
+			} else {
+				select {
+				case <-stop:
+					logger.Info("backfill received stop signal")
+					return nil
+				case <-ticker.C:
+				}
+				req, s := newFarmRequest(ctx, farmID)
+				tracker.markQueued(farmID, laneBackfill)
+				select {
+				case <-stop:
+					tracker.unqueue(farmID)
+					logger.Info("backfill received stop signal")
+					return nil
+				case b.queue.lane(laneBackfill) <- req:
+					s.finish(nil)
+					req.logger().Debug("queued farm")
+				}
+				b.mu.Lock()
+				b.queued++
+				b.mu.Unlock()
+			}
+
+			score := strconv.FormatFloat(z.Score, 'f', -1, 64)
+			err = b.redisdb.Set(b.cursorKey(), score, 0).Err()
+			if err != nil {
+				return err
+			}
+			b.mu.Lock()
+			b.lastSeen = member
+			b.mu.Unlock()
+		}
+	}
+}
+
+func (b *backfiller) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := "idle"
+	if b.running {
+		state = "running"
+	}
+	order := "oldest first"
+	if b.newestFirst {
+		order = "newest first"
+	}
+	return fmt.Sprintf("backfill %s (%s): queued %d, skipped %d, last farm [%s]",
+		state, order, b.queued, b.skipped, b.lastSeen)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeSpidered is an in-memory `spidered` set, kept sorted by score.
+type fakeSpidered struct {
+	members []redis.Z
+	keys    map[string]string
+}
+
+func newFakeSpidered(farmIDs ...string) *fakeSpidered {
+	f := &fakeSpidered{keys: make(map[string]string)}
+	for _, farmID := range farmIDs {
+		score, _ := idToNum(farmID)
+		f.members = append(f.members, redis.Z{Score: float64(score), Member: farmID})
+	}
+	return f
+}
+
+func parseBound(bound string, inf float64) (float64, bool) {
+	if strings.HasSuffix(bound, "inf") {
+		return inf, false
+	}
+	if strings.HasPrefix(bound, "(") {
+		v, _ := strconv.ParseFloat(bound[1:], 64)
+		return v, true
+	}
+	v, _ := strconv.ParseFloat(bound, 64)
+	return v, false
+}
+
+func (f *fakeSpidered) inRange(z redis.Z, opt redis.ZRangeBy) bool {
+	min, minExcl := parseBound(opt.Min, -1)
+	max, maxExcl := parseBound(opt.Max, 1e18)
+	if z.Score < min || (minExcl && z.Score == min) {
+		return false
+	}
+	return z.Score < max || (!maxExcl && z.Score == max)
+}
+
+func (f *fakeSpidered) ZRangeByScoreWithScores(key string, opt redis.ZRangeBy) *redis.ZSliceCmd {
+	var zs []redis.Z
+	for _, z := range f.members {
+		if f.inRange(z, opt) && int64(len(zs)) < opt.Count {
+			zs = append(zs, z)
+		}
+	}
+	return redis.NewZSliceCmdResult(zs, nil)
+}
+
+func (f *fakeSpidered) ZRevRangeByScoreWithScores(key string, opt redis.ZRangeBy) *redis.ZSliceCmd {
+	var zs []redis.Z
+	for i := len(f.members) - 1; i >= 0; i-- {
+		z := f.members[i]
+		if f.inRange(z, opt) && int64(len(zs)) < opt.Count {
+			zs = append(zs, z)
+		}
+	}
+	return redis.NewZSliceCmdResult(zs, nil)
+}
+
+func (f *fakeSpidered) Get(key string) *redis.StringCmd {
+	v, ok := f.keys[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(v, nil)
+}
+
+func (f *fakeSpidered) Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	f.keys[key] = value.(string)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (f *fakeSpidered) ZAddNX(key string, members ...redis.Z) *redis.IntCmd {
+	added := 0
+	for _, z := range members {
+		i := sort.Search(len(f.members), func(i int) bool { return f.members[i].Score >= z.Score })
+		if i < len(f.members) && f.members[i].Member == z.Member {
+			continue
+		}
+		f.members = append(f.members, redis.Z{})
+		copy(f.members[i+1:], f.members[i:])
+		f.members[i] = z
+		added++
+	}
+	return redis.NewIntResult(int64(added), nil)
+}
+
+func (f *fakeSpidered) PoolStats() *redis.PoolStats {
+	return &redis.PoolStats{}
+}
+
+func TestBackfill(t *testing.T) {
+	Convey("Given a spidered set with one farm already stored", t, func() {
+		allFarms.stats = map[string]svStats{"1BC124": {FarmID: "1BC124"}}
+		store := newFakeSpidered("1BC123", "1BC124", "1BC125", "1BC126")
+		farmQueue := newFarmQueue(10)
+		b := newBackfiller(store, farmQueue)
+		queue := farmQueue.lane(laneBackfill)
+		b.interval = time.Millisecond
+		b.batchSize = 2
+
+		Convey("oldest first queues unknown farms in id order", func() {
+			err := b.run(make(chan struct{}))
+			So(err, ShouldBeNil)
+			So(len(queue), ShouldEqual, 3)
+			So((<-queue).FarmID, ShouldEqual, "1BC123")
+			So((<-queue).FarmID, ShouldEqual, "1BC125")
+			So((<-queue).FarmID, ShouldEqual, "1BC126")
+			So(b.skipped, ShouldEqual, 1)
+
+			Convey("...and a second run resumes from the saved cursor", func() {
+				err := b.run(make(chan struct{}))
+				So(err, ShouldBeNil)
+				So(len(queue), ShouldEqual, 0)
+			})
+		})
+
+		Convey("newest first queues unknown farms in reverse order", func() {
+			b.newestFirst = true
+			err := b.run(make(chan struct{}))
+			So(err, ShouldBeNil)
+			So((<-queue).FarmID, ShouldEqual, "1BC126")
+			So((<-queue).FarmID, ShouldEqual, "1BC125")
+			So((<-queue).FarmID, ShouldEqual, "1BC123")
+		})
+
+		Convey("a stopped backfill keeps its place", func() {
+			stop := make(chan struct{})
+			close(stop)
+			err := b.run(stop)
+			So(err, ShouldBeNil)
+			So(len(queue), ShouldEqual, 0)
+			cursor, _ := b.cursor()
+			So(cursor, ShouldEqual, "")
+		})
+	})
+}
@@ -1,51 +1,831 @@
 package main
 
 import (
-	"runtime"
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
-	log "github.com/sirupsen/logrus"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
 	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpcstatus "google.golang.org/grpc/status"
 )
 
+var (
+	serverAddr = flag.String("addr", "127.0.0.1:3334", "farmstats gRPC address")
+	httpAddr   = flag.String("http", "http://127.0.0.1:8080", "farmstats HTTP API address, for export, snapshot and restore")
+	useTLS     = flag.Bool("tls", false, "connect to the gRPC server over TLS")
+	caFile     = flag.String("ca", "", "CA certificate to verify the server with (implies -tls)")
+	certFile   = flag.String("cert", "", "client certificate, for servers that require one (implies -tls)")
+	keyFile    = flag.String("key", "", "client certificate's private key")
+	serverName = flag.String("server-name", "", "name to verify the server's certificate against")
+	token      = flag.String("token", os.Getenv("FARMSTATS_TOKEN"), "API token (default $FARMSTATS_TOKEN)")
+	timeout    = flag.Duration("timeout", 10*time.Second, "time allowed for each request")
+	format     = flag.String("format", "table", "output format: table, json or csv")
+)
+
+// importChunkSize is how much of a file each Import message carries.
+const importChunkSize = 1 << 20
+
+const usage = `usage: client [flags] <command> [args]
+
+commands:
+  get [-enqueue [-wait]] <farmID>...
+                         show the stats for farms; -enqueue fetches unknown
+                         farms, -wait waits up to -timeout for them
+  list [-page-size n] [-all]
+                         list stored farms in id order
+  watch                  show farms as they are stored
+  aggregate              summarise friendship levels across all farms
+  enqueue <farmID>...    queue farms to be fetched
+  import <file>          queue the farm ids or upload.farm URLs in a text,
+                         JSON or CSV file; - reads stdin
+  fetch                  queue the farms on the recent farms list
+  export [-format csv|jsonl|parquet] [-o file] [-since t] [-until t]
+         [-villager name -min-level n]
+                         download every stored farm, one villager per column
+  snapshot [-o file]     download a compressed snapshot of the farms, their
+                         history, the spidered set and backfill cursors
+  restore <file>         load a snapshot into the server; - reads stdin
+  image [-thumbnail] [-o file] <farmID>
+                         download the render of a farm, or its thumbnail
+  duplicates [-distance n] <farmID>
+                         list farms whose render looks like this farm's
+  spider                 queue the farms on the homepage listing
+  spider <page>          queue the farms on one page of the listing
+  spider all <page>      record every farm from that page back to the start
+  spider stop            stop any running multi-page spiders
+  spider status          show running spiders
+  queue                  show queue depths, with the farm queue by lane
+  workers [n|auto]       show what each farm worker is doing; n pins the
+                         pool at n workers, auto lets it autoscale again
+
+flags:
+`
+
 func main() {
-	log.SetLevel(log.DebugLevel)
-	log.Infof("Starting grpc client %s %d", "v1.0", runtime.GOMAXPROCS(0))
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
 
-	var opts []grpc.DialOption
-	opts = append(opts, grpc.WithInsecure())
-	serverAddr := "127.0.0.1:3334"
-	conn, err := grpc.Dial(serverAddr, opts...)
+	out, err := newPrinter(os.Stdout, *format)
+	if err != nil {
+		fatal(err)
+	}
+
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+	// these go through the HTTP API rather than gRPC
+	if httpCmd, ok := map[string]func([]string) error{
+		"export":     export,
+		"snapshot":   snapshot,
+		"restore":    restore,
+		"image":      image,
+		"duplicates": duplicates,
+	}[cmd]; ok {
+		err := httpCmd(args)
+		if err != nil {
+			fatal(err)
+		}
+		return
+	}
+	switch cmd {
+	case "get", "list", "watch", "aggregate", "enqueue", "import", "fetch", "spider", "queue", "workers":
+	default:
+		fatal(fmt.Errorf("unknown command [%s]", cmd))
+	}
+
+	conn, err := dial()
 	if err != nil {
-		log.Fatalf("fail to dial: %v", err)
+		fatal(err)
 	}
 	defer conn.Close()
 	client := pb.NewFarmStatsClient(conn)
+	admin := pb.NewAdminClient(conn)
+
+	switch cmd {
+	case "get":
+		err = getFarms(client, out, args)
+	case "list":
+		err = listFarms(client, out, args)
+	case "watch":
+		err = watchFarms(client, out)
+	case "aggregate":
+		err = aggregate(client, out)
+	case "enqueue":
+		err = enqueueFarms(admin, out, args)
+	case "import":
+		err = importFarms(admin, out, args)
+	case "fetch":
+		err = fetchRecents(admin, out)
+	case "spider":
+		err = spider(admin, out, args)
+	case "queue":
+		err = queueStatus(admin, out)
+	case "workers":
+		err = workerStatus(admin, out, args)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "client: %v\n", err)
+	os.Exit(1)
+}
+
+func dial() (*grpc.ClientConn, error) {
+	var opts []grpc.DialOption
+	if *useTLS || *caFile != "" || *certFile != "" {
+		cfg, err := clientTLS(*caFile, *certFile, *keyFile, *serverName)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+
+	if *token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(*token)))
+	}
 
-	// first req ~ 1.8ms
-	printStats(client, &pb.FarmID{Id: "1FkeUV"})
-	time.Sleep(2 * time.Second)
-	// subsequent reqs (with same connection?) ~ 0.6ms
-	printStats(client, &pb.FarmID{Id: "1FkeUV"})
-	printStats(client, &pb.FarmID{Id: "1FkeUV"})
-	printStats(client, &pb.FarmID{Id: "1FkeUV"})
-	printStats(client, &pb.FarmID{Id: "1FkeUV"})
-	printStats(client, &pb.FarmID{Id: "1FkeUV"})
-	printStats(client, &pb.FarmID{Id: "1FkeUV"})
-}
-
-// printFeature gets the feature for the given point.
-func printStats(client pb.FarmStatsClient, farmID *pb.FarmID) {
-	log.Infof("Getting feature for farm (%s)", farmID.Id)
-	t1 := time.Now()
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
-	stats, err := client.GetStats(ctx, farmID)
+	opts = append(opts, grpc.WithBlock())
+	return grpc.DialContext(ctx, *serverAddr, opts...)
+}
+
+// clientTLS verifies the server against caFile, or the system roots, and
+// presents the client certificate if one is given.
+func clientTLS(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// export downloads the farms from the HTTP API's export endpoint.
+func export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	exportFormat := fs.String("format", "csv", "csv, jsonl or parquet")
+	output := fs.String("o", "", "file to write to (default stdout)")
+	since := fs.String("since", "", "only farms fetched at or after this RFC 3339 time")
+	until := fs.String("until", "", "only farms fetched before this RFC 3339 time")
+	villager := fs.String("villager", "", "only farms where this villager is at least -min-level")
+	minLevel := fs.Uint("min-level", 0, "friendship level for -villager")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	q := url.Values{"format": {*exportFormat}}
+	for name, value := range map[string]string{"since": *since, "until": *until, "villager": *villager} {
+		if value != "" {
+			q.Set(name, value)
+		}
+	}
+	if *minLevel > 0 {
+		q.Set("minLevel", strconv.Itoa(int(*minLevel)))
+	}
+	res, err := apiRequest("GET", "/api/v1/export?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return download(res.Body, *output)
+}
+
+// snapshot downloads a snapshot of the server's whole dataset.
+func snapshot(args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	output := fs.String("o", "", "file to write to (default stdout)")
+	err := fs.Parse(args)
 	if err != nil {
-		log.Fatalf("%v.GetStats(_) = _, %v: ", client, err)
+		return err
+	}
+	res, err := apiRequest("GET", "/api/v1/snapshot", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return download(res.Body, *output)
+}
+
+// restore uploads a snapshot to the server.
+func restore(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("restore needs a snapshot file, or - for stdin")
+	}
+	var in io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	res, err := apiRequest("POST", "/api/v1/restore", in)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, err = io.Copy(os.Stderr, res.Body)
+	return err
+}
+
+// image downloads a farm's render, or its thumbnail, as a PNG.
+func image(args []string) error {
+	fs := flag.NewFlagSet("image", flag.ContinueOnError)
+	thumb := fs.Bool("thumbnail", false, "download the thumbnail instead")
+	output := fs.String("o", "", "file to write to (default stdout)")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("image needs a farm id")
+	}
+	kind := "image"
+	if *thumb {
+		kind = "thumbnail"
+	}
+	res, err := apiRequest("GET", "/api/v1/farms/"+url.PathEscape(fs.Arg(0))+"/"+kind, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return download(res.Body, *output)
+}
+
+// duplicates prints the farms whose render looks like a farm's, as JSON.
+func duplicates(args []string) error {
+	fs := flag.NewFlagSet("duplicates", flag.ContinueOnError)
+	distance := fs.Int("distance", -1, "most bits the hashes may differ by (default the server's)")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("duplicates needs a farm id")
+	}
+	path := "/api/v1/farms/" + url.PathEscape(fs.Arg(0)) + "/duplicates"
+	if *distance >= 0 {
+		path += "?distance=" + strconv.Itoa(*distance)
+	}
+	res, err := apiRequest("GET", path, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return download(res.Body, "")
+}
+
+// apiRequest calls the HTTP API, returning an error for anything but a 200.
+// Exports and snapshots can be large, so the -timeout only applies until
+// the response starts.
+func apiRequest(method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, strings.TrimRight(*httpAddr, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if *token != "" {
+		req.Header.Set("Authorization", "Bearer "+*token)
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ResponseHeaderTimeout: *timeout,
+	}
+	if *caFile != "" || *certFile != "" {
+		transport.TLSClientConfig, err = clientTLS(*caFile, *certFile, *keyFile, *serverName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("%s %s failed: %s: %s", method, req.URL.Path, res.Status, strings.TrimSpace(string(msg)))
+	}
+	return res, nil
+}
+
+// download copies body to the named file, or stdout.
+func download(body io.Reader, output string) error {
+	if output == "" {
+		_, err := io.Copy(os.Stdout, body)
+		return err
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// tokenCredentials sends the API token as a bearer token with every call.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+func getFarms(client pb.FarmStatsClient, out *printer, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	enqueue := fs.Bool("enqueue", false, "fetch farms the server has not stored")
+	wait := fs.Bool("wait", false, "with -enqueue, wait for the fetch to finish")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("get needs at least one farm id")
+	}
+	out.header(farmHeader)
+	for _, farmID := range fs.Args() {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		farm, err := client.GetStats(ctx, &pb.FarmID{Id: farmID, Enqueue: *enqueue, Wait: *wait})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("%s: %s", farmID, describeError(err))
+		}
+		out.row(farmRow(farm), farm)
+	}
+	return out.flush()
+}
+
+// describeError adds the details the server attached to a gRPC error, such
+// as when to retry or why a farm could not be read.
+func describeError(err error) string {
+	st, ok := grpcstatus.FromError(err)
+	if !ok {
+		return err.Error()
+	}
+	msg := fmt.Sprintf("%s (%s)", st.Message(), st.Code())
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.RetryInfo:
+			if delay, err := ptypes.Duration(d.RetryDelay); err == nil {
+				msg += fmt.Sprintf("; retry after %v", delay)
+			}
+		case *errdetails.ErrorInfo:
+			msg += fmt.Sprintf("; %s", d.Type)
+			if reason := d.Metadata["error"]; reason != "" {
+				msg += fmt.Sprintf(": %s", reason)
+			}
+		case *errdetails.BadRequest:
+			for _, v := range d.FieldViolations {
+				msg += fmt.Sprintf("; %s: %s", v.Field, v.Description)
+			}
+		}
+	}
+	return msg
+}
+
+func listFarms(client pb.FarmStatsClient, out *printer, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	pageSize := fs.Uint("page-size", 100, "farms per page")
+	all := fs.Bool("all", false, "fetch every page")
+	token := fs.String("page-token", "", "continue from a previous page")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	out.header(farmHeader)
+	req := &pb.ListFarmsRequest{PageSize: uint32(*pageSize), PageToken: *token}
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		page, err := client.ListFarms(ctx, req)
+		cancel()
+		if err != nil {
+			return err
+		}
+		for _, farm := range page.Farms {
+			out.row(farmRow(farm), farm)
+		}
+		if !*all || page.NextPageToken == "" {
+			if page.NextPageToken != "" {
+				fmt.Fprintf(os.Stderr, "%d farms stored; next page: -page-token %s\n", page.Total, page.NextPageToken)
+			}
+			break
+		}
+		req.PageToken = page.NextPageToken
+	}
+	return out.flush()
+}
+
+// watchFarms prints farms as they arrive. The timeout only applies to
+// setting up the stream.
+func watchFarms(client pb.FarmStatsClient, out *printer) error {
+	stream, err := client.WatchFarms(context.Background(), &pb.WatchFarmsRequest{})
+	if err != nil {
+		return err
+	}
+	out.header(farmHeader)
+	for {
+		farm, err := stream.Recv()
+		if err == io.EOF {
+			return out.flush()
+		}
+		if err != nil {
+			return err
+		}
+		out.row(farmRow(farm), farm)
+		err = out.flush()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func aggregate(client pb.FarmStatsClient, out *printer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	aggs, err := client.Aggregate(ctx, &pb.AggregateRequest{})
+	if err != nil {
+		return err
+	}
+	out.header([]string{"villager", "mean", "min", "max", "maxed"})
+	for _, v := range aggs.Villagers {
+		out.row([]string{
+			v.Villager,
+			strconv.FormatFloat(v.Mean, 'f', 2, 64),
+			strconv.Itoa(int(v.Min)),
+			strconv.Itoa(int(v.Max)),
+			strconv.Itoa(int(v.Maxed)),
+		}, v)
+	}
+	err = out.flush()
+	if err == nil && out.format == "table" {
+		fmt.Fprintf(out.w, "%d farms\n", aggs.Farms)
+	}
+	return err
+}
+
+// farmHeader names the columns of farmRow: the farm id, then each villager
+// in the order the server sends them.
+var farmHeader = func() []string {
+	header := []string{"id"}
+	t := reflect.TypeOf(pb.Farm{})
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type.Kind() == reflect.Uint32 {
+			header = append(header, strings.ToLower(t.Field(i).Name))
+		}
+	}
+	return header
+}()
+
+func farmRow(farm *pb.Farm) []string {
+	row := []string{farm.Id}
+	v := reflect.ValueOf(farm).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).Kind() == reflect.Uint32 {
+			row = append(row, strconv.FormatUint(v.Field(i).Uint(), 10))
+		}
+	}
+	return row
+}
+
+// printer writes rows as an aligned table, CSV, or one JSON object per line.
+type printer struct {
+	w      io.Writer
+	format string
+
+	cols    []string
+	widths  []int
+	rows    [][]string
+	msgs    []proto.Message
+	started bool
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "table", "json", "csv":
+		return &printer{w: w, format: format}, nil
+	}
+	return nil, fmt.Errorf("unknown format [%s]", format)
+}
+
+func (p *printer) header(cols []string) {
+	p.cols = cols
+}
+
+// row queues a row; msg is what the json format prints for it. Rows that
+// come from one message pass nil after the first.
+func (p *printer) row(cells []string, msg proto.Message) {
+	p.rows = append(p.rows, cells)
+	p.msgs = append(p.msgs, msg)
+}
+
+// flush writes the queued rows. Table columns are sized by the first flush,
+// so rows printed as they arrive stay lined up.
+func (p *printer) flush() error {
+	defer func() {
+		p.rows, p.msgs = nil, nil
+		p.started = true
+	}()
+
+	switch p.format {
+	case "json":
+		m := jsonpb.Marshaler{EmitDefaults: true}
+		for _, msg := range p.msgs {
+			if msg == nil {
+				continue
+			}
+			err := m.Marshal(p.w, msg)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(p.w)
+		}
+		return nil
+	case "csv":
+		w := csv.NewWriter(p.w)
+		if !p.started {
+			w.Write(p.cols)
+		}
+		w.WriteAll(p.rows)
+		return w.Error()
+	}
+
+	if p.widths == nil {
+		p.widths = make([]int, len(p.cols))
+		for i, col := range p.cols {
+			p.widths[i] = len(col)
+		}
+		for _, row := range p.rows {
+			for i, cell := range row {
+				if i < len(p.widths) && len(cell) > p.widths[i] {
+					p.widths[i] = len(cell)
+				}
+			}
+		}
+	}
+	bw := bufio.NewWriter(p.w)
+	writeRow := func(cells []string) {
+		for i, cell := range cells {
+			if i > 0 {
+				bw.WriteString("  ")
+			}
+			if i == len(cells)-1 {
+				bw.WriteString(cell)
+			} else {
+				fmt.Fprintf(bw, "%-*s", p.widths[i], cell)
+			}
+		}
+		bw.WriteString("\n")
+	}
+	if !p.started {
+		writeRow(p.cols)
+	}
+	for _, row := range p.rows {
+		writeRow(row)
+	}
+	return bw.Flush()
+}
+
+func printEnqueued(out *printer, res *pb.EnqueueResponse) error {
+	out.header([]string{"id", "result"})
+	var msg proto.Message = res
+	for _, farmID := range res.Queued {
+		out.row([]string{farmID, "queued"}, msg)
+		msg = nil
+	}
+	for _, rejected := range res.Rejected {
+		out.row([]string{rejected.Id, rejected.Reason}, msg)
+		msg = nil
+	}
+	return out.flush()
+}
+
+func enqueueFarms(admin pb.AdminClient, out *printer, farmIDs []string) error {
+	if len(farmIDs) == 0 {
+		return fmt.Errorf("enqueue needs at least one farm id")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	res, err := admin.Enqueue(ctx, &pb.EnqueueRequest{Ids: farmIDs})
+	if err != nil {
+		return err
+	}
+	return printEnqueued(out, res)
+}
+
+// importFarms sends a file to be imported, reporting progress on stderr and
+// listing the rejected entries.
+func importFarms(admin pb.AdminClient, out *printer, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("import needs a file, or - for stdin")
+	}
+	in := os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	stream, err := admin.Import(context.Background())
+	if err != nil {
+		return err
+	}
+	if err := sendImport(stream, in); err != nil {
+		return err
+	}
+	out.header([]string{"id", "result"})
+	for {
+		progress, err := stream.Recv()
+		if err == io.EOF {
+			return out.flush()
+		}
+		if err != nil {
+			return err
+		}
+		for _, rejected := range progress.Rejected {
+			out.row([]string{rejected.Id, rejected.Reason}, rejected)
+		}
+		fmt.Fprintf(os.Stderr, "%d/%d entries: %d queued, %d duplicates\n",
+			progress.Processed, progress.Entries, progress.Queued, progress.Duplicates)
+	}
+}
+
+// sendImport uploads a file in importChunkSize pieces, keeping each
+// message well under gRPC's size limit.
+func sendImport(stream pb.Admin_ImportClient, in io.Reader) error {
+	buf := make([]byte, importChunkSize)
+	for {
+		n, err := io.ReadFull(in, buf)
+		if n > 0 {
+			sendErr := stream.Send(&pb.ImportRequest{Data: buf[:n]})
+			if sendErr == io.EOF {
+				// the server has given up; Recv returns why
+				return nil
+			}
+			if sendErr != nil {
+				return sendErr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return stream.CloseSend()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func fetchRecents(admin pb.AdminClient, out *printer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	res, err := admin.FetchRecents(ctx, &pb.FetchRecentsRequest{})
+	if err != nil {
+		return err
+	}
+	return printEnqueued(out, res)
+}
+
+func spider(admin pb.AdminClient, out *printer, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var st *pb.SpiderStatus
+	var err error
+	switch {
+	case len(args) == 0:
+		st, err = admin.StartSpider(ctx, &pb.StartSpiderRequest{Mode: pb.StartSpiderRequest_HOMEPAGE})
+	case len(args) == 1 && args[0] == "stop":
+		st, err = admin.StopSpiders(ctx, &pb.StopSpidersRequest{})
+	case len(args) == 1 && args[0] == "status":
+		st, err = admin.GetSpiderStatus(ctx, &pb.SpiderStatusRequest{})
+	case len(args) == 1 || (len(args) == 2 && args[0] == "all"):
+		req := &pb.StartSpiderRequest{Mode: pb.StartSpiderRequest_PAGE}
+		if len(args) == 2 {
+			req.Mode = pb.StartSpiderRequest_ALL
+		}
+		page, perr := strconv.ParseUint(args[len(args)-1], 10, 32)
+		if perr != nil {
+			return fmt.Errorf("invalid page number [%s]", args[len(args)-1])
+		}
+		req.Page = uint32(page)
+		st, err = admin.StartSpider(ctx, req)
+	default:
+		return fmt.Errorf("usage: spider [<page> | all <page> | stop | status]")
+	}
+	if err != nil {
+		return err
+	}
+	out.header([]string{"running", "stopping"})
+	out.row([]string{strconv.Itoa(int(st.Running)), strconv.FormatBool(st.Stopping)}, st)
+	return out.flush()
+}
+
+func queueStatus(admin pb.AdminClient, out *printer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	st, err := admin.GetQueueStatus(ctx, &pb.QueueStatusRequest{})
+	if err != nil {
+		return err
+	}
+	out.header([]string{"queue", "depth", "capacity"})
+	out.row([]string{"farms", strconv.Itoa(int(st.Farms)), strconv.Itoa(int(st.FarmsCapacity))}, st)
+	for _, lane := range st.Lanes {
+		out.row([]string{"farms/" + lane.Name, strconv.Itoa(int(lane.Farms)), strconv.Itoa(int(lane.Capacity))}, nil)
+	}
+	out.row([]string{"stats", strconv.Itoa(int(st.Stats)), strconv.Itoa(int(st.StatsCapacity))}, nil)
+	return out.flush()
+}
+
+// workerStatus shows the worker pool, resizing it first if asked.
+func workerStatus(admin pb.AdminClient, out *printer, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: workers [n|auto]")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	var pool *pb.WorkerPool
+	var err error
+	switch {
+	case len(args) == 0:
+		pool, err = admin.GetWorkers(ctx, &pb.WorkersRequest{})
+	case args[0] == "auto":
+		pool, err = admin.SetWorkers(ctx, &pb.SetWorkersRequest{Auto: true})
+	default:
+		n, convErr := strconv.Atoi(args[0])
+		if convErr != nil || n < 1 {
+			return fmt.Errorf("workers needs a positive number or auto")
+		}
+		pool, err = admin.SetWorkers(ctx, &pb.SetWorkersRequest{Size: uint32(n)})
+	}
+	if err != nil {
+		return err
+	}
+	mode := fmt.Sprintf("autoscaling %d-%d", pool.Min, pool.Max)
+	if pool.Pinned {
+		mode = "pinned"
+	}
+	fmt.Fprintf(os.Stderr, "%d workers (%s), %d farms in the last minute\n", pool.Size, mode, pool.PerMinute)
+	out.header([]string{"worker", "state", "farm", "processed", "failed", "per_minute"})
+	for _, w := range pool.Workers {
+		out.row([]string{
+			strconv.Itoa(int(w.Id)), w.State, w.FarmId,
+			strconv.Itoa(int(w.Processed)), strconv.Itoa(int(w.Failed)),
+			strconv.FormatFloat(w.PerMinute, 'f', 1, 64),
+		}, w)
 	}
-	dur := time.Since(t1)
-	log.Infof("got stats: [%v] in %s", stats, dur)
+	return out.flush()
 }
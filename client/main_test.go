@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPrinter(t *testing.T) {
+	farm := &pb.Farm{Id: "1AAAAA", Abigail: 10}
+
+	Convey("Farm rows follow the header's villager order", t, func() {
+		row := farmRow(farm)
+		So(len(row), ShouldEqual, len(farmHeader))
+		So(farmHeader[:2], ShouldResemble, []string{"id", "abigail"})
+		So(row[:3], ShouldResemble, []string{"1AAAAA", "10", "0"})
+	})
+
+	Convey("Rows print as a table, CSV or JSON", t, func() {
+		var buf bytes.Buffer
+		_, err := newPrinter(&buf, "yaml")
+		So(err, ShouldNotBeNil)
+
+		out, _ := newPrinter(&buf, "table")
+		out.header([]string{"id", "abigail"})
+		out.row([]string{"1AAAAA", "10"}, farm)
+		So(out.flush(), ShouldBeNil)
+		out.row([]string{"1AAAAB", "2"}, farm)
+		So(out.flush(), ShouldBeNil)
+		So(buf.String(), ShouldEqual, "id      abigail\n1AAAAA  10\n1AAAAB  2\n")
+
+		buf.Reset()
+		out, _ = newPrinter(&buf, "csv")
+		out.header([]string{"id", "abigail"})
+		out.row([]string{"1AAAAA", "10"}, farm)
+		So(out.flush(), ShouldBeNil)
+		So(buf.String(), ShouldEqual, "id,abigail\n1AAAAA,10\n")
+
+		buf.Reset()
+		out, _ = newPrinter(&buf, "json")
+		out.row([]string{"1AAAAA", "10"}, farm)
+		So(out.flush(), ShouldBeNil)
+		So(buf.String(), ShouldStartWith, `{"id":"1AAAAA","abigail":10,"alex":0`)
+	})
+}
+
+func TestExport(t *testing.T) {
+	Convey("Given an export endpoint", t, func() {
+		var got *http.Request
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r
+			if r.URL.Query().Get("format") == "xml" {
+				http.Error(w, "unknown format [xml]", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("farmID\n1AAAAA\n"))
+		}))
+		defer srv.Close()
+		defer func(addr, tok string) { *httpAddr, *token = addr, tok }(*httpAddr, *token)
+		*httpAddr, *token = srv.URL, "secret"
+
+		dir, err := ioutil.TempDir("", "export")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "farms.csv")
+
+		Convey("the export is written to the file with the filters and token", func() {
+			So(export([]string{"-o", path, "-villager", "Abigail", "-min-level", "8"}), ShouldBeNil)
+			data, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "farmID\n1AAAAA\n")
+			So(got.URL.Path, ShouldEqual, "/api/v1/export")
+			So(got.URL.RawQuery, ShouldEqual, "format=csv&minLevel=8&villager=Abigail")
+			So(got.Header.Get("Authorization"), ShouldEqual, "Bearer secret")
+		})
+
+		Convey("restore uploads the snapshot", func() {
+			So(ioutil.WriteFile(path, []byte("snapshot"), 0644), ShouldBeNil)
+			So(restore([]string{path}), ShouldBeNil)
+			So(got.Method, ShouldEqual, "POST")
+			So(got.URL.Path, ShouldEqual, "/api/v1/restore")
+		})
+
+		Convey("images and duplicates are fetched from the farm's endpoints", func() {
+			So(image([]string{"-thumbnail", "-o", path, "1AAAAA"}), ShouldBeNil)
+			So(got.URL.Path, ShouldEqual, "/api/v1/farms/1AAAAA/thumbnail")
+			So(duplicates([]string{"-distance", "2", "1AAAAA"}), ShouldBeNil)
+			So(got.URL.Path, ShouldEqual, "/api/v1/farms/1AAAAA/duplicates")
+			So(got.URL.RawQuery, ShouldEqual, "distance=2")
+			So(image(nil), ShouldNotBeNil)
+		})
+
+		Convey("errors from the server are reported", func() {
+			err := export([]string{"-format", "xml"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "unknown format [xml]")
+		})
+	})
+}
+
+// fakeImportStream records the chunks an import sends.
+type fakeImportStream struct {
+	grpc.ClientStream
+	chunks []string
+	closed bool
+}
+
+func (s *fakeImportStream) Send(req *pb.ImportRequest) error {
+	s.chunks = append(s.chunks, string(req.Data))
+	return nil
+}
+
+func (s *fakeImportStream) CloseSend() error {
+	s.closed = true
+	return nil
+}
+
+func (s *fakeImportStream) Recv() (*pb.ImportProgress, error) {
+	return nil, nil
+}
+
+func TestSendImport(t *testing.T) {
+	Convey("Import files are sent in chunks", t, func() {
+		data := strings.Repeat("1AAAAA\n", importChunkSize/7*3)
+		stream := &fakeImportStream{}
+		So(sendImport(stream, strings.NewReader(data)), ShouldBeNil)
+		So(stream.chunks, ShouldHaveLength, 3)
+		So(len(stream.chunks[0]), ShouldEqual, importChunkSize)
+		So(strings.Join(stream.chunks, ""), ShouldEqual, data)
+		So(stream.closed, ShouldBeTrue)
+
+		stream = &fakeImportStream{}
+		So(sendImport(stream, strings.NewReader("")), ShouldBeNil)
+		So(stream.chunks, ShouldBeEmpty)
+		So(stream.closed, ShouldBeTrue)
+	})
+}
+
+func TestDescribeError(t *testing.T) {
+	Convey("gRPC errors show the details the server attached", t, func() {
+		st, _ := grpcstatus.New(codes.Unavailable, "farm 1AAAAA is queued, retry after 3s").
+			WithDetails(&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(3 * time.Second)})
+		So(describeError(st.Err()), ShouldEqual, "farm 1AAAAA is queued, retry after 3s (Unavailable); retry after 3s")
+
+		st, _ = grpcstatus.New(codes.Internal, "farm 1AAAAA failed to parse: no villagers found").
+			WithDetails(&errdetails.ErrorInfo{Type: "PARSE_FAILED", Metadata: map[string]string{"error": "no villagers found for 1AAAAA"}})
+		So(describeError(st.Err()), ShouldEndWith, "(Internal); PARSE_FAILED: no villagers found for 1AAAAA")
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// config is read from the JSON file given with -config. Anything left out
+// keeps its default.
+type config struct {
+	Jobs  map[string]jobConfig `json:"jobs"`
+	Cache cacheConfig          `json:"cache"`
+	Log   logConfig            `json:"log"`
+	Trace traceConfig          `json:"tracing"`
+	Auth  authConfig           `json:"auth"`
+	TLS   serversTLS           `json:"tls"`
+	// Images sets up downloading, thumbnailing and hashing farm renders.
+	Images imagesConfig `json:"images"`
+	// Workers bounds the autoscaling pool of farm workers.
+	Workers workersConfig `json:"workers"`
+	// OnDemand fetches unknown farms for GetStats and /api/v1/farms/{id}
+	// while the request waits, instead of answering not found.
+	OnDemand bool `json:"on_demand"`
+	// Restore is a snapshot to load the store from at startup. We are not
+	// ready until it has loaded.
+	Restore string `json:"restore"`
+}
+
+// cacheConfig sets where fetched pages are kept and for how long each class
+// of page is used without asking upload.farm whether it changed.
+type cacheConfig struct {
+	Disabled bool              `json:"disabled"`
+	Dir      string            `json:"dir"`
+	TTLs     map[string]string `json:"ttls"`
+}
+
+func defaultConfig() config {
+	return config{
+		Jobs: map[string]jobConfig{
+			"recents":  {Every: "30s"},
+			"crawl":    {Every: "10m", Jitter: "1m"},
+			"refresh":  {Every: defaultRefreshCheck.String()},
+			"backfill": {Every: "1h", Jitter: "5m"},
+			"images":   {Every: "10m", Jitter: "1m"},
+		},
+		Workers: workersConfig{Min: defaultMinWorkers, Max: defaultMaxWorkers},
+		Images: imagesConfig{
+			Dir:        "images",
+			ThumbWidth: defaultThumbWidth,
+		},
+		Cache: cacheConfig{
+			Dir: "cache",
+			TTLs: map[string]string{
+				"recents": "0s",
+				"listing": "5m",
+				"farm":    "1h",
+				"other":   "0s",
+			},
+		},
+	}
+}
+
+func loadConfig(path string) (config, error) {
+	cfg := defaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	var fileCfg config
+	err = json.Unmarshal(body, &fileCfg)
+	if err != nil {
+		return cfg, err
+	}
+
+	if fileCfg.Log.Format != "" {
+		cfg.Log.Format = fileCfg.Log.Format
+	}
+	if fileCfg.Log.Level != "" {
+		cfg.Log.Level = fileCfg.Log.Level
+	}
+	cfg.TLS = fileCfg.TLS
+	cfg.OnDemand = fileCfg.OnDemand
+	cfg.Restore = fileCfg.Restore
+	if fileCfg.Workers.Min != 0 {
+		cfg.Workers.Min = fileCfg.Workers.Min
+	}
+	if fileCfg.Workers.Max != 0 {
+		cfg.Workers.Max = fileCfg.Workers.Max
+	}
+	if len(fileCfg.Auth.Tokens) > 0 {
+		cfg.Auth = fileCfg.Auth
+	}
+	if fileCfg.Trace.Exporter != "" {
+		cfg.Trace = fileCfg.Trace
+	}
+	for name, job := range fileCfg.Jobs {
+		cfg.Jobs[name] = job
+	}
+	cfg.Cache.Disabled = fileCfg.Cache.Disabled
+	if fileCfg.Cache.Dir != "" {
+		cfg.Cache.Dir = fileCfg.Cache.Dir
+	}
+	cfg.Images.Disabled = fileCfg.Images.Disabled
+	if fileCfg.Images.Dir != "" {
+		cfg.Images.Dir = fileCfg.Images.Dir
+	}
+	if fileCfg.Images.ThumbWidth != 0 {
+		cfg.Images.ThumbWidth = fileCfg.Images.ThumbWidth
+	}
+	if fileCfg.Images.MaxDistance != nil {
+		cfg.Images.MaxDistance = fileCfg.Images.MaxDistance
+	}
+	for class, ttl := range fileCfg.Cache.TTLs {
+		cfg.Cache.TTLs[class] = ttl
+	}
+	return cfg, nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// exportPageSize is how many farms are copied out of the store at a time,
+// so an export never holds the lock, or every farm, for long.
+const exportPageSize = 500
+
+// exportRowGroupSize bounds how much of a parquet export is buffered before
+// it is written out.
+const exportRowGroupSize = 4 * 1024 * 1024
+
+// exportColumns is the column order for every format: the farm's details,
+// then one column per villager in registry order.
+var exportColumns = append([]string{"farmID", "fetched", "pageHash"}, villagers...)
+
+// exportFilter picks the farms to export. Zero values match everything.
+type exportFilter struct {
+	Since    time.Time
+	Until    time.Time
+	Villager string
+	MinLevel uint32
+}
+
+// parseExportFilter reads the since, until, villager and minLevel query
+// parameters.
+func parseExportFilter(q url.Values) (exportFilter, error) {
+	var f exportFilter
+	var err error
+	if s := q.Get("since"); s != "" {
+		f.Since, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return f, fmt.Errorf("since should be an RFC 3339 time: %v", err)
+		}
+	}
+	if s := q.Get("until"); s != "" {
+		f.Until, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return f, fmt.Errorf("until should be an RFC 3339 time: %v", err)
+		}
+	}
+	if s := q.Get("minLevel"); s != "" {
+		level, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return f, fmt.Errorf("minLevel should be a number: %v", err)
+		}
+		f.MinLevel = uint32(level)
+	}
+	f.Villager = q.Get("villager")
+	if f.Villager != "" && !isVillager(f.Villager) {
+		return f, fmt.Errorf("unknown villager [%s]", f.Villager)
+	}
+	if f.MinLevel > 0 && f.Villager == "" {
+		return f, fmt.Errorf("minLevel needs a villager")
+	}
+	return f, nil
+}
+
+func isVillager(name string) bool {
+	for _, v := range villagers {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (f exportFilter) match(stats svStats) bool {
+	if !f.Since.IsZero() && stats.Fetched.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !stats.Fetched.Before(f.Until) {
+		return false
+	}
+	if f.Villager != "" && stats.villager(f.Villager) < f.MinLevel {
+		return false
+	}
+	return true
+}
+
+// farmExporter writes farms in one export format.
+type farmExporter interface {
+	write(stats svStats) error
+	// flush writes out anything buffered; close also finishes the file.
+	flush() error
+	close() error
+}
+
+type exportFormat struct {
+	contentType string
+	extension   string
+	new         func(w io.Writer) (farmExporter, error)
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv":     {"text/csv", "csv", newCSVExporter},
+	"jsonl":   {"application/x-ndjson", "jsonl", newJSONLExporter},
+	"parquet": {"application/vnd.apache.parquet", "parquet", newParquetExporter},
+}
+
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func newCSVExporter(w io.Writer) (farmExporter, error) {
+	e := &csvExporter{w: csv.NewWriter(w)}
+	return e, e.w.Write(exportColumns)
+}
+
+func (e *csvExporter) write(stats svStats) error {
+	row := []string{stats.FarmID, stats.Fetched.UTC().Format(time.RFC3339), stats.PageHash}
+	for _, name := range villagers {
+		row = append(row, strconv.Itoa(int(stats.villager(name))))
+	}
+	return e.w.Write(row)
+}
+
+func (e *csvExporter) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExporter) close() error {
+	return e.flush()
+}
+
+// jsonlExporter writes one flat JSON object per farm. Objects are built by
+// hand so their keys keep the column order.
+type jsonlExporter struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func newJSONLExporter(w io.Writer) (farmExporter, error) {
+	return &jsonlExporter{w: w}, nil
+}
+
+func (e *jsonlExporter) write(stats svStats) error {
+	values := []interface{}{stats.FarmID, stats.Fetched.UTC(), stats.PageHash}
+	for _, name := range villagers {
+		values = append(values, stats.villager(name))
+	}
+	e.buf.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(exportColumns[i])
+		v, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		e.buf.Write(key)
+		e.buf.WriteByte(':')
+		e.buf.Write(v)
+	}
+	e.buf.WriteString("}\n")
+	return nil
+}
+
+func (e *jsonlExporter) flush() error {
+	_, err := e.w.Write(e.buf.Bytes())
+	e.buf.Reset()
+	return err
+}
+
+func (e *jsonlExporter) close() error {
+	return e.flush()
+}
+
+// parquetExporter writes a row group whenever exportRowGroupSize of farms
+// have been buffered.
+type parquetExporter struct {
+	w *writer.CSVWriter
+}
+
+func newParquetExporter(w io.Writer) (farmExporter, error) {
+	schema := []string{
+		"name=farmID, type=UTF8",
+		"name=fetched, type=TIMESTAMP_MILLIS",
+		"name=pageHash, type=UTF8",
+	}
+	for _, name := range villagers {
+		schema = append(schema, fmt.Sprintf("name=%s, type=INT32", name))
+	}
+	pw, err := writer.NewCSVWriterFromWriter(schema, w, 1)
+	if err != nil {
+		return nil, err
+	}
+	pw.RowGroupSize = exportRowGroupSize
+	return &parquetExporter{w: pw}, nil
+}
+
+func (e *parquetExporter) write(stats svStats) error {
+	row := []interface{}{stats.FarmID, stats.Fetched.UnixNano() / int64(time.Millisecond), stats.PageHash}
+	for _, name := range villagers {
+		row = append(row, int32(stats.villager(name)))
+	}
+	return e.w.Write(row)
+}
+
+// flush does nothing: parquet row groups are written when they fill up.
+func (e *parquetExporter) flush() error {
+	return nil
+}
+
+func (e *parquetExporter) close() error {
+	return e.w.WriteStop()
+}
+
+// exportFarms writes every stored farm matching filter to e in farm id
+// order, a page at a time, returning how many it wrote.
+func exportFarms(e farmExporter, filter exportFilter) (int, error) {
+	n := 0
+	pager := allFarms.pager(exportPageSize)
+	for {
+		page := pager.next()
+		if page == nil {
+			return n, e.close()
+		}
+		for _, stats := range page {
+			if !filter.match(stats) {
+				continue
+			}
+			err := e.write(stats)
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+		err := e.flush()
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// exportHandler streams the stored farms as CSV, JSON Lines or Parquet,
+// chosen with ?format=, optionally filtered by fetch time and by a
+// villager's friendship level.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "csv"
+	}
+	format, ok := exportFormats[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown format [%s], use csv, jsonl or parquet", name), http.StatusBadRequest)
+		return
+	}
+	filter, err := parseExportFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="farms.%s"`, format.extension))
+	logger := log.WithField("reqID", reqIDFromContext(r.Context()))
+	e, err := format.new(w)
+	if err != nil {
+		logger.WithError(err).Error("cannot start export")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// once farms have been sent the status is fixed, so errors can only be
+	// logged, and show up to the client as a truncated export
+	n, err := exportFarms(e, filter)
+	if err != nil {
+		logger.WithError(err).Warnf("export stopped after %d farms", n)
+		return
+	}
+	logger.WithField("format", name).Infof("exported %d farms", n)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExport(t *testing.T) {
+	Convey("Given some stored farms", t, func() {
+		fetched := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+		allFarms.stats = map[string]svStats{
+			"1AAAAB": {FarmID: "1AAAAB", Fetched: fetched.Add(time.Hour), PageHash: "b", Abigail: 2},
+			"1AAAAA": {FarmID: "1AAAAA", Fetched: fetched, PageHash: "a", Abigail: 10, Wizard: 3},
+		}
+		export := func(query string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			exportHandler(w, httptest.NewRequest("GET", "/api/v1/export"+query, nil))
+			return w
+		}
+
+		Convey("CSV has a column per villager in registry order", func() {
+			w := export("")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Header().Get("Content-Type"), ShouldEqual, "text/csv")
+			rows, err := csv.NewReader(w.Body).ReadAll()
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 3)
+			So(rows[0][:4], ShouldResemble, []string{"farmID", "fetched", "pageHash", "Abigail"})
+			So(rows[0][len(rows[0])-1], ShouldEqual, "Wizard")
+			So(len(rows[0]), ShouldEqual, 3+len(villagers))
+			So(rows[1][:4], ShouldResemble, []string{"1AAAAA", "2020-05-01T12:00:00Z", "a", "10"})
+			So(rows[1][len(rows[1])-1], ShouldEqual, "3")
+			So(rows[2][0], ShouldEqual, "1AAAAB")
+		})
+
+		Convey("JSON Lines keeps the same key order", func() {
+			w := export("?format=jsonl")
+			lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+			So(lines, ShouldHaveLength, 2)
+			So(lines[0], ShouldStartWith, `{"farmID":"1AAAAA","fetched":"2020-05-01T12:00:00Z","pageHash":"a","Abigail":10,"Alex":0,`)
+			So(lines[0], ShouldEndWith, `"Wizard":3}`)
+		})
+
+		Convey("Parquet files can be read back", func() {
+			w := export("?format=parquet")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			pf, err := buffer.NewBufferFile(w.Body.Bytes())
+			So(err, ShouldBeNil)
+			pr, err := reader.NewParquetReader(pf, nil, 1)
+			So(err, ShouldBeNil)
+			defer pr.ReadStop()
+			So(pr.GetNumRows(), ShouldEqual, 2)
+			// the reader renames columns to Go names, so compare the
+			// names in the file
+			var names []string
+			for i := 1; i < len(pr.SchemaHandler.Infos); i++ {
+				names = append(names, pr.SchemaHandler.GetExName(i))
+			}
+			So(names, ShouldResemble, exportColumns)
+		})
+
+		Convey("farms can be filtered", func() {
+			w := export("?format=jsonl&since=2020-05-01T12:30:00Z")
+			So(w.Body.String(), ShouldStartWith, `{"farmID":"1AAAAB"`)
+			w = export("?format=jsonl&until=2020-05-01T12:30:00Z")
+			So(w.Body.String(), ShouldStartWith, `{"farmID":"1AAAAA"`)
+			So(strings.Count(w.Body.String(), "\n"), ShouldEqual, 1)
+			w = export("?format=jsonl&villager=Abigail&minLevel=10")
+			So(strings.Count(w.Body.String(), "\n"), ShouldEqual, 1)
+		})
+
+		Convey("exports go a page at a time", func() {
+			for i := 0; i < exportPageSize+10; i++ {
+				id := fmt.Sprintf("1B%04d", i)
+				allFarms.stats[id] = svStats{FarmID: id}
+			}
+			w := export("?format=jsonl")
+			So(strings.Count(w.Body.String(), "\n"), ShouldEqual, exportPageSize+12)
+		})
+
+		Convey("bad requests are refused", func() {
+			So(export("?format=xml").Code, ShouldEqual, http.StatusBadRequest)
+			So(export("?since=yesterday").Code, ShouldEqual, http.StatusBadRequest)
+			So(export("?villager=Bob").Code, ShouldEqual, http.StatusBadRequest)
+			So(export("?minLevel=5").Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
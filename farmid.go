@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const farmIDLength = 6
+
+// FarmID is a valid upload.farm farm id: six characters from the base-62
+// alphabet in chars, starting with 1.
+type FarmID string
+
+var (
+	errFarmIDLength = fmt.Errorf("must be %d characters", farmIDLength)
+	errFarmIDPrefix = errors.New("must start with 1")
+	errFarmIDChars  = errors.New("must only use 0-9, a-z and A-Z")
+	errFarmIDURL    = errors.New("is not an upload.farm URL")
+)
+
+// FarmIDError says why some input is not a farm id. Err is one of the
+// errFarmID errors.
+type FarmIDError struct {
+	Input string
+	Err   error
+}
+
+func (e *FarmIDError) Error() string {
+	return fmt.Sprintf("invalid farm id [%s]: %v", e.Input, e.Err)
+}
+
+func (e *FarmIDError) Unwrap() error {
+	return e.Err
+}
+
+// ParseFarmID checks that s is exactly a farm id.
+func ParseFarmID(s string) (FarmID, error) {
+	if len(s) != farmIDLength {
+		return "", &FarmIDError{Input: s, Err: errFarmIDLength}
+	}
+	if s[0] != '1' {
+		return "", &FarmIDError{Input: s, Err: errFarmIDPrefix}
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(chars, s[i]) < 0 {
+			return "", &FarmIDError{Input: s, Err: errFarmIDChars}
+		}
+	}
+	return FarmID(s), nil
+}
+
+// farmIDFromRecent reads the farm id at the start of a _mini_recents entry
+// such as "1BC123.png". Whatever follows the id must not look like more of
+// it.
+func farmIDFromRecent(entry string) (FarmID, error) {
+	if len(entry) > farmIDLength {
+		if strings.IndexByte(chars, entry[farmIDLength]) >= 0 {
+			return "", &FarmIDError{Input: entry, Err: errFarmIDLength}
+		}
+		entry = entry[:farmIDLength]
+	}
+	return ParseFarmID(entry)
+}
+
+// farmIDFromURL reads the farm id from an upload.farm URL such as
+// https://upload.farm/1BC123 or upload.farm/all/1BC123-f.png.
+func farmIDFromURL(s string) (FarmID, error) {
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil || strings.TrimPrefix(u.Hostname(), "www.") != "upload.farm" {
+		return "", &FarmIDError{Input: s, Err: errFarmIDURL}
+	}
+	path := strings.Trim(u.Path, "/")
+	if path == "" {
+		return "", &FarmIDError{Input: s, Err: errFarmIDURL}
+	}
+	return farmIDFromRecent(path[strings.LastIndex(path, "/")+1:])
+}
+
+// parseFarmInput reads a farm id from what a person or a file gave us: a
+// farm id, a _mini_recents entry or an upload.farm URL.
+func parseFarmInput(s string) (FarmID, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "://") || strings.Contains(s, "upload.farm") {
+		return farmIDFromURL(s)
+	}
+	return farmIDFromRecent(s)
+}
+
+// num is the farm id's position in the base-62 ordering, used as its score
+// in the spidered set.
+func (id FarmID) num() int64 {
+	// a FarmID only holds characters idToNum accepts
+	n, _ := idToNum(string(id))
+	return n
+}
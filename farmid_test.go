@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFarmID(t *testing.T) {
+	Convey("Farm ids must be six base-62 characters starting with 1", t, func() {
+		farmID, err := ParseFarmID("1H0thB")
+		So(err, ShouldBeNil)
+		So(farmID, ShouldEqual, FarmID("1H0thB"))
+		So(farmID.num(), ShouldEqual, 1551627847)
+
+		for input, reason := range map[string]error{
+			"":        errFarmIDLength,
+			"1FkeU\n": errFarmIDChars,
+			"1FkeU":   errFarmIDLength,
+			"1bc-12":  errFarmIDChars,
+			"2BC123":  errFarmIDPrefix,
+			"1BC1234": errFarmIDLength,
+		} {
+			_, err := ParseFarmID(input)
+			var idErr *FarmIDError
+			So(errors.As(err, &idErr), ShouldBeTrue)
+			So(idErr.Input, ShouldEqual, input)
+			So(errors.Is(err, reason), ShouldBeTrue)
+		}
+	})
+
+	Convey("_mini_recents entries hold a farm id and a suffix", t, func() {
+		farmID, err := farmIDFromRecent("1BC123.png")
+		So(err, ShouldBeNil)
+		So(farmID, ShouldEqual, FarmID("1BC123"))
+		_, err = farmIDFromRecent("1BC1234.png")
+		So(errors.Is(err, errFarmIDLength), ShouldBeTrue)
+		_, err = farmIDFromRecent("1bc-12.png")
+		So(errors.Is(err, errFarmIDChars), ShouldBeTrue)
+	})
+
+	Convey("Input can be an id, an entry or an upload.farm URL", t, func() {
+		for input, want := range map[string]FarmID{
+			"1BC123":                            "1BC123",
+			" 1BC123\r\n":                       "1BC123",
+			"1BC123.extra":                      "1BC123",
+			"https://upload.farm/1BC123":        "1BC123",
+			"http://www.upload.farm/1BC123/":    "1BC123",
+			"upload.farm/1BC123":                "1BC123",
+			"https://upload.farm/all/1BC123-f":  "1BC123",
+			"https://upload.farm/1BC123?x=true": "1BC123",
+		} {
+			farmID, err := parseFarmInput(input)
+			So(err, ShouldBeNil)
+			So(farmID, ShouldEqual, want)
+		}
+		for _, input := range []string{"https://example.com/1BC123", "https://upload.farm/", "https://upload.farm/all"} {
+			_, err := parseFarmInput(input)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"sort"
+
+	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// listFarms returns up to pageSize farms in id order, starting after the
+// given farm id, along with the id to continue from ("" on the last page)
+// and the number of farms stored.
+func (s *farmStats) listFarms(pageSize int, after string) ([]svStats, string, int) {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	s.mu.Lock()
+	farmIDs := make([]string, 0, len(s.stats))
+	for farmID := range s.stats {
+		if farmID > after {
+			farmIDs = append(farmIDs, farmID)
+		}
+	}
+	total := len(s.stats)
+	sort.Strings(farmIDs)
+	next := ""
+	if len(farmIDs) > pageSize {
+		farmIDs = farmIDs[:pageSize]
+		next = farmIDs[pageSize-1]
+	}
+	page := make([]svStats, 0, len(farmIDs))
+	for _, farmID := range farmIDs {
+		page = append(page, s.stats[farmID])
+	}
+	s.mu.Unlock()
+	return page, next, total
+}
+
+// farmPager walks every stored farm in id order, copying them out of the
+// store a page at a time. It sorts the ids once, when it is made: farms
+// stored after that are left out, and any since removed are skipped.
+type farmPager struct {
+	farms    *farmStats
+	ids      []string
+	pageSize int
+}
+
+func (s *farmStats) pager(pageSize int) *farmPager {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.stats))
+	for farmID := range s.stats {
+		ids = append(ids, farmID)
+	}
+	s.mu.Unlock()
+	sort.Strings(ids)
+	return &farmPager{farms: s, ids: ids, pageSize: pageSize}
+}
+
+// next returns the next page of farms, or nil once there are no more.
+func (p *farmPager) next() []svStats {
+	for len(p.ids) > 0 {
+		n := p.pageSize
+		if n > len(p.ids) {
+			n = len(p.ids)
+		}
+		ids := p.ids[:n]
+		p.ids = p.ids[n:]
+
+		page := make([]svStats, 0, len(ids))
+		p.farms.mu.Lock()
+		for _, farmID := range ids {
+			if stats, ok := p.farms.stats[farmID]; ok {
+				page = append(page, stats)
+			}
+		}
+		p.farms.mu.Unlock()
+		if len(page) > 0 {
+			return page
+		}
+	}
+	return nil
+}
+
+type villagerAggregate struct {
+	Villager string
+	Mean     float64
+	Min, Max uint32
+	Maxed    int
+}
+
+// aggregate summarises each villager's friendship level across every
+// stored farm.
+func (s *farmStats) aggregate() (int, []villagerAggregate) {
+	aggs := make([]villagerAggregate, len(villagers))
+	for i, name := range villagers {
+		aggs[i] = villagerAggregate{Villager: name}
+	}
+
+	s.mu.Lock()
+	n := len(s.stats)
+	first := true
+	for _, stats := range s.stats {
+		for i, name := range villagers {
+			level := stats.villager(name)
+			aggs[i].Mean += float64(level)
+			if first || level < aggs[i].Min {
+				aggs[i].Min = level
+			}
+			if level > aggs[i].Max {
+				aggs[i].Max = level
+			}
+			if level >= 10 {
+				aggs[i].Maxed++
+			}
+		}
+		first = false
+	}
+	s.mu.Unlock()
+
+	if n > 0 {
+		for i := range aggs {
+			aggs[i].Mean /= float64(n)
+		}
+	}
+	return n, aggs
+}
+
+func (s *farmStats) ListFarms(ctx context.Context, req *pb.ListFarmsRequest) (*pb.FarmList, error) {
+	page, next, total := allFarms.listFarms(int(req.PageSize), req.PageToken)
+	res := &pb.FarmList{NextPageToken: next, Total: uint32(total)}
+	for _, stats := range page {
+		res.Farms = append(res.Farms, stats.toProto())
+	}
+	return res, nil
+}
+
+func (s *farmStats) WatchFarms(req *pb.WatchFarmsRequest, stream pb.FarmStats_WatchFarmsServer) error {
+	sub := farmEvents.subscribe(nil)
+	defer farmEvents.unsubscribe(sub)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-sub.events:
+			err := stream.Send(event.(svStats).toProto())
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *farmStats) Aggregate(ctx context.Context, req *pb.AggregateRequest) (*pb.Aggregates, error) {
+	n, aggs := allFarms.aggregate()
+	res := &pb.Aggregates{Farms: uint32(n)}
+	for _, agg := range aggs {
+		res.Villagers = append(res.Villagers, &pb.VillagerAggregate{
+			Villager: agg.Villager,
+			Mean:     agg.Mean,
+			Min:      agg.Min,
+			Max:      agg.Max,
+			Maxed:    uint32(agg.Maxed),
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
+	"golang.org/x/net/context"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFarms(t *testing.T) {
+	Convey("Given three stored farms", t, func() {
+		allFarms.stats = map[string]svStats{
+			"1AAAAC": {FarmID: "1AAAAC", Abigail: 10, Alex: 2},
+			"1AAAAA": {FarmID: "1AAAAA", Abigail: 4, Alex: 6},
+			"1AAAAB": {FarmID: "1AAAAB", Abigail: 1, Alex: 1},
+		}
+
+		Convey("they are listed in id order, a page at a time", func() {
+			res, err := allFarms.ListFarms(context.Background(), &pb.ListFarmsRequest{PageSize: 2})
+			So(err, ShouldBeNil)
+			So(res.Total, ShouldEqual, 3)
+			So(len(res.Farms), ShouldEqual, 2)
+			So(res.Farms[0].Id, ShouldEqual, "1AAAAA")
+			So(res.NextPageToken, ShouldEqual, "1AAAAB")
+
+			res, err = allFarms.ListFarms(context.Background(), &pb.ListFarmsRequest{PageSize: 2, PageToken: res.NextPageToken})
+			So(err, ShouldBeNil)
+			So(len(res.Farms), ShouldEqual, 1)
+			So(res.Farms[0].Id, ShouldEqual, "1AAAAC")
+			So(res.NextPageToken, ShouldEqual, "")
+		})
+
+		Convey("a pager copies them out in id order from one sorted snapshot", func() {
+			pager := allFarms.pager(2)
+			allFarms.mu.Lock()
+			allFarms.stats["1AAAAD"] = svStats{FarmID: "1AAAAD"}
+			delete(allFarms.stats, "1AAAAB")
+			allFarms.mu.Unlock()
+
+			page := pager.next()
+			So(len(page), ShouldEqual, 1)
+			So(page[0].FarmID, ShouldEqual, "1AAAAA")
+			page = pager.next()
+			So(len(page), ShouldEqual, 1)
+			So(page[0].FarmID, ShouldEqual, "1AAAAC")
+			So(pager.next(), ShouldBeNil)
+		})
+
+		Convey("aggregates summarise each villager", func() {
+			res, err := allFarms.Aggregate(context.Background(), &pb.AggregateRequest{})
+			So(err, ShouldBeNil)
+			So(res.Farms, ShouldEqual, 3)
+			So(res.Villagers[0].Villager, ShouldEqual, "Abigail")
+			So(res.Villagers[0].Mean, ShouldEqual, 5)
+			So(res.Villagers[0].Min, ShouldEqual, 1)
+			So(res.Villagers[0].Max, ShouldEqual, 10)
+			So(res.Villagers[0].Maxed, ShouldEqual, 1)
+			So(res.Villagers[1].Min, ShouldEqual, 1)
+			So(len(res.Villagers), ShouldEqual, len(villagers))
+		})
+
+		Convey("subscribers see farms as they are stored", func() {
+			sub := farmEvents.subscribe(nil)
+			farmEvents.publish(svStats{FarmID: "1AAAAD", Abigail: 3})
+			farmEvents.unsubscribe(sub)
+			farmEvents.publish(svStats{FarmID: "1AAAAE"})
+
+			select {
+			case event := <-sub.events:
+				So(event.(svStats).FarmID, ShouldEqual, "1AAAAD")
+			case <-time.After(time.Second):
+				So("no farm received", ShouldBeEmpty)
+			}
+			_, open := <-sub.events
+			So(open, ShouldBeFalse)
+		})
+	})
+}
@@ -23,8 +23,41 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type StartSpiderRequest_Mode int32
+
+const (
+	// the farms on the homepage listing, queued and recorded in redis
+	StartSpiderRequest_HOMEPAGE StartSpiderRequest_Mode = 0
+	// one page of the listing, queued and recorded in redis
+	StartSpiderRequest_PAGE StartSpiderRequest_Mode = 1
+	// every page from page back to 0, recorded in redis only
+	StartSpiderRequest_ALL StartSpiderRequest_Mode = 2
+)
+
+var StartSpiderRequest_Mode_name = map[int32]string{
+	0: "HOMEPAGE",
+	1: "PAGE",
+	2: "ALL",
+}
+var StartSpiderRequest_Mode_value = map[string]int32{
+	"HOMEPAGE": 0,
+	"PAGE":     1,
+	"ALL":      2,
+}
+
+func (x StartSpiderRequest_Mode) String() string {
+	return proto.EnumName(StartSpiderRequest_Mode_name, int32(x))
+}
+func (StartSpiderRequest_Mode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{19, 0}
+}
+
 type FarmID struct {
-	Id                   string   `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	// GetStats only: queue the farm to be fetched if it is not stored
+	Enqueue bool `protobuf:"varint,2,opt,name=enqueue" json:"enqueue,omitempty"`
+	// with enqueue, wait for the fetch until the request's deadline
+	Wait                 bool     `protobuf:"varint,3,opt,name=wait" json:"wait,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -34,7 +67,7 @@ func (m *FarmID) Reset()         { *m = FarmID{} }
 func (m *FarmID) String() string { return proto.CompactTextString(m) }
 func (*FarmID) ProtoMessage()    {}
 func (*FarmID) Descriptor() ([]byte, []int) {
-	return fileDescriptor_farmstats_48e2e7cef98cd1dc, []int{0}
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{0}
 }
 func (m *FarmID) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_FarmID.Unmarshal(m, b)
@@ -61,6 +94,20 @@ func (m *FarmID) GetId() string {
 	return ""
 }
 
+func (m *FarmID) GetEnqueue() bool {
+	if m != nil {
+		return m.Enqueue
+	}
+	return false
+}
+
+func (m *FarmID) GetWait() bool {
+	if m != nil {
+		return m.Wait
+	}
+	return false
+}
+
 type Farm struct {
 	Id                   string   `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
 	Abigail              uint32   `protobuf:"varint,2,opt,name=abigail" json:"abigail,omitempty"`
@@ -106,7 +153,7 @@ func (m *Farm) Reset()         { *m = Farm{} }
 func (m *Farm) String() string { return proto.CompactTextString(m) }
 func (*Farm) ProtoMessage()    {}
 func (*Farm) Descriptor() ([]byte, []int) {
-	return fileDescriptor_farmstats_48e2e7cef98cd1dc, []int{1}
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{1}
 }
 func (m *Farm) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Farm.Unmarshal(m, b)
@@ -383,7 +430,7 @@ func (m *Response) Reset()         { *m = Response{} }
 func (m *Response) String() string { return proto.CompactTextString(m) }
 func (*Response) ProtoMessage()    {}
 func (*Response) Descriptor() ([]byte, []int) {
-	return fileDescriptor_farmstats_48e2e7cef98cd1dc, []int{2}
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{2}
 }
 func (m *Response) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Response.Unmarshal(m, b)
@@ -417,84 +464,2038 @@ func (m *Response) GetId() string {
 	return ""
 }
 
-func init() {
-	proto.RegisterType((*FarmID)(nil), "farmstats.FarmID")
-	proto.RegisterType((*Farm)(nil), "farmstats.Farm")
-	proto.RegisterType((*Response)(nil), "farmstats.Response")
+type FarmHistoryRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	// versions to compare; 0 means the first and latest versions
+	From                 uint32   `protobuf:"varint,2,opt,name=from" json:"from,omitempty"`
+	To                   uint32   `protobuf:"varint,3,opt,name=to" json:"to,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+func (m *FarmHistoryRequest) Reset()         { *m = FarmHistoryRequest{} }
+func (m *FarmHistoryRequest) String() string { return proto.CompactTextString(m) }
+func (*FarmHistoryRequest) ProtoMessage()    {}
+func (*FarmHistoryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{3}
+}
+func (m *FarmHistoryRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FarmHistoryRequest.Unmarshal(m, b)
+}
+func (m *FarmHistoryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FarmHistoryRequest.Marshal(b, m, deterministic)
+}
+func (dst *FarmHistoryRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FarmHistoryRequest.Merge(dst, src)
+}
+func (m *FarmHistoryRequest) XXX_Size() int {
+	return xxx_messageInfo_FarmHistoryRequest.Size(m)
+}
+func (m *FarmHistoryRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FarmHistoryRequest.DiscardUnknown(m)
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+var xxx_messageInfo_FarmHistoryRequest proto.InternalMessageInfo
 
-// FarmStatsClient is the client API for FarmStats service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type FarmStatsClient interface {
-	// Get the stats for a given farm
-	GetStats(ctx context.Context, in *FarmID, opts ...grpc.CallOption) (*Farm, error)
+func (m *FarmHistoryRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
 }
 
-type farmStatsClient struct {
-	cc *grpc.ClientConn
+func (m *FarmHistoryRequest) GetFrom() uint32 {
+	if m != nil {
+		return m.From
+	}
+	return 0
 }
 
-func NewFarmStatsClient(cc *grpc.ClientConn) FarmStatsClient {
-	return &farmStatsClient{cc}
+func (m *FarmHistoryRequest) GetTo() uint32 {
+	if m != nil {
+		return m.To
+	}
+	return 0
 }
 
-func (c *farmStatsClient) GetStats(ctx context.Context, in *FarmID, opts ...grpc.CallOption) (*Farm, error) {
-	out := new(Farm)
-	err := c.cc.Invoke(ctx, "/farmstats.FarmStats/GetStats", in, out, opts...)
-	if err != nil {
-		return nil, err
+type Snapshot struct {
+	Version uint32 `protobuf:"varint,1,opt,name=version" json:"version,omitempty"`
+	// unix timestamp of the fetch
+	Fetched              int64    `protobuf:"varint,2,opt,name=fetched" json:"fetched,omitempty"`
+	PageHash             string   `protobuf:"bytes,3,opt,name=page_hash,json=pageHash" json:"page_hash,omitempty"`
+	Farm                 *Farm    `protobuf:"bytes,4,opt,name=farm" json:"farm,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Snapshot) Reset()         { *m = Snapshot{} }
+func (m *Snapshot) String() string { return proto.CompactTextString(m) }
+func (*Snapshot) ProtoMessage()    {}
+func (*Snapshot) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{4}
+}
+func (m *Snapshot) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Snapshot.Unmarshal(m, b)
+}
+func (m *Snapshot) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Snapshot.Marshal(b, m, deterministic)
+}
+func (dst *Snapshot) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Snapshot.Merge(dst, src)
+}
+func (m *Snapshot) XXX_Size() int {
+	return xxx_messageInfo_Snapshot.Size(m)
+}
+func (m *Snapshot) XXX_DiscardUnknown() {
+	xxx_messageInfo_Snapshot.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Snapshot proto.InternalMessageInfo
+
+func (m *Snapshot) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
 	}
-	return out, nil
+	return 0
 }
 
-// Server API for FarmStats service
+func (m *Snapshot) GetFetched() int64 {
+	if m != nil {
+		return m.Fetched
+	}
+	return 0
+}
 
-type FarmStatsServer interface {
-	// Get the stats for a given farm
-	GetStats(context.Context, *FarmID) (*Farm, error)
+func (m *Snapshot) GetPageHash() string {
+	if m != nil {
+		return m.PageHash
+	}
+	return ""
 }
 
-func RegisterFarmStatsServer(s *grpc.Server, srv FarmStatsServer) {
-	s.RegisterService(&_FarmStats_serviceDesc, srv)
+func (m *Snapshot) GetFarm() *Farm {
+	if m != nil {
+		return m.Farm
+	}
+	return nil
 }
 
-func _FarmStats_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(FarmID)
-	if err := dec(in); err != nil {
-		return nil, err
+type VillagerDelta struct {
+	Villager             string   `protobuf:"bytes,1,opt,name=villager" json:"villager,omitempty"`
+	From                 uint32   `protobuf:"varint,2,opt,name=from" json:"from,omitempty"`
+	To                   uint32   `protobuf:"varint,3,opt,name=to" json:"to,omitempty"`
+	Delta                int32    `protobuf:"varint,4,opt,name=delta" json:"delta,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VillagerDelta) Reset()         { *m = VillagerDelta{} }
+func (m *VillagerDelta) String() string { return proto.CompactTextString(m) }
+func (*VillagerDelta) ProtoMessage()    {}
+func (*VillagerDelta) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{5}
+}
+func (m *VillagerDelta) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VillagerDelta.Unmarshal(m, b)
+}
+func (m *VillagerDelta) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VillagerDelta.Marshal(b, m, deterministic)
+}
+func (dst *VillagerDelta) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VillagerDelta.Merge(dst, src)
+}
+func (m *VillagerDelta) XXX_Size() int {
+	return xxx_messageInfo_VillagerDelta.Size(m)
+}
+func (m *VillagerDelta) XXX_DiscardUnknown() {
+	xxx_messageInfo_VillagerDelta.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VillagerDelta proto.InternalMessageInfo
+
+func (m *VillagerDelta) GetVillager() string {
+	if m != nil {
+		return m.Villager
 	}
-	if interceptor == nil {
-		return srv.(FarmStatsServer).GetStats(ctx, in)
+	return ""
+}
+
+func (m *VillagerDelta) GetFrom() uint32 {
+	if m != nil {
+		return m.From
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/farmstats.FarmStats/GetStats",
+	return 0
+}
+
+func (m *VillagerDelta) GetTo() uint32 {
+	if m != nil {
+		return m.To
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(FarmStatsServer).GetStats(ctx, req.(*FarmID))
+	return 0
+}
+
+func (m *VillagerDelta) GetDelta() int32 {
+	if m != nil {
+		return m.Delta
 	}
-	return interceptor(ctx, in, info, handler)
+	return 0
 }
 
-var _FarmStats_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "farmstats.FarmStats",
-	HandlerType: (*FarmStatsServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "GetStats",
-			Handler:    _FarmStats_GetStats_Handler,
-		},
-	},
-	Streams:  []grpc.StreamDesc{},
+type FarmHistory struct {
+	Id                   string           `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Snapshots            []*Snapshot      `protobuf:"bytes,2,rep,name=snapshots" json:"snapshots,omitempty"`
+	From                 uint32           `protobuf:"varint,3,opt,name=from" json:"from,omitempty"`
+	To                   uint32           `protobuf:"varint,4,opt,name=to" json:"to,omitempty"`
+	Deltas               []*VillagerDelta `protobuf:"bytes,5,rep,name=deltas" json:"deltas,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *FarmHistory) Reset()         { *m = FarmHistory{} }
+func (m *FarmHistory) String() string { return proto.CompactTextString(m) }
+func (*FarmHistory) ProtoMessage()    {}
+func (*FarmHistory) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{6}
+}
+func (m *FarmHistory) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FarmHistory.Unmarshal(m, b)
+}
+func (m *FarmHistory) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FarmHistory.Marshal(b, m, deterministic)
+}
+func (dst *FarmHistory) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FarmHistory.Merge(dst, src)
+}
+func (m *FarmHistory) XXX_Size() int {
+	return xxx_messageInfo_FarmHistory.Size(m)
+}
+func (m *FarmHistory) XXX_DiscardUnknown() {
+	xxx_messageInfo_FarmHistory.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FarmHistory proto.InternalMessageInfo
+
+func (m *FarmHistory) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *FarmHistory) GetSnapshots() []*Snapshot {
+	if m != nil {
+		return m.Snapshots
+	}
+	return nil
+}
+
+func (m *FarmHistory) GetFrom() uint32 {
+	if m != nil {
+		return m.From
+	}
+	return 0
+}
+
+func (m *FarmHistory) GetTo() uint32 {
+	if m != nil {
+		return m.To
+	}
+	return 0
+}
+
+func (m *FarmHistory) GetDeltas() []*VillagerDelta {
+	if m != nil {
+		return m.Deltas
+	}
+	return nil
+}
+
+type ListFarmsRequest struct {
+	// at most this many farms; 0 means 100
+	PageSize uint32 `protobuf:"varint,1,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+	// next_page_token from the previous page, empty for the first
+	PageToken            string   `protobuf:"bytes,2,opt,name=page_token,json=pageToken" json:"page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListFarmsRequest) Reset()         { *m = ListFarmsRequest{} }
+func (m *ListFarmsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListFarmsRequest) ProtoMessage()    {}
+func (*ListFarmsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{7}
+}
+func (m *ListFarmsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListFarmsRequest.Unmarshal(m, b)
+}
+func (m *ListFarmsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListFarmsRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListFarmsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListFarmsRequest.Merge(dst, src)
+}
+func (m *ListFarmsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListFarmsRequest.Size(m)
+}
+func (m *ListFarmsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListFarmsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListFarmsRequest proto.InternalMessageInfo
+
+func (m *ListFarmsRequest) GetPageSize() uint32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListFarmsRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+type FarmList struct {
+	Farms []*Farm `protobuf:"bytes,1,rep,name=farms" json:"farms,omitempty"`
+	// empty on the last page
+	NextPageToken        string   `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken" json:"next_page_token,omitempty"`
+	Total                uint32   `protobuf:"varint,3,opt,name=total" json:"total,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FarmList) Reset()         { *m = FarmList{} }
+func (m *FarmList) String() string { return proto.CompactTextString(m) }
+func (*FarmList) ProtoMessage()    {}
+func (*FarmList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{8}
+}
+func (m *FarmList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FarmList.Unmarshal(m, b)
+}
+func (m *FarmList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FarmList.Marshal(b, m, deterministic)
+}
+func (dst *FarmList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FarmList.Merge(dst, src)
+}
+func (m *FarmList) XXX_Size() int {
+	return xxx_messageInfo_FarmList.Size(m)
+}
+func (m *FarmList) XXX_DiscardUnknown() {
+	xxx_messageInfo_FarmList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FarmList proto.InternalMessageInfo
+
+func (m *FarmList) GetFarms() []*Farm {
+	if m != nil {
+		return m.Farms
+	}
+	return nil
+}
+
+func (m *FarmList) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+func (m *FarmList) GetTotal() uint32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+type WatchFarmsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchFarmsRequest) Reset()         { *m = WatchFarmsRequest{} }
+func (m *WatchFarmsRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchFarmsRequest) ProtoMessage()    {}
+func (*WatchFarmsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{9}
+}
+func (m *WatchFarmsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchFarmsRequest.Unmarshal(m, b)
+}
+func (m *WatchFarmsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchFarmsRequest.Marshal(b, m, deterministic)
+}
+func (dst *WatchFarmsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchFarmsRequest.Merge(dst, src)
+}
+func (m *WatchFarmsRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchFarmsRequest.Size(m)
+}
+func (m *WatchFarmsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchFarmsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchFarmsRequest proto.InternalMessageInfo
+
+type AggregateRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AggregateRequest) Reset()         { *m = AggregateRequest{} }
+func (m *AggregateRequest) String() string { return proto.CompactTextString(m) }
+func (*AggregateRequest) ProtoMessage()    {}
+func (*AggregateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{10}
+}
+func (m *AggregateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AggregateRequest.Unmarshal(m, b)
+}
+func (m *AggregateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AggregateRequest.Marshal(b, m, deterministic)
+}
+func (dst *AggregateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AggregateRequest.Merge(dst, src)
+}
+func (m *AggregateRequest) XXX_Size() int {
+	return xxx_messageInfo_AggregateRequest.Size(m)
+}
+func (m *AggregateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AggregateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AggregateRequest proto.InternalMessageInfo
+
+type VillagerAggregate struct {
+	Villager string  `protobuf:"bytes,1,opt,name=villager" json:"villager,omitempty"`
+	Mean     float64 `protobuf:"fixed64,2,opt,name=mean" json:"mean,omitempty"`
+	Min      uint32  `protobuf:"varint,3,opt,name=min" json:"min,omitempty"`
+	Max      uint32  `protobuf:"varint,4,opt,name=max" json:"max,omitempty"`
+	// farms where the villager is at 10/10
+	Maxed                uint32   `protobuf:"varint,5,opt,name=maxed" json:"maxed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VillagerAggregate) Reset()         { *m = VillagerAggregate{} }
+func (m *VillagerAggregate) String() string { return proto.CompactTextString(m) }
+func (*VillagerAggregate) ProtoMessage()    {}
+func (*VillagerAggregate) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{11}
+}
+func (m *VillagerAggregate) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VillagerAggregate.Unmarshal(m, b)
+}
+func (m *VillagerAggregate) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VillagerAggregate.Marshal(b, m, deterministic)
+}
+func (dst *VillagerAggregate) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VillagerAggregate.Merge(dst, src)
+}
+func (m *VillagerAggregate) XXX_Size() int {
+	return xxx_messageInfo_VillagerAggregate.Size(m)
+}
+func (m *VillagerAggregate) XXX_DiscardUnknown() {
+	xxx_messageInfo_VillagerAggregate.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VillagerAggregate proto.InternalMessageInfo
+
+func (m *VillagerAggregate) GetVillager() string {
+	if m != nil {
+		return m.Villager
+	}
+	return ""
+}
+
+func (m *VillagerAggregate) GetMean() float64 {
+	if m != nil {
+		return m.Mean
+	}
+	return 0
+}
+
+func (m *VillagerAggregate) GetMin() uint32 {
+	if m != nil {
+		return m.Min
+	}
+	return 0
+}
+
+func (m *VillagerAggregate) GetMax() uint32 {
+	if m != nil {
+		return m.Max
+	}
+	return 0
+}
+
+func (m *VillagerAggregate) GetMaxed() uint32 {
+	if m != nil {
+		return m.Maxed
+	}
+	return 0
+}
+
+type Aggregates struct {
+	Farms                uint32               `protobuf:"varint,1,opt,name=farms" json:"farms,omitempty"`
+	Villagers            []*VillagerAggregate `protobuf:"bytes,2,rep,name=villagers" json:"villagers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Aggregates) Reset()         { *m = Aggregates{} }
+func (m *Aggregates) String() string { return proto.CompactTextString(m) }
+func (*Aggregates) ProtoMessage()    {}
+func (*Aggregates) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{12}
+}
+func (m *Aggregates) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Aggregates.Unmarshal(m, b)
+}
+func (m *Aggregates) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Aggregates.Marshal(b, m, deterministic)
+}
+func (dst *Aggregates) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Aggregates.Merge(dst, src)
+}
+func (m *Aggregates) XXX_Size() int {
+	return xxx_messageInfo_Aggregates.Size(m)
+}
+func (m *Aggregates) XXX_DiscardUnknown() {
+	xxx_messageInfo_Aggregates.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Aggregates proto.InternalMessageInfo
+
+func (m *Aggregates) GetFarms() uint32 {
+	if m != nil {
+		return m.Farms
+	}
+	return 0
+}
+
+func (m *Aggregates) GetVillagers() []*VillagerAggregate {
+	if m != nil {
+		return m.Villagers
+	}
+	return nil
+}
+
+type EnqueueRequest struct {
+	Ids                  []string `protobuf:"bytes,1,rep,name=ids" json:"ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EnqueueRequest) Reset()         { *m = EnqueueRequest{} }
+func (m *EnqueueRequest) String() string { return proto.CompactTextString(m) }
+func (*EnqueueRequest) ProtoMessage()    {}
+func (*EnqueueRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{13}
+}
+func (m *EnqueueRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EnqueueRequest.Unmarshal(m, b)
+}
+func (m *EnqueueRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EnqueueRequest.Marshal(b, m, deterministic)
+}
+func (dst *EnqueueRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EnqueueRequest.Merge(dst, src)
+}
+func (m *EnqueueRequest) XXX_Size() int {
+	return xxx_messageInfo_EnqueueRequest.Size(m)
+}
+func (m *EnqueueRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_EnqueueRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EnqueueRequest proto.InternalMessageInfo
+
+func (m *EnqueueRequest) GetIds() []string {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+type RejectedFarmID struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Reason               string   `protobuf:"bytes,2,opt,name=reason" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RejectedFarmID) Reset()         { *m = RejectedFarmID{} }
+func (m *RejectedFarmID) String() string { return proto.CompactTextString(m) }
+func (*RejectedFarmID) ProtoMessage()    {}
+func (*RejectedFarmID) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{14}
+}
+func (m *RejectedFarmID) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RejectedFarmID.Unmarshal(m, b)
+}
+func (m *RejectedFarmID) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RejectedFarmID.Marshal(b, m, deterministic)
+}
+func (dst *RejectedFarmID) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RejectedFarmID.Merge(dst, src)
+}
+func (m *RejectedFarmID) XXX_Size() int {
+	return xxx_messageInfo_RejectedFarmID.Size(m)
+}
+func (m *RejectedFarmID) XXX_DiscardUnknown() {
+	xxx_messageInfo_RejectedFarmID.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RejectedFarmID proto.InternalMessageInfo
+
+func (m *RejectedFarmID) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *RejectedFarmID) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+type EnqueueResponse struct {
+	Queued               []string          `protobuf:"bytes,1,rep,name=queued" json:"queued,omitempty"`
+	Rejected             []*RejectedFarmID `protobuf:"bytes,2,rep,name=rejected" json:"rejected,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *EnqueueResponse) Reset()         { *m = EnqueueResponse{} }
+func (m *EnqueueResponse) String() string { return proto.CompactTextString(m) }
+func (*EnqueueResponse) ProtoMessage()    {}
+func (*EnqueueResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{15}
+}
+func (m *EnqueueResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EnqueueResponse.Unmarshal(m, b)
+}
+func (m *EnqueueResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EnqueueResponse.Marshal(b, m, deterministic)
+}
+func (dst *EnqueueResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EnqueueResponse.Merge(dst, src)
+}
+func (m *EnqueueResponse) XXX_Size() int {
+	return xxx_messageInfo_EnqueueResponse.Size(m)
+}
+func (m *EnqueueResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_EnqueueResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EnqueueResponse proto.InternalMessageInfo
+
+func (m *EnqueueResponse) GetQueued() []string {
+	if m != nil {
+		return m.Queued
+	}
+	return nil
+}
+
+func (m *EnqueueResponse) GetRejected() []*RejectedFarmID {
+	if m != nil {
+		return m.Rejected
+	}
+	return nil
+}
+
+type FetchRecentsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FetchRecentsRequest) Reset()         { *m = FetchRecentsRequest{} }
+func (m *FetchRecentsRequest) String() string { return proto.CompactTextString(m) }
+func (*FetchRecentsRequest) ProtoMessage()    {}
+func (*FetchRecentsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{16}
+}
+func (m *FetchRecentsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FetchRecentsRequest.Unmarshal(m, b)
+}
+func (m *FetchRecentsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FetchRecentsRequest.Marshal(b, m, deterministic)
+}
+func (dst *FetchRecentsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FetchRecentsRequest.Merge(dst, src)
+}
+func (m *FetchRecentsRequest) XXX_Size() int {
+	return xxx_messageInfo_FetchRecentsRequest.Size(m)
+}
+func (m *FetchRecentsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FetchRecentsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FetchRecentsRequest proto.InternalMessageInfo
+
+type ImportRequest struct {
+	// the next chunk of the file's contents: one id or URL per line, a
+	// JSON array of them, or CSV
+	Data                 []byte   `protobuf:"bytes,1,opt,name=data" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ImportRequest) Reset()         { *m = ImportRequest{} }
+func (m *ImportRequest) String() string { return proto.CompactTextString(m) }
+func (*ImportRequest) ProtoMessage()    {}
+func (*ImportRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{17}
+}
+func (m *ImportRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ImportRequest.Unmarshal(m, b)
+}
+func (m *ImportRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ImportRequest.Marshal(b, m, deterministic)
+}
+func (dst *ImportRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ImportRequest.Merge(dst, src)
+}
+func (m *ImportRequest) XXX_Size() int {
+	return xxx_messageInfo_ImportRequest.Size(m)
+}
+func (m *ImportRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ImportRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ImportRequest proto.InternalMessageInfo
+
+func (m *ImportRequest) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+type ImportProgress struct {
+	// ids and URLs found in the file
+	Entries uint32 `protobuf:"varint,1,opt,name=entries" json:"entries,omitempty"`
+	// entries dealt with so far
+	Processed uint32 `protobuf:"varint,2,opt,name=processed" json:"processed,omitempty"`
+	Queued    uint32 `protobuf:"varint,3,opt,name=queued" json:"queued,omitempty"`
+	// entries for farms already seen earlier in the file
+	Duplicates uint32 `protobuf:"varint,4,opt,name=duplicates" json:"duplicates,omitempty"`
+	// entries rejected since the previous progress report
+	Rejected             []*RejectedFarmID `protobuf:"bytes,5,rep,name=rejected" json:"rejected,omitempty"`
+	Done                 bool              `protobuf:"varint,6,opt,name=done" json:"done,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *ImportProgress) Reset()         { *m = ImportProgress{} }
+func (m *ImportProgress) String() string { return proto.CompactTextString(m) }
+func (*ImportProgress) ProtoMessage()    {}
+func (*ImportProgress) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{18}
+}
+func (m *ImportProgress) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ImportProgress.Unmarshal(m, b)
+}
+func (m *ImportProgress) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ImportProgress.Marshal(b, m, deterministic)
+}
+func (dst *ImportProgress) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ImportProgress.Merge(dst, src)
+}
+func (m *ImportProgress) XXX_Size() int {
+	return xxx_messageInfo_ImportProgress.Size(m)
+}
+func (m *ImportProgress) XXX_DiscardUnknown() {
+	xxx_messageInfo_ImportProgress.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ImportProgress proto.InternalMessageInfo
+
+func (m *ImportProgress) GetEntries() uint32 {
+	if m != nil {
+		return m.Entries
+	}
+	return 0
+}
+
+func (m *ImportProgress) GetProcessed() uint32 {
+	if m != nil {
+		return m.Processed
+	}
+	return 0
+}
+
+func (m *ImportProgress) GetQueued() uint32 {
+	if m != nil {
+		return m.Queued
+	}
+	return 0
+}
+
+func (m *ImportProgress) GetDuplicates() uint32 {
+	if m != nil {
+		return m.Duplicates
+	}
+	return 0
+}
+
+func (m *ImportProgress) GetRejected() []*RejectedFarmID {
+	if m != nil {
+		return m.Rejected
+	}
+	return nil
+}
+
+func (m *ImportProgress) GetDone() bool {
+	if m != nil {
+		return m.Done
+	}
+	return false
+}
+
+type StartSpiderRequest struct {
+	Mode                 StartSpiderRequest_Mode `protobuf:"varint,1,opt,name=mode,proto3,enum=farmstats.StartSpiderRequest_Mode" json:"mode,omitempty"`
+	Page                 uint32                  `protobuf:"varint,2,opt,name=page" json:"page,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
+	XXX_unrecognized     []byte                  `json:"-"`
+	XXX_sizecache        int32                   `json:"-"`
+}
+
+func (m *StartSpiderRequest) Reset()         { *m = StartSpiderRequest{} }
+func (m *StartSpiderRequest) String() string { return proto.CompactTextString(m) }
+func (*StartSpiderRequest) ProtoMessage()    {}
+func (*StartSpiderRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{19}
+}
+func (m *StartSpiderRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StartSpiderRequest.Unmarshal(m, b)
+}
+func (m *StartSpiderRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StartSpiderRequest.Marshal(b, m, deterministic)
+}
+func (dst *StartSpiderRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StartSpiderRequest.Merge(dst, src)
+}
+func (m *StartSpiderRequest) XXX_Size() int {
+	return xxx_messageInfo_StartSpiderRequest.Size(m)
+}
+func (m *StartSpiderRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StartSpiderRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StartSpiderRequest proto.InternalMessageInfo
+
+func (m *StartSpiderRequest) GetMode() StartSpiderRequest_Mode {
+	if m != nil {
+		return m.Mode
+	}
+	return StartSpiderRequest_HOMEPAGE
+}
+
+func (m *StartSpiderRequest) GetPage() uint32 {
+	if m != nil {
+		return m.Page
+	}
+	return 0
+}
+
+type StopSpidersRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StopSpidersRequest) Reset()         { *m = StopSpidersRequest{} }
+func (m *StopSpidersRequest) String() string { return proto.CompactTextString(m) }
+func (*StopSpidersRequest) ProtoMessage()    {}
+func (*StopSpidersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{20}
+}
+func (m *StopSpidersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StopSpidersRequest.Unmarshal(m, b)
+}
+func (m *StopSpidersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StopSpidersRequest.Marshal(b, m, deterministic)
+}
+func (dst *StopSpidersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StopSpidersRequest.Merge(dst, src)
+}
+func (m *StopSpidersRequest) XXX_Size() int {
+	return xxx_messageInfo_StopSpidersRequest.Size(m)
+}
+func (m *StopSpidersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StopSpidersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StopSpidersRequest proto.InternalMessageInfo
+
+type SpiderStatusRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SpiderStatusRequest) Reset()         { *m = SpiderStatusRequest{} }
+func (m *SpiderStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*SpiderStatusRequest) ProtoMessage()    {}
+func (*SpiderStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{21}
+}
+func (m *SpiderStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SpiderStatusRequest.Unmarshal(m, b)
+}
+func (m *SpiderStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SpiderStatusRequest.Marshal(b, m, deterministic)
+}
+func (dst *SpiderStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SpiderStatusRequest.Merge(dst, src)
+}
+func (m *SpiderStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_SpiderStatusRequest.Size(m)
+}
+func (m *SpiderStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SpiderStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SpiderStatusRequest proto.InternalMessageInfo
+
+type SpiderStatus struct {
+	// multi-page spiders still running
+	Running uint32 `protobuf:"varint,1,opt,name=running" json:"running,omitempty"`
+	// true once spiders have been asked to stop
+	Stopping             bool     `protobuf:"varint,2,opt,name=stopping" json:"stopping,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SpiderStatus) Reset()         { *m = SpiderStatus{} }
+func (m *SpiderStatus) String() string { return proto.CompactTextString(m) }
+func (*SpiderStatus) ProtoMessage()    {}
+func (*SpiderStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{22}
+}
+func (m *SpiderStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SpiderStatus.Unmarshal(m, b)
+}
+func (m *SpiderStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SpiderStatus.Marshal(b, m, deterministic)
+}
+func (dst *SpiderStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SpiderStatus.Merge(dst, src)
+}
+func (m *SpiderStatus) XXX_Size() int {
+	return xxx_messageInfo_SpiderStatus.Size(m)
+}
+func (m *SpiderStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_SpiderStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SpiderStatus proto.InternalMessageInfo
+
+func (m *SpiderStatus) GetRunning() uint32 {
+	if m != nil {
+		return m.Running
+	}
+	return 0
+}
+
+func (m *SpiderStatus) GetStopping() bool {
+	if m != nil {
+		return m.Stopping
+	}
+	return false
+}
+
+type QueueStatusRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *QueueStatusRequest) Reset()         { *m = QueueStatusRequest{} }
+func (m *QueueStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*QueueStatusRequest) ProtoMessage()    {}
+func (*QueueStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{23}
+}
+func (m *QueueStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueueStatusRequest.Unmarshal(m, b)
+}
+func (m *QueueStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueueStatusRequest.Marshal(b, m, deterministic)
+}
+func (dst *QueueStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueueStatusRequest.Merge(dst, src)
+}
+func (m *QueueStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_QueueStatusRequest.Size(m)
+}
+func (m *QueueStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueueStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueueStatusRequest proto.InternalMessageInfo
+
+type QueueStatus struct {
+	// farms waiting in every lane, and how many the lanes can hold
+	Farms         uint32 `protobuf:"varint,1,opt,name=farms" json:"farms,omitempty"`
+	FarmsCapacity uint32 `protobuf:"varint,2,opt,name=farms_capacity,json=farmsCapacity" json:"farms_capacity,omitempty"`
+	Stats         uint32 `protobuf:"varint,3,opt,name=stats" json:"stats,omitempty"`
+	StatsCapacity uint32 `protobuf:"varint,4,opt,name=stats_capacity,json=statsCapacity" json:"stats_capacity,omitempty"`
+	// the farm queue by lane, highest priority first
+	Lanes                []*QueueLane `protobuf:"bytes,5,rep,name=lanes" json:"lanes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *QueueStatus) Reset()         { *m = QueueStatus{} }
+func (m *QueueStatus) String() string { return proto.CompactTextString(m) }
+func (*QueueStatus) ProtoMessage()    {}
+func (*QueueStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{24}
+}
+func (m *QueueStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueueStatus.Unmarshal(m, b)
+}
+func (m *QueueStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueueStatus.Marshal(b, m, deterministic)
+}
+func (dst *QueueStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueueStatus.Merge(dst, src)
+}
+func (m *QueueStatus) XXX_Size() int {
+	return xxx_messageInfo_QueueStatus.Size(m)
+}
+func (m *QueueStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueueStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueueStatus proto.InternalMessageInfo
+
+func (m *QueueStatus) GetFarms() uint32 {
+	if m != nil {
+		return m.Farms
+	}
+	return 0
+}
+
+func (m *QueueStatus) GetFarmsCapacity() uint32 {
+	if m != nil {
+		return m.FarmsCapacity
+	}
+	return 0
+}
+
+func (m *QueueStatus) GetStats() uint32 {
+	if m != nil {
+		return m.Stats
+	}
+	return 0
+}
+
+func (m *QueueStatus) GetStatsCapacity() uint32 {
+	if m != nil {
+		return m.StatsCapacity
+	}
+	return 0
+}
+
+func (m *QueueStatus) GetLanes() []*QueueLane {
+	if m != nil {
+		return m.Lanes
+	}
+	return nil
+}
+
+type QueueLane struct {
+	Name     string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Farms    uint32 `protobuf:"varint,2,opt,name=farms" json:"farms,omitempty"`
+	Capacity uint32 `protobuf:"varint,3,opt,name=capacity" json:"capacity,omitempty"`
+	// share of the workers while every lane has farms waiting
+	Weight               uint32   `protobuf:"varint,4,opt,name=weight" json:"weight,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *QueueLane) Reset()         { *m = QueueLane{} }
+func (m *QueueLane) String() string { return proto.CompactTextString(m) }
+func (*QueueLane) ProtoMessage()    {}
+func (*QueueLane) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{25}
+}
+func (m *QueueLane) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueueLane.Unmarshal(m, b)
+}
+func (m *QueueLane) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueueLane.Marshal(b, m, deterministic)
+}
+func (dst *QueueLane) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueueLane.Merge(dst, src)
+}
+func (m *QueueLane) XXX_Size() int {
+	return xxx_messageInfo_QueueLane.Size(m)
+}
+func (m *QueueLane) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueueLane.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueueLane proto.InternalMessageInfo
+
+func (m *QueueLane) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *QueueLane) GetFarms() uint32 {
+	if m != nil {
+		return m.Farms
+	}
+	return 0
+}
+
+func (m *QueueLane) GetCapacity() uint32 {
+	if m != nil {
+		return m.Capacity
+	}
+	return 0
+}
+
+func (m *QueueLane) GetWeight() uint32 {
+	if m != nil {
+		return m.Weight
+	}
+	return 0
+}
+
+type WorkersRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WorkersRequest) Reset()         { *m = WorkersRequest{} }
+func (m *WorkersRequest) String() string { return proto.CompactTextString(m) }
+func (*WorkersRequest) ProtoMessage()    {}
+func (*WorkersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{26}
+}
+func (m *WorkersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WorkersRequest.Unmarshal(m, b)
+}
+func (m *WorkersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WorkersRequest.Marshal(b, m, deterministic)
+}
+func (dst *WorkersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WorkersRequest.Merge(dst, src)
+}
+func (m *WorkersRequest) XXX_Size() int {
+	return xxx_messageInfo_WorkersRequest.Size(m)
+}
+func (m *WorkersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WorkersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WorkersRequest proto.InternalMessageInfo
+
+type SetWorkersRequest struct {
+	// workers to run; ignored when auto is set
+	Size uint32 `protobuf:"varint,1,opt,name=size" json:"size,omitempty"`
+	// go back to autoscaling between the configured min and max
+	Auto                 bool     `protobuf:"varint,2,opt,name=auto" json:"auto,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetWorkersRequest) Reset()         { *m = SetWorkersRequest{} }
+func (m *SetWorkersRequest) String() string { return proto.CompactTextString(m) }
+func (*SetWorkersRequest) ProtoMessage()    {}
+func (*SetWorkersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{27}
+}
+func (m *SetWorkersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetWorkersRequest.Unmarshal(m, b)
+}
+func (m *SetWorkersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetWorkersRequest.Marshal(b, m, deterministic)
+}
+func (dst *SetWorkersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetWorkersRequest.Merge(dst, src)
+}
+func (m *SetWorkersRequest) XXX_Size() int {
+	return xxx_messageInfo_SetWorkersRequest.Size(m)
+}
+func (m *SetWorkersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetWorkersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetWorkersRequest proto.InternalMessageInfo
+
+func (m *SetWorkersRequest) GetSize() uint32 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *SetWorkersRequest) GetAuto() bool {
+	if m != nil {
+		return m.Auto
+	}
+	return false
+}
+
+type WorkerPool struct {
+	Size uint32 `protobuf:"varint,1,opt,name=size" json:"size,omitempty"`
+	Min  uint32 `protobuf:"varint,2,opt,name=min" json:"min,omitempty"`
+	Max  uint32 `protobuf:"varint,3,opt,name=max" json:"max,omitempty"`
+	// true while the size is set by hand rather than autoscaled
+	Pinned bool `protobuf:"varint,4,opt,name=pinned" json:"pinned,omitempty"`
+	// farms processed by the pool in the last minute
+	PerMinute            uint32    `protobuf:"varint,5,opt,name=per_minute,json=perMinute" json:"per_minute,omitempty"`
+	Workers              []*Worker `protobuf:"bytes,6,rep,name=workers" json:"workers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *WorkerPool) Reset()         { *m = WorkerPool{} }
+func (m *WorkerPool) String() string { return proto.CompactTextString(m) }
+func (*WorkerPool) ProtoMessage()    {}
+func (*WorkerPool) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{28}
+}
+func (m *WorkerPool) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WorkerPool.Unmarshal(m, b)
+}
+func (m *WorkerPool) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WorkerPool.Marshal(b, m, deterministic)
+}
+func (dst *WorkerPool) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WorkerPool.Merge(dst, src)
+}
+func (m *WorkerPool) XXX_Size() int {
+	return xxx_messageInfo_WorkerPool.Size(m)
+}
+func (m *WorkerPool) XXX_DiscardUnknown() {
+	xxx_messageInfo_WorkerPool.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WorkerPool proto.InternalMessageInfo
+
+func (m *WorkerPool) GetSize() uint32 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *WorkerPool) GetMin() uint32 {
+	if m != nil {
+		return m.Min
+	}
+	return 0
+}
+
+func (m *WorkerPool) GetMax() uint32 {
+	if m != nil {
+		return m.Max
+	}
+	return 0
+}
+
+func (m *WorkerPool) GetPinned() bool {
+	if m != nil {
+		return m.Pinned
+	}
+	return false
+}
+
+func (m *WorkerPool) GetPerMinute() uint32 {
+	if m != nil {
+		return m.PerMinute
+	}
+	return 0
+}
+
+func (m *WorkerPool) GetWorkers() []*Worker {
+	if m != nil {
+		return m.Workers
+	}
+	return nil
+}
+
+type Worker struct {
+	Id uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	// idle, fetching or parsing
+	State  string `protobuf:"bytes,2,opt,name=state" json:"state,omitempty"`
+	FarmId string `protobuf:"bytes,3,opt,name=farm_id,json=farmId" json:"farm_id,omitempty"`
+	// unix timestamp of the last change of state
+	Since     int64  `protobuf:"varint,4,opt,name=since" json:"since,omitempty"`
+	Processed uint32 `protobuf:"varint,5,opt,name=processed" json:"processed,omitempty"`
+	Failed    uint32 `protobuf:"varint,6,opt,name=failed" json:"failed,omitempty"`
+	// farms processed per minute since the worker started
+	PerMinute            float64  `protobuf:"fixed64,7,opt,name=per_minute,json=perMinute" json:"per_minute,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Worker) Reset()         { *m = Worker{} }
+func (m *Worker) String() string { return proto.CompactTextString(m) }
+func (*Worker) ProtoMessage()    {}
+func (*Worker) Descriptor() ([]byte, []int) {
+	return fileDescriptor_farmstats_d273247a14c0c724, []int{29}
+}
+func (m *Worker) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Worker.Unmarshal(m, b)
+}
+func (m *Worker) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Worker.Marshal(b, m, deterministic)
+}
+func (dst *Worker) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Worker.Merge(dst, src)
+}
+func (m *Worker) XXX_Size() int {
+	return xxx_messageInfo_Worker.Size(m)
+}
+func (m *Worker) XXX_DiscardUnknown() {
+	xxx_messageInfo_Worker.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Worker proto.InternalMessageInfo
+
+func (m *Worker) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *Worker) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *Worker) GetFarmId() string {
+	if m != nil {
+		return m.FarmId
+	}
+	return ""
+}
+
+func (m *Worker) GetSince() int64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+func (m *Worker) GetProcessed() uint32 {
+	if m != nil {
+		return m.Processed
+	}
+	return 0
+}
+
+func (m *Worker) GetFailed() uint32 {
+	if m != nil {
+		return m.Failed
+	}
+	return 0
+}
+
+func (m *Worker) GetPerMinute() float64 {
+	if m != nil {
+		return m.PerMinute
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*FarmID)(nil), "farmstats.FarmID")
+	proto.RegisterType((*Farm)(nil), "farmstats.Farm")
+	proto.RegisterType((*Response)(nil), "farmstats.Response")
+	proto.RegisterType((*FarmHistoryRequest)(nil), "farmstats.FarmHistoryRequest")
+	proto.RegisterType((*Snapshot)(nil), "farmstats.Snapshot")
+	proto.RegisterType((*VillagerDelta)(nil), "farmstats.VillagerDelta")
+	proto.RegisterType((*FarmHistory)(nil), "farmstats.FarmHistory")
+	proto.RegisterType((*ListFarmsRequest)(nil), "farmstats.ListFarmsRequest")
+	proto.RegisterType((*FarmList)(nil), "farmstats.FarmList")
+	proto.RegisterType((*WatchFarmsRequest)(nil), "farmstats.WatchFarmsRequest")
+	proto.RegisterType((*AggregateRequest)(nil), "farmstats.AggregateRequest")
+	proto.RegisterType((*VillagerAggregate)(nil), "farmstats.VillagerAggregate")
+	proto.RegisterType((*Aggregates)(nil), "farmstats.Aggregates")
+	proto.RegisterType((*EnqueueRequest)(nil), "farmstats.EnqueueRequest")
+	proto.RegisterType((*RejectedFarmID)(nil), "farmstats.RejectedFarmID")
+	proto.RegisterType((*EnqueueResponse)(nil), "farmstats.EnqueueResponse")
+	proto.RegisterType((*FetchRecentsRequest)(nil), "farmstats.FetchRecentsRequest")
+	proto.RegisterType((*ImportRequest)(nil), "farmstats.ImportRequest")
+	proto.RegisterType((*ImportProgress)(nil), "farmstats.ImportProgress")
+	proto.RegisterType((*StartSpiderRequest)(nil), "farmstats.StartSpiderRequest")
+	proto.RegisterType((*StopSpidersRequest)(nil), "farmstats.StopSpidersRequest")
+	proto.RegisterType((*SpiderStatusRequest)(nil), "farmstats.SpiderStatusRequest")
+	proto.RegisterType((*SpiderStatus)(nil), "farmstats.SpiderStatus")
+	proto.RegisterType((*QueueStatusRequest)(nil), "farmstats.QueueStatusRequest")
+	proto.RegisterType((*QueueStatus)(nil), "farmstats.QueueStatus")
+	proto.RegisterType((*QueueLane)(nil), "farmstats.QueueLane")
+	proto.RegisterType((*WorkersRequest)(nil), "farmstats.WorkersRequest")
+	proto.RegisterType((*SetWorkersRequest)(nil), "farmstats.SetWorkersRequest")
+	proto.RegisterType((*WorkerPool)(nil), "farmstats.WorkerPool")
+	proto.RegisterType((*Worker)(nil), "farmstats.Worker")
+	proto.RegisterEnum("farmstats.StartSpiderRequest_Mode", StartSpiderRequest_Mode_name, StartSpiderRequest_Mode_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// FarmStatsClient is the client API for FarmStats service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type FarmStatsClient interface {
+	// Get the stats for a given farm. Errors carry google.rpc error details:
+	// RetryInfo when the farm is queued, ErrorInfo when its last scrape failed
+	GetStats(ctx context.Context, in *FarmID, opts ...grpc.CallOption) (*Farm, error)
+	// Get every stored version of a farm, with the changes between two of them
+	GetFarmHistory(ctx context.Context, in *FarmHistoryRequest, opts ...grpc.CallOption) (*FarmHistory, error)
+	// List stored farms in id order, a page at a time
+	ListFarms(ctx context.Context, in *ListFarmsRequest, opts ...grpc.CallOption) (*FarmList, error)
+	// Stream farms as they are stored
+	WatchFarms(ctx context.Context, in *WatchFarmsRequest, opts ...grpc.CallOption) (FarmStats_WatchFarmsClient, error)
+	// Summarise friendship levels across every stored farm
+	Aggregate(ctx context.Context, in *AggregateRequest, opts ...grpc.CallOption) (*Aggregates, error)
+}
+
+type farmStatsClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewFarmStatsClient(cc *grpc.ClientConn) FarmStatsClient {
+	return &farmStatsClient{cc}
+}
+
+func (c *farmStatsClient) GetStats(ctx context.Context, in *FarmID, opts ...grpc.CallOption) (*Farm, error) {
+	out := new(Farm)
+	err := c.cc.Invoke(ctx, "/farmstats.FarmStats/GetStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *farmStatsClient) GetFarmHistory(ctx context.Context, in *FarmHistoryRequest, opts ...grpc.CallOption) (*FarmHistory, error) {
+	out := new(FarmHistory)
+	err := c.cc.Invoke(ctx, "/farmstats.FarmStats/GetFarmHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *farmStatsClient) ListFarms(ctx context.Context, in *ListFarmsRequest, opts ...grpc.CallOption) (*FarmList, error) {
+	out := new(FarmList)
+	err := c.cc.Invoke(ctx, "/farmstats.FarmStats/ListFarms", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *farmStatsClient) WatchFarms(ctx context.Context, in *WatchFarmsRequest, opts ...grpc.CallOption) (FarmStats_WatchFarmsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_FarmStats_serviceDesc.Streams[0], "/farmstats.FarmStats/WatchFarms", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &farmStatsWatchFarmsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FarmStats_WatchFarmsClient interface {
+	Recv() (*Farm, error)
+	grpc.ClientStream
+}
+
+type farmStatsWatchFarmsClient struct {
+	grpc.ClientStream
+}
+
+func (x *farmStatsWatchFarmsClient) Recv() (*Farm, error) {
+	m := new(Farm)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *farmStatsClient) Aggregate(ctx context.Context, in *AggregateRequest, opts ...grpc.CallOption) (*Aggregates, error) {
+	out := new(Aggregates)
+	err := c.cc.Invoke(ctx, "/farmstats.FarmStats/Aggregate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for FarmStats service
+
+type FarmStatsServer interface {
+	// Get the stats for a given farm. Errors carry google.rpc error details:
+	// RetryInfo when the farm is queued, ErrorInfo when its last scrape failed
+	GetStats(context.Context, *FarmID) (*Farm, error)
+	// Get every stored version of a farm, with the changes between two of them
+	GetFarmHistory(context.Context, *FarmHistoryRequest) (*FarmHistory, error)
+	// List stored farms in id order, a page at a time
+	ListFarms(context.Context, *ListFarmsRequest) (*FarmList, error)
+	// Stream farms as they are stored
+	WatchFarms(*WatchFarmsRequest, FarmStats_WatchFarmsServer) error
+	// Summarise friendship levels across every stored farm
+	Aggregate(context.Context, *AggregateRequest) (*Aggregates, error)
+}
+
+func RegisterFarmStatsServer(s *grpc.Server, srv FarmStatsServer) {
+	s.RegisterService(&_FarmStats_serviceDesc, srv)
+}
+
+func _FarmStats_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FarmID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FarmStatsServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/farmstats.FarmStats/GetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FarmStatsServer).GetStats(ctx, req.(*FarmID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FarmStats_GetFarmHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FarmHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FarmStatsServer).GetFarmHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/farmstats.FarmStats/GetFarmHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FarmStatsServer).GetFarmHistory(ctx, req.(*FarmHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FarmStats_ListFarms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFarmsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FarmStatsServer).ListFarms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/farmstats.FarmStats/ListFarms",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FarmStatsServer).ListFarms(ctx, req.(*ListFarmsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FarmStats_WatchFarms_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchFarmsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FarmStatsServer).WatchFarms(m, &farmStatsWatchFarmsServer{stream})
+}
+
+type FarmStats_WatchFarmsServer interface {
+	Send(*Farm) error
+	grpc.ServerStream
+}
+
+type farmStatsWatchFarmsServer struct {
+	grpc.ServerStream
+}
+
+func (x *farmStatsWatchFarmsServer) Send(m *Farm) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _FarmStats_Aggregate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AggregateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FarmStatsServer).Aggregate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/farmstats.FarmStats/Aggregate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FarmStatsServer).Aggregate(ctx, req.(*AggregateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _FarmStats_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "farmstats.FarmStats",
+	HandlerType: (*FarmStatsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStats",
+			Handler:    _FarmStats_GetStats_Handler,
+		},
+		{
+			MethodName: "GetFarmHistory",
+			Handler:    _FarmStats_GetFarmHistory_Handler,
+		},
+		{
+			MethodName: "ListFarms",
+			Handler:    _FarmStats_ListFarms_Handler,
+		},
+		{
+			MethodName: "Aggregate",
+			Handler:    _FarmStats_Aggregate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchFarms",
+			Handler:       _FarmStats_WatchFarms_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "farmstats.proto",
+}
+
+// AdminClient is the client API for Admin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AdminClient interface {
+	// Queue farms to be fetched
+	Enqueue(ctx context.Context, in *EnqueueRequest, opts ...grpc.CallOption) (*EnqueueResponse, error)
+	// Fetch the recent farms list and queue every farm on it
+	FetchRecents(ctx context.Context, in *FetchRecentsRequest, opts ...grpc.CallOption) (*EnqueueResponse, error)
+	// Queue every farm id or upload.farm URL in a text, JSON or CSV file,
+	// sent in chunks, reporting progress as it goes
+	Import(ctx context.Context, opts ...grpc.CallOption) (Admin_ImportClient, error)
+	// Start a spider over the farm listing
+	StartSpider(ctx context.Context, in *StartSpiderRequest, opts ...grpc.CallOption) (*SpiderStatus, error)
+	// Ask running multi-page spiders to stop
+	StopSpiders(ctx context.Context, in *StopSpidersRequest, opts ...grpc.CallOption) (*SpiderStatus, error)
+	GetSpiderStatus(ctx context.Context, in *SpiderStatusRequest, opts ...grpc.CallOption) (*SpiderStatus, error)
+	GetQueueStatus(ctx context.Context, in *QueueStatusRequest, opts ...grpc.CallOption) (*QueueStatus, error)
+	// Show the farm workers and what each is doing
+	GetWorkers(ctx context.Context, in *WorkersRequest, opts ...grpc.CallOption) (*WorkerPool, error)
+	// Pin the worker pool at a size, or let it autoscale again
+	SetWorkers(ctx context.Context, in *SetWorkersRequest, opts ...grpc.CallOption) (*WorkerPool, error)
+}
+
+type adminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAdminClient(cc *grpc.ClientConn) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) Enqueue(ctx context.Context, in *EnqueueRequest, opts ...grpc.CallOption) (*EnqueueResponse, error) {
+	out := new(EnqueueResponse)
+	err := c.cc.Invoke(ctx, "/farmstats.Admin/Enqueue", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) FetchRecents(ctx context.Context, in *FetchRecentsRequest, opts ...grpc.CallOption) (*EnqueueResponse, error) {
+	out := new(EnqueueResponse)
+	err := c.cc.Invoke(ctx, "/farmstats.Admin/FetchRecents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Import(ctx context.Context, opts ...grpc.CallOption) (Admin_ImportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Admin_serviceDesc.Streams[0], "/farmstats.Admin/Import", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adminImportClient{stream}
+	return x, nil
+}
+
+type Admin_ImportClient interface {
+	Send(*ImportRequest) error
+	Recv() (*ImportProgress, error)
+	grpc.ClientStream
+}
+
+type adminImportClient struct {
+	grpc.ClientStream
+}
+
+func (x *adminImportClient) Send(m *ImportRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *adminImportClient) Recv() (*ImportProgress, error) {
+	m := new(ImportProgress)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *adminClient) StartSpider(ctx context.Context, in *StartSpiderRequest, opts ...grpc.CallOption) (*SpiderStatus, error) {
+	out := new(SpiderStatus)
+	err := c.cc.Invoke(ctx, "/farmstats.Admin/StartSpider", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) StopSpiders(ctx context.Context, in *StopSpidersRequest, opts ...grpc.CallOption) (*SpiderStatus, error) {
+	out := new(SpiderStatus)
+	err := c.cc.Invoke(ctx, "/farmstats.Admin/StopSpiders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetSpiderStatus(ctx context.Context, in *SpiderStatusRequest, opts ...grpc.CallOption) (*SpiderStatus, error) {
+	out := new(SpiderStatus)
+	err := c.cc.Invoke(ctx, "/farmstats.Admin/GetSpiderStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetQueueStatus(ctx context.Context, in *QueueStatusRequest, opts ...grpc.CallOption) (*QueueStatus, error) {
+	out := new(QueueStatus)
+	err := c.cc.Invoke(ctx, "/farmstats.Admin/GetQueueStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetWorkers(ctx context.Context, in *WorkersRequest, opts ...grpc.CallOption) (*WorkerPool, error) {
+	out := new(WorkerPool)
+	err := c.cc.Invoke(ctx, "/farmstats.Admin/GetWorkers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) SetWorkers(ctx context.Context, in *SetWorkersRequest, opts ...grpc.CallOption) (*WorkerPool, error) {
+	out := new(WorkerPool)
+	err := c.cc.Invoke(ctx, "/farmstats.Admin/SetWorkers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Admin service
+
+type AdminServer interface {
+	// Queue farms to be fetched
+	Enqueue(context.Context, *EnqueueRequest) (*EnqueueResponse, error)
+	// Fetch the recent farms list and queue every farm on it
+	FetchRecents(context.Context, *FetchRecentsRequest) (*EnqueueResponse, error)
+	// Queue every farm id or upload.farm URL in a text, JSON or CSV file,
+	// sent in chunks, reporting progress as it goes
+	Import(Admin_ImportServer) error
+	// Start a spider over the farm listing
+	StartSpider(context.Context, *StartSpiderRequest) (*SpiderStatus, error)
+	// Ask running multi-page spiders to stop
+	StopSpiders(context.Context, *StopSpidersRequest) (*SpiderStatus, error)
+	GetSpiderStatus(context.Context, *SpiderStatusRequest) (*SpiderStatus, error)
+	GetQueueStatus(context.Context, *QueueStatusRequest) (*QueueStatus, error)
+	// Show the farm workers and what each is doing
+	GetWorkers(context.Context, *WorkersRequest) (*WorkerPool, error)
+	// Pin the worker pool at a size, or let it autoscale again
+	SetWorkers(context.Context, *SetWorkersRequest) (*WorkerPool, error)
+}
+
+func RegisterAdminServer(s *grpc.Server, srv AdminServer) {
+	s.RegisterService(&_Admin_serviceDesc, srv)
+}
+
+func _Admin_Enqueue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnqueueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Enqueue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/farmstats.Admin/Enqueue",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Enqueue(ctx, req.(*EnqueueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_FetchRecents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FetchRecentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).FetchRecents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/farmstats.Admin/FetchRecents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).FetchRecents(ctx, req.(*FetchRecentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Import_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AdminServer).Import(&adminImportServer{stream})
+}
+
+type Admin_ImportServer interface {
+	Send(*ImportProgress) error
+	Recv() (*ImportRequest, error)
+	grpc.ServerStream
+}
+
+type adminImportServer struct {
+	grpc.ServerStream
+}
+
+func (x *adminImportServer) Send(m *ImportProgress) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *adminImportServer) Recv() (*ImportRequest, error) {
+	m := new(ImportRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Admin_StartSpider_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartSpiderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).StartSpider(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/farmstats.Admin/StartSpider",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).StartSpider(ctx, req.(*StartSpiderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_StopSpiders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopSpidersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).StopSpiders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/farmstats.Admin/StopSpiders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).StopSpiders(ctx, req.(*StopSpidersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetSpiderStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SpiderStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetSpiderStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/farmstats.Admin/GetSpiderStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetSpiderStatus(ctx, req.(*SpiderStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetQueueStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueueStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetQueueStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/farmstats.Admin/GetQueueStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetQueueStatus(ctx, req.(*QueueStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetWorkers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetWorkers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/farmstats.Admin/GetWorkers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetWorkers(ctx, req.(*WorkersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_SetWorkers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetWorkersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).SetWorkers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/farmstats.Admin/SetWorkers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).SetWorkers(ctx, req.(*SetWorkersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Admin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "farmstats.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Enqueue",
+			Handler:    _Admin_Enqueue_Handler,
+		},
+		{
+			MethodName: "FetchRecents",
+			Handler:    _Admin_FetchRecents_Handler,
+		},
+		{
+			MethodName: "StartSpider",
+			Handler:    _Admin_StartSpider_Handler,
+		},
+		{
+			MethodName: "StopSpiders",
+			Handler:    _Admin_StopSpiders_Handler,
+		},
+		{
+			MethodName: "GetSpiderStatus",
+			Handler:    _Admin_GetSpiderStatus_Handler,
+		},
+		{
+			MethodName: "GetQueueStatus",
+			Handler:    _Admin_GetQueueStatus_Handler,
+		},
+		{
+			MethodName: "GetWorkers",
+			Handler:    _Admin_GetWorkers_Handler,
+		},
+		{
+			MethodName: "SetWorkers",
+			Handler:    _Admin_SetWorkers_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Import",
+			Handler:       _Admin_Import_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "farmstats.proto",
 }
 
@@ -565,43 +2566,117 @@ var _ImgDownload_serviceDesc = grpc.ServiceDesc{
 	Metadata: "farmstats.proto",
 }
 
-func init() { proto.RegisterFile("farmstats.proto", fileDescriptor_farmstats_48e2e7cef98cd1dc) }
-
-var fileDescriptor_farmstats_48e2e7cef98cd1dc = []byte{
-	// 558 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x94, 0x4f, 0x73, 0xd3, 0x3c,
-	0x10, 0xc6, 0xdb, 0xb4, 0x4d, 0x13, 0xf5, 0xbf, 0xda, 0xb7, 0xef, 0x52, 0x0a, 0x04, 0x73, 0xe9,
-	0xa9, 0x03, 0xe5, 0x0c, 0xc3, 0x40, 0xa7, 0x4c, 0xaf, 0xe1, 0xc6, 0x6d, 0x13, 0x6f, 0x63, 0xa5,
-	0xb2, 0xe4, 0x91, 0x9c, 0x04, 0xf3, 0x61, 0xf8, 0xac, 0xcc, 0x6a, 0x9d, 0x04, 0x3a, 0xdc, 0xf6,
-	0xf7, 0x68, 0xf7, 0xf1, 0x33, 0x1a, 0xad, 0xd5, 0xd1, 0x03, 0x86, 0x32, 0xd6, 0x58, 0xc7, 0xeb,
-	0x2a, 0xf8, 0xda, 0xeb, 0xfe, 0x4a, 0xc8, 0x40, 0x75, 0xef, 0x30, 0x94, 0xf7, 0xb7, 0xfa, 0x50,
-	0x75, 0x4c, 0x0e, 0x9b, 0x83, 0xcd, 0xab, 0xfe, 0xb0, 0x63, 0xf2, 0xec, 0x57, 0x57, 0x6d, 0xf3,
-	0xd1, 0xd3, 0x03, 0x0d, 0x6a, 0x17, 0x47, 0x66, 0x82, 0xc6, 0x42, 0x67, 0xb0, 0x79, 0x75, 0x30,
-	0x5c, 0xa2, 0xd6, 0x6a, 0x1b, 0x2d, 0xfd, 0x80, 0xad, 0x24, 0xa7, 0x5a, 0x5f, 0xa8, 0xde, 0x18,
-	0x83, 0xb7, 0xc6, 0x11, 0x6c, 0x27, 0x7d, 0xc5, 0xfa, 0x4c, 0xed, 0x8c, 0xad, 0x71, 0x35, 0xec,
-	0xa4, 0x03, 0x01, 0x7d, 0xa9, 0xfa, 0x39, 0x95, 0x54, 0x07, 0x33, 0x8b, 0xd0, 0x4d, 0x27, 0x6b,
-	0x81, 0x67, 0xf2, 0x05, 0x86, 0x07, 0xd8, 0x95, 0x99, 0x04, 0x9c, 0x89, 0xac, 0x35, 0xbe, 0xae,
-	0xa1, 0x27, 0x99, 0x5a, 0xe4, 0x7e, 0x2a, 0x8d, 0x6d, 0xa0, 0x2f, 0xfd, 0x09, 0xf4, 0xb9, 0xea,
-	0xd2, 0x9c, 0x6c, 0xe3, 0x40, 0x25, 0xb9, 0x25, 0xd6, 0x27, 0xe4, 0xc3, 0x84, 0x60, 0x4f, 0x74,
-	0x21, 0x7d, 0xac, 0xb6, 0x26, 0xb3, 0x08, 0xfb, 0x49, 0xe4, 0x92, 0x7d, 0x0b, 0xb4, 0xd4, 0xc0,
-	0x81, 0xf8, 0x26, 0xe0, 0xf9, 0x02, 0xc3, 0x9c, 0x1a, 0x38, 0x94, 0x79, 0x21, 0xbe, 0x85, 0x82,
-	0xdc, 0xb8, 0x28, 0xd1, 0xc1, 0x91, 0xdc, 0xc2, 0x92, 0xd9, 0x7b, 0x8a, 0x11, 0x8e, 0xc5, 0x7b,
-	0x8a, 0x91, 0xef, 0x71, 0xea, 0x73, 0x03, 0x27, 0x72, 0x8f, 0x5c, 0xb3, 0xf6, 0x48, 0xae, 0x06,
-	0x2d, 0x1a, 0xd7, 0xfc, 0xb5, 0xc7, 0xe0, 0x47, 0xb3, 0x08, 0xa7, 0xf2, 0x35, 0x21, 0xee, 0xb5,
-	0x84, 0x05, 0x9c, 0x49, 0x2f, 0xd7, 0x9c, 0xd7, 0xd2, 0xc2, 0x44, 0xf8, 0x4f, 0xf2, 0x26, 0x48,
-	0xaa, 0x71, 0xb3, 0x08, 0xe7, 0xad, 0xca, 0xc0, 0xbe, 0x25, 0x06, 0x67, 0x08, 0xfe, 0x17, 0x5f,
-	0x21, 0xf6, 0x2d, 0x31, 0xcc, 0x00, 0xc4, 0x97, 0x6b, 0x4e, 0x5f, 0x61, 0x09, 0xcf, 0x24, 0x7d,
-	0x85, 0x25, 0x7b, 0x56, 0xe4, 0x5c, 0x03, 0x17, 0xe2, 0x99, 0x80, 0x3d, 0x2b, 0x43, 0x21, 0x10,
-	0x3c, 0x17, 0x4f, 0x21, 0xee, 0x0e, 0x7e, 0x64, 0x1c, 0x5c, 0x4a, 0x77, 0x02, 0x76, 0x8d, 0x58,
-	0xc2, 0x0b, 0x71, 0x8d, 0xe2, 0x1a, 0xd1, 0xe5, 0x0d, 0xbc, 0x94, 0xbe, 0x04, 0xfc, 0x56, 0x22,
-	0x8d, 0x30, 0xd6, 0x06, 0x1d, 0xbc, 0x92, 0xb7, 0xb2, 0x12, 0xd2, 0x4c, 0x81, 0x8e, 0x60, 0xd0,
-	0xce, 0x30, 0xf0, 0x5b, 0x99, 0x1b, 0x37, 0xe6, 0xcb, 0x7c, 0x2d, 0x6f, 0xa5, 0x45, 0xee, 0x5f,
-	0x18, 0x6b, 0x1b, 0xc8, 0xa4, 0x3f, 0x01, 0x27, 0x5f, 0x98, 0x9f, 0x18, 0x72, 0x78, 0x23, 0xc9,
-	0x85, 0xb2, 0x8f, 0xaa, 0x37, 0xa4, 0x58, 0x79, 0x17, 0x49, 0x67, 0x6a, 0x7f, 0x59, 0x7f, 0xf1,
-	0x39, 0xa5, 0x6d, 0x39, 0x18, 0xfe, 0xa5, 0xb5, 0x7b, 0xd4, 0x59, 0xee, 0xd1, 0xcd, 0x07, 0xd5,
-	0xe7, 0xfd, 0xfa, 0xc6, 0x7b, 0xa8, 0xdf, 0xaa, 0xde, 0x57, 0xaa, 0xa5, 0x3e, 0xb9, 0x5e, 0x2f,
-	0xac, 0x2c, 0xe7, 0xc5, 0xd1, 0x13, 0x29, 0xdb, 0xb8, 0xf9, 0xa4, 0xf6, 0xee, 0xcb, 0xc9, 0xad,
-	0x5f, 0x38, 0xeb, 0x31, 0xd7, 0xef, 0xd4, 0xce, 0x1d, 0xd5, 0xe3, 0xe2, 0x5f, 0xd3, 0xa7, 0x7f,
-	0x48, 0xcb, 0x48, 0xd9, 0xc6, 0xe7, 0xbd, 0xef, 0xeb, 0x1f, 0xc1, 0xa8, 0x9b, 0x7e, 0x0d, 0xef,
-	0x7f, 0x07, 0x00, 0x00, 0xff, 0xff, 0x7c, 0xc7, 0xac, 0x9f, 0x2d, 0x04, 0x00, 0x00,
+func init() { proto.RegisterFile("farmstats.proto", fileDescriptor_farmstats_d273247a14c0c724) }
+
+var fileDescriptor_farmstats_d273247a14c0c724 = []byte{
+	// 1737 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x58, 0x5b, 0x6f, 0x5c, 0x49,
+	0x11, 0xf6, 0x5c, 0x3d, 0x53, 0xf6, 0x8c, 0xed, 0x76, 0xec, 0x74, 0x26, 0x17, 0x4c, 0x47, 0x0b,
+	0x11, 0x48, 0x51, 0x30, 0x02, 0x21, 0x90, 0x60, 0xbd, 0x71, 0xe2, 0x58, 0x72, 0x20, 0x1c, 0x23,
+	0x56, 0xe2, 0x01, 0xd3, 0x9e, 0xd3, 0x9e, 0xe9, 0xe4, 0x5c, 0x66, 0x4f, 0xf7, 0xc4, 0x99, 0x48,
+	0x48, 0xbc, 0xf2, 0xc6, 0x2f, 0xe0, 0x11, 0xf1, 0x0c, 0x3f, 0x03, 0xf1, 0x9f, 0x56, 0x55, 0xdd,
+	0x7d, 0xe6, 0xcc, 0x25, 0xbb, 0xfb, 0x56, 0x5f, 0x75, 0x9d, 0xaf, 0xeb, 0xd2, 0x5d, 0xd5, 0x33,
+	0xb0, 0x73, 0x23, 0x8b, 0xd4, 0x58, 0x69, 0xcd, 0xd3, 0x49, 0x91, 0xdb, 0x9c, 0x75, 0x4b, 0x85,
+	0x78, 0x09, 0xed, 0x97, 0xb2, 0x48, 0xcf, 0x4f, 0x59, 0x1f, 0xea, 0x3a, 0xe6, 0xb5, 0xa3, 0xda,
+	0x93, 0x6e, 0x54, 0xd7, 0x31, 0xe3, 0xb0, 0xa9, 0xb2, 0xaf, 0xa6, 0x6a, 0xaa, 0x78, 0xfd, 0xa8,
+	0xf6, 0xa4, 0x13, 0x05, 0xc8, 0x18, 0x34, 0x6f, 0xa5, 0xb6, 0xbc, 0x41, 0x6a, 0x92, 0xc5, 0x3f,
+	0xdb, 0xd0, 0x44, 0xa2, 0x75, 0x34, 0xf2, 0x5a, 0x8f, 0xa4, 0x4e, 0x88, 0xa6, 0x17, 0x05, 0x88,
+	0x34, 0x32, 0x51, 0x1f, 0x88, 0xa6, 0x17, 0x91, 0xcc, 0x06, 0xd0, 0x19, 0xca, 0x22, 0x4f, 0x74,
+	0xa6, 0x78, 0x93, 0xf4, 0x25, 0x66, 0x77, 0xa0, 0x35, 0x4c, 0x74, 0x66, 0x79, 0x8b, 0x16, 0x1c,
+	0x60, 0x0f, 0xa0, 0x1b, 0xab, 0x54, 0xd9, 0x42, 0x4f, 0x0d, 0x6f, 0xd3, 0xca, 0x5c, 0x81, 0xdf,
+	0xc4, 0xb7, 0xb2, 0xb8, 0xe1, 0x9b, 0xee, 0x1b, 0x02, 0x14, 0x5a, 0x92, 0xe8, 0xdc, 0x5a, 0xde,
+	0x71, 0x3e, 0x79, 0x88, 0xf6, 0x2a, 0xd5, 0xc9, 0x8c, 0x77, 0x9d, 0x3d, 0x01, 0x76, 0x08, 0x6d,
+	0xf5, 0x5e, 0x25, 0xb3, 0x8c, 0x03, 0xa9, 0x3d, 0x42, 0xfd, 0x48, 0xe5, 0xc5, 0x48, 0xf1, 0x2d,
+	0xa7, 0x77, 0x88, 0xed, 0x42, 0x63, 0x34, 0x35, 0x7c, 0x9b, 0x94, 0x28, 0x22, 0xef, 0x58, 0x26,
+	0x6a, 0xc6, 0x7b, 0x8e, 0x97, 0x00, 0x7e, 0x3f, 0x96, 0xc5, 0x7b, 0x35, 0xe3, 0x7d, 0xf7, 0xbd,
+	0x43, 0x98, 0x85, 0xb1, 0xca, 0x86, 0xe3, 0x54, 0x66, 0x7c, 0xc7, 0x65, 0x21, 0x60, 0xe4, 0x7e,
+	0x2b, 0x0d, 0xdf, 0x75, 0xdc, 0x6f, 0xa5, 0xc1, 0x3c, 0xbe, 0xcd, 0x63, 0xcd, 0xf7, 0x5c, 0x1e,
+	0x51, 0x46, 0xdd, 0x3b, 0x95, 0x59, 0xce, 0x9c, 0x0e, 0x65, 0xdc, 0xed, 0x5d, 0x91, 0x5f, 0x4f,
+	0x0d, 0xdf, 0x77, 0xbb, 0x39, 0x84, 0xb6, 0x89, 0x92, 0x63, 0x7e, 0xc7, 0xd9, 0xa2, 0x8c, 0xfe,
+	0x26, 0xea, 0x56, 0x1b, 0x7e, 0xe0, 0xfc, 0x25, 0x40, 0x5a, 0x9d, 0x4d, 0x0d, 0x3f, 0xf4, 0x5a,
+	0x04, 0xc8, 0x9b, 0xca, 0x22, 0xd3, 0x8a, 0xdf, 0x75, 0xbc, 0x0e, 0x21, 0x6f, 0x2a, 0x8b, 0x29,
+	0xe7, 0x8e, 0x17, 0x65, 0xf4, 0x7e, 0x22, 0x53, 0x7e, 0xcf, 0x79, 0x3f, 0x91, 0x29, 0x72, 0x4e,
+	0x54, 0x96, 0xcd, 0xf8, 0xc0, 0x71, 0x12, 0x40, 0xce, 0x89, 0x56, 0x45, 0xa1, 0xf8, 0x7d, 0xc7,
+	0xe9, 0x10, 0x5a, 0x17, 0xf9, 0xb5, 0xce, 0xf8, 0x03, 0x67, 0x4d, 0x00, 0x59, 0x8d, 0x4c, 0xf9,
+	0x43, 0xc7, 0x6a, 0x1c, 0xab, 0x91, 0x59, 0x3c, 0xe3, 0x8f, 0x9c, 0x1d, 0x01, 0x3c, 0x2b, 0x46,
+	0x5d, 0x4b, 0x63, 0xb5, 0xcc, 0xf8, 0xf7, 0xdc, 0x59, 0x29, 0x15, 0xf4, 0xcd, 0x58, 0x66, 0x8a,
+	0x1f, 0xf9, 0x6f, 0x10, 0xe0, 0x59, 0x79, 0xaf, 0xb3, 0x21, 0x26, 0xf3, 0xfb, 0xee, 0xac, 0x78,
+	0x88, 0xf6, 0xb7, 0x3a, 0x49, 0x66, 0x5c, 0x38, 0x7b, 0x02, 0xe8, 0xf9, 0xad, 0xfe, 0x28, 0x8b,
+	0x98, 0x3f, 0x76, 0x9e, 0x3b, 0x24, 0x7e, 0x0d, 0x9d, 0x48, 0x99, 0x49, 0x9e, 0x19, 0xc5, 0x04,
+	0x6c, 0x07, 0xf9, 0x79, 0x1e, 0x2b, 0xba, 0x2d, 0xbd, 0x68, 0x41, 0xe7, 0xef, 0x51, 0x3d, 0xdc,
+	0x23, 0xf1, 0x0a, 0x18, 0xde, 0xaf, 0x57, 0xda, 0xd8, 0xbc, 0x98, 0x45, 0xea, 0xab, 0xa9, 0x32,
+	0x76, 0xe5, 0xb6, 0x31, 0x68, 0xde, 0x14, 0x79, 0xea, 0xaf, 0x1a, 0xc9, 0x68, 0x63, 0x73, 0x7f,
+	0xcb, 0xea, 0x36, 0x17, 0x7f, 0xab, 0x41, 0xe7, 0x32, 0x93, 0x13, 0x33, 0xce, 0x2d, 0x85, 0xa7,
+	0x0a, 0xa3, 0xf3, 0xcc, 0x7b, 0x11, 0x20, 0xae, 0xdc, 0x28, 0x3b, 0x1c, 0x2b, 0xe7, 0x45, 0x23,
+	0x0a, 0x90, 0xdd, 0x87, 0xee, 0x44, 0x8e, 0xd4, 0xd5, 0x58, 0x9a, 0x31, 0xf1, 0x76, 0xa3, 0x0e,
+	0x2a, 0x5e, 0x49, 0x33, 0x66, 0x8f, 0xa1, 0x89, 0xdd, 0x85, 0x6e, 0xef, 0xd6, 0xf1, 0xce, 0xd3,
+	0x79, 0xef, 0x41, 0xf7, 0x23, 0x5a, 0x14, 0x0a, 0x7a, 0x7f, 0xd4, 0x49, 0x22, 0x47, 0xaa, 0x38,
+	0x55, 0x89, 0x95, 0x78, 0xe2, 0xdf, 0x7b, 0x85, 0x8f, 0xa6, 0xc4, 0xdf, 0x25, 0x26, 0xba, 0xe7,
+	0x48, 0x44, 0xdb, 0xb6, 0x22, 0x07, 0xc4, 0xbf, 0x6a, 0xb0, 0x55, 0x49, 0xda, 0x4a, 0xb6, 0x7e,
+	0x02, 0x5d, 0xe3, 0x13, 0x61, 0x78, 0xfd, 0xa8, 0xf1, 0x64, 0xeb, 0x78, 0xbf, 0xe2, 0x70, 0x48,
+	0x52, 0x34, 0xb7, 0x2a, 0x9d, 0x69, 0xac, 0x38, 0xd3, 0x2c, 0x9d, 0x79, 0x06, 0x6d, 0xda, 0xdf,
+	0xf0, 0x16, 0x71, 0xf2, 0x0a, 0xe7, 0x42, 0xd8, 0x91, 0xb7, 0x13, 0xbf, 0x85, 0xdd, 0x0b, 0x6d,
+	0x2c, 0xfa, 0x6a, 0x42, 0x69, 0x43, 0x96, 0x8d, 0xfe, 0x18, 0x4e, 0x08, 0x65, 0xf9, 0x52, 0x7f,
+	0x54, 0xec, 0x21, 0x00, 0x2d, 0xda, 0xfc, 0x9d, 0xca, 0xfc, 0x29, 0x21, 0xf3, 0x3f, 0xa0, 0x42,
+	0xe4, 0xd0, 0x41, 0x2e, 0xe4, 0x64, 0x9f, 0x41, 0x8b, 0xb6, 0xe7, 0xb5, 0xa3, 0xc6, 0xba, 0x8a,
+	0xb8, 0x55, 0xf6, 0x03, 0xd8, 0xc9, 0xd4, 0x07, 0x7b, 0xb5, 0x42, 0xdb, 0x43, 0xf5, 0x9b, 0x40,
+	0x8d, 0x99, 0xb6, 0xb9, 0x95, 0x89, 0xcf, 0x80, 0x03, 0x62, 0x1f, 0xf6, 0xbe, 0x94, 0x76, 0x38,
+	0xae, 0x46, 0x20, 0x18, 0xec, 0x9e, 0x8c, 0x46, 0x85, 0x1a, 0x49, 0xab, 0x82, 0xee, 0xaf, 0xb0,
+	0x17, 0x52, 0x50, 0xae, 0x7d, 0x5b, 0xf5, 0x53, 0x25, 0x9d, 0x33, 0xb5, 0x88, 0x64, 0xbc, 0xef,
+	0xa9, 0xce, 0xbc, 0x07, 0x28, 0x92, 0x46, 0x7e, 0xf0, 0x35, 0x40, 0x11, 0xfd, 0x4c, 0xe5, 0x07,
+	0x15, 0x87, 0x69, 0x41, 0x40, 0xfc, 0x19, 0xa0, 0xdc, 0x96, 0xfa, 0x59, 0x48, 0x0d, 0xd9, 0x10,
+	0x60, 0xbf, 0x84, 0x6e, 0xd8, 0x3d, 0x9c, 0x8a, 0x07, 0x6b, 0x2a, 0x38, 0x0f, 0x6d, 0x6e, 0x2e,
+	0x04, 0xf4, 0x5f, 0xb8, 0x29, 0x19, 0xca, 0xb8, 0x0b, 0x0d, 0x1d, 0xbb, 0xe4, 0x77, 0x23, 0x14,
+	0xc5, 0x2f, 0xa0, 0x1f, 0xa9, 0xb7, 0x6a, 0x68, 0x55, 0xfc, 0x89, 0xd1, 0x7b, 0x08, 0xed, 0x42,
+	0x49, 0x93, 0x87, 0x12, 0x78, 0x24, 0xfe, 0x02, 0x3b, 0x25, 0xbb, 0x6f, 0x25, 0x87, 0xd0, 0x26,
+	0x45, 0xec, 0x77, 0xf0, 0x88, 0xfd, 0x0c, 0x3a, 0x85, 0xdf, 0xc4, 0xc7, 0x70, 0xaf, 0x12, 0xc3,
+	0xe2, 0xfe, 0x51, 0x69, 0x2a, 0x0e, 0x60, 0xff, 0x25, 0xde, 0xf2, 0x48, 0x61, 0x8b, 0x2b, 0x2b,
+	0xf9, 0x18, 0x7a, 0xe7, 0xe9, 0x24, 0x2f, 0x6c, 0x88, 0x8a, 0x41, 0x33, 0x96, 0x56, 0x92, 0xcf,
+	0xdb, 0x11, 0xc9, 0xe2, 0x7f, 0x35, 0xe8, 0x3b, 0xab, 0x37, 0x45, 0x3e, 0x2a, 0x94, 0x31, 0xee,
+	0x0d, 0x61, 0x0b, 0xad, 0x42, 0x8a, 0x03, 0xc4, 0x56, 0x3c, 0x29, 0xf2, 0xa1, 0x32, 0xc6, 0xf7,
+	0x97, 0x5e, 0x34, 0x57, 0x54, 0xa2, 0x72, 0x35, 0x0e, 0x51, 0x3d, 0x02, 0x88, 0xa7, 0x93, 0x44,
+	0x0f, 0xb1, 0x7c, 0xbe, 0xda, 0x15, 0xcd, 0x42, 0xd4, 0xad, 0xef, 0x1c, 0x35, 0x45, 0x93, 0x67,
+	0x8a, 0x9e, 0x0f, 0x9d, 0x88, 0x64, 0xf1, 0xf7, 0x1a, 0xb0, 0x4b, 0x2b, 0x0b, 0x7b, 0x39, 0xd1,
+	0xb1, 0x2a, 0x42, 0xe0, 0x3f, 0x87, 0x66, 0x1a, 0x5a, 0x76, 0xff, 0x58, 0x54, 0xbb, 0xc5, 0x8a,
+	0xf1, 0xd3, 0xd7, 0x79, 0xac, 0x22, 0xb2, 0xc7, 0x2d, 0xf0, 0x66, 0x85, 0x26, 0x86, 0xb2, 0xf8,
+	0x21, 0x34, 0xd1, 0x82, 0x6d, 0x43, 0xe7, 0xd5, 0xef, 0x5e, 0xbf, 0x78, 0x73, 0x72, 0xf6, 0x62,
+	0x77, 0x83, 0x75, 0xa0, 0x49, 0x52, 0x8d, 0x6d, 0x42, 0xe3, 0xe4, 0xe2, 0x62, 0xb7, 0x2e, 0xee,
+	0xa0, 0x2b, 0xf9, 0xc4, 0x91, 0x97, 0x45, 0x39, 0x80, 0x7d, 0xa7, 0xb9, 0xb4, 0xd2, 0x4e, 0x4b,
+	0xf5, 0x29, 0x6c, 0x57, 0xd5, 0x58, 0x83, 0x62, 0x9a, 0x65, 0x3a, 0x1b, 0x85, 0x1a, 0x78, 0x88,
+	0xd7, 0xce, 0xd8, 0x7c, 0x32, 0xc1, 0x25, 0xf7, 0xc4, 0x2b, 0x31, 0x6e, 0xf9, 0x7b, 0xcc, 0xf9,
+	0x22, 0xf7, 0x7f, 0x6b, 0xb0, 0x55, 0x51, 0x7f, 0xe2, 0x02, 0x7d, 0x06, 0x7d, 0x12, 0xae, 0x86,
+	0x72, 0x22, 0x87, 0xda, 0xce, 0x7c, 0xd4, 0x3d, 0xd2, 0x3e, 0xf7, 0x4a, 0xfc, 0x98, 0x32, 0x17,
+	0x3a, 0x09, 0x01, 0xfc, 0x98, 0x84, 0xf9, 0xc7, 0xae, 0xcc, 0x3d, 0xd2, 0x96, 0x1f, 0xff, 0x08,
+	0x5a, 0x89, 0xcc, 0x54, 0x68, 0xb1, 0x77, 0x2a, 0x85, 0x20, 0x07, 0x2f, 0x64, 0xa6, 0x22, 0x67,
+	0x22, 0x34, 0x74, 0x4b, 0x1d, 0x16, 0x22, 0x93, 0xa9, 0xf2, 0xb7, 0x8d, 0xe4, 0x79, 0x18, 0xf5,
+	0x6a, 0x18, 0xf4, 0x16, 0xf5, 0x3e, 0x34, 0xc2, 0x5b, 0xd4, 0x6f, 0x8f, 0x53, 0x5e, 0xe9, 0xd1,
+	0xd8, 0x7a, 0xef, 0x3c, 0x12, 0xbb, 0xd0, 0xff, 0x32, 0x2f, 0xde, 0x55, 0xaa, 0xf4, 0x2b, 0xd8,
+	0xbb, 0x54, 0x76, 0x51, 0x89, 0x4e, 0x54, 0xda, 0x3a, 0xc9, 0xa8, 0x93, 0x53, 0x9b, 0xfb, 0x4a,
+	0x90, 0x2c, 0xfe, 0x5d, 0x03, 0x70, 0x9f, 0xbe, 0xc9, 0xf3, 0x64, 0xed, 0x67, 0xbe, 0x17, 0xd6,
+	0x57, 0x7a, 0x61, 0x63, 0xde, 0x0b, 0xe9, 0x35, 0x95, 0x65, 0x2a, 0x26, 0x6f, 0x3b, 0x91, 0x47,
+	0x34, 0x45, 0x54, 0x71, 0x95, 0xea, 0x6c, 0x6a, 0x95, 0x6f, 0x94, 0xdd, 0x89, 0x2a, 0x5e, 0x93,
+	0x82, 0xfd, 0x18, 0x36, 0x6f, 0x9d, 0xdf, 0xbc, 0x4d, 0x59, 0xde, 0xab, 0x64, 0xd9, 0xb9, 0x15,
+	0x05, 0x0b, 0xf1, 0x9f, 0x1a, 0xb4, 0x9d, 0xae, 0xd2, 0xce, 0x7a, 0xd4, 0xce, 0x7c, 0xa1, 0x95,
+	0xef, 0x66, 0x0e, 0xb0, 0xbb, 0xb0, 0x89, 0x6c, 0x57, 0x3a, 0xf6, 0x6f, 0x88, 0x36, 0xc2, 0x73,
+	0x67, 0x8e, 0x4f, 0x2c, 0x72, 0xb6, 0x11, 0x39, 0xb0, 0xd8, 0x30, 0x5a, 0x6b, 0x1a, 0xc6, 0x8d,
+	0xd4, 0x89, 0x8a, 0xfd, 0x4f, 0x00, 0x8f, 0x96, 0x22, 0xdc, 0xa4, 0x19, 0x32, 0x8f, 0xf0, 0xf8,
+	0xff, 0x75, 0xe8, 0x62, 0x37, 0xb8, 0xa4, 0xa3, 0xf7, 0x0c, 0x3a, 0x67, 0xca, 0x3a, 0x79, 0x6f,
+	0x69, 0x4c, 0x9e, 0x9f, 0x0e, 0x96, 0x27, 0xa7, 0xd8, 0x60, 0xe7, 0xd0, 0x3f, 0x53, 0xb6, 0xfa,
+	0xc4, 0x78, 0xb8, 0x64, 0xb4, 0xf8, 0x5e, 0x1b, 0x1c, 0xae, 0x5f, 0x16, 0x1b, 0xec, 0x37, 0xd0,
+	0x2d, 0x9f, 0x00, 0xec, 0x7e, 0xc5, 0x6c, 0xf9, 0x61, 0x30, 0xd8, 0x5f, 0xe2, 0x40, 0x03, 0x22,
+	0x80, 0xf9, 0x08, 0x66, 0xd5, 0x89, 0xb5, 0x32, 0x99, 0xd7, 0x84, 0xf2, 0xac, 0xc6, 0x4e, 0xa0,
+	0x3b, 0x1f, 0xc9, 0x55, 0x0f, 0x96, 0x87, 0xf8, 0xe0, 0x60, 0xdd, 0xa2, 0x11, 0x1b, 0xc7, 0xff,
+	0x68, 0x41, 0xeb, 0x24, 0xc6, 0x43, 0xf8, 0x05, 0x6c, 0xfa, 0x51, 0xc5, 0xaa, 0x2d, 0x78, 0x71,
+	0x38, 0x0e, 0x06, 0xeb, 0x96, 0xdc, 0x64, 0x13, 0x1b, 0xec, 0x02, 0xb6, 0xab, 0xc3, 0x88, 0x3d,
+	0xaa, 0x7a, 0xbd, 0x3a, 0xa5, 0xbe, 0x85, 0xed, 0x39, 0xb4, 0xdd, 0x74, 0x62, 0xd5, 0xf7, 0xd8,
+	0xc2, 0x58, 0x1b, 0xdc, 0x5b, 0x59, 0x09, 0xa3, 0x4c, 0x6c, 0x3c, 0xa9, 0x3d, 0xab, 0xb1, 0x33,
+	0xd8, 0xaa, 0xf4, 0xf9, 0x85, 0x6a, 0xaf, 0xf6, 0xff, 0xc1, 0xdd, 0xea, 0x72, 0xa5, 0x27, 0x8b,
+	0x0d, 0x47, 0x54, 0xb6, 0xf4, 0x25, 0xa2, 0xe5, 0x56, 0xff, 0x4d, 0x44, 0x17, 0xb0, 0x83, 0x87,
+	0xb6, 0xa2, 0x5c, 0xc8, 0xd3, 0x9a, 0x09, 0xf1, 0x4d, 0x6c, 0xee, 0x40, 0x57, 0x5b, 0xfc, 0xc3,
+	0xe5, 0xce, 0xba, 0xc8, 0x75, 0xb8, 0x7e, 0x59, 0x6c, 0xb0, 0xcf, 0x01, 0xce, 0xca, 0xc6, 0xb7,
+	0x70, 0x08, 0x16, 0x9b, 0xe1, 0xe0, 0x60, 0x65, 0x09, 0x9b, 0x1d, 0x55, 0x0c, 0xe6, 0xad, 0x73,
+	0xe1, 0x44, 0xaf, 0x74, 0xd4, 0x4f, 0x92, 0x1c, 0x7f, 0x0e, 0x5b, 0xe7, 0xe9, 0xe8, 0x34, 0xbf,
+	0xcd, 0x92, 0x5c, 0xe2, 0x93, 0xbf, 0x45, 0x47, 0x67, 0xdd, 0x05, 0xdf, 0x5f, 0x78, 0x2b, 0x84,
+	0x83, 0xf3, 0xc5, 0xd6, 0x9f, 0xe6, 0xff, 0x97, 0x5c, 0xb7, 0xe9, 0x1f, 0x94, 0x9f, 0x7e, 0x3d,
+	0x00, 0x3e, 0x6c, 0xee, 0xde, 0x54, 0x11, 0x00, 0x00,
 }
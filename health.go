@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/go-redis/redis"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	defaultReadyBudget      = 2 * time.Second
+	defaultUpstreamCacheFor = 30 * time.Second
+	defaultHealthInterval   = 10 * time.Second
+)
+
+type pinger interface {
+	Ping() *redis.StatusCmd
+}
+
+type healthCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// healthChecker decides whether we are ready to serve: redis answers, the
+// store is loaded, the farm workers are running and upload.farm responds
+// within budget. The same answer is published through grpc.health.v1.
+type healthChecker struct {
+	redisdb  pinger
+	upstream string
+	budget   time.Duration
+	cacheFor time.Duration
+	grpc     *health.Server
+
+	mu              sync.Mutex
+	wantedWorkers   int
+	workers         int
+	upstreamChecked time.Time
+	upstreamErr     error
+	reported        bool
+	wasReady        bool
+}
+
+func newHealthChecker(redisdb pinger, wantedWorkers int) *healthChecker {
+	return &healthChecker{
+		redisdb:       redisdb,
+		upstream:      farmBaseURL,
+		budget:        defaultReadyBudget,
+		cacheFor:      defaultUpstreamCacheFor,
+		wantedWorkers: wantedWorkers,
+		grpc:          health.NewServer(),
+	}
+}
+
+// workerStarted and workerStopped bracket the life of a queue worker.
+func (h *healthChecker) workerStarted() {
+	h.mu.Lock()
+	h.workers++
+	h.mu.Unlock()
+}
+
+func (h *healthChecker) workerStopped() {
+	h.mu.Lock()
+	h.workers--
+	h.mu.Unlock()
+}
+
+// expectWorkers changes how many workers must be running to be ready, as
+// the worker pool is resized.
+func (h *healthChecker) expectWorkers(n int) {
+	h.mu.Lock()
+	h.wantedWorkers = n
+	h.mu.Unlock()
+}
+
+// checkUpstream asks upload.farm for its homepage, remembering the answer
+// for a while so frequent probes do not turn into frequent requests.
+func (h *healthChecker) checkUpstream(ctx context.Context) error {
+	h.mu.Lock()
+	if time.Since(h.upstreamChecked) < h.cacheFor {
+		err := h.upstreamErr
+		h.mu.Unlock()
+		return err
+	}
+	h.mu.Unlock()
+
+	req, err := http.NewRequest("HEAD", h.upstream, nil)
+	if err == nil {
+		var res *http.Response
+		res, err = httpClient.Do(req.WithContext(ctx))
+		if err == nil {
+			res.Body.Close()
+			if res.StatusCode >= 500 {
+				err = fmt.Errorf(res.Status)
+			}
+		}
+	}
+
+	h.mu.Lock()
+	h.upstreamChecked = time.Now()
+	h.upstreamErr = err
+	h.mu.Unlock()
+	return err
+}
+
+// checkRedis pings redis, giving up when ctx ends. go-redis cannot cancel
+// a ping, so a slow one finishes in the background.
+func (h *healthChecker) checkRedis(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() { done <- h.redisdb.Ping().Err() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checks runs every check within ctx.
+func (h *healthChecker) checks(ctx context.Context) []healthCheck {
+	var checks []healthCheck
+	add := func(name string, err error) {
+		check := healthCheck{Name: name, OK: err == nil}
+		if err != nil {
+			check.Detail = err.Error()
+		}
+		checks = append(checks, check)
+	}
+
+	add("redis", h.checkRedis(ctx))
+
+	if !allFarms.isLoaded() {
+		add("store", fmt.Errorf("not loaded"))
+	} else {
+		add("store", nil)
+	}
+
+	h.mu.Lock()
+	workers, wanted := h.workers, h.wantedWorkers
+	h.mu.Unlock()
+	if workers < wanted {
+		add("workers", fmt.Errorf("%d of %d running", workers, wanted))
+	} else {
+		add("workers", nil)
+	}
+
+	add("upstream", h.checkUpstream(ctx))
+	return checks
+}
+
+// ready runs the checks within the readiness budget, logging when the
+// answer changes rather than on every probe.
+func (h *healthChecker) ready(ctx context.Context) ([]healthCheck, bool) {
+	ctx, cancel := context.WithTimeout(ctx, h.budget)
+	defer cancel()
+	checks := h.checks(ctx)
+	ok := true
+	for _, check := range checks {
+		if !check.OK {
+			ok = false
+			break
+		}
+	}
+
+	h.mu.Lock()
+	changed := !h.reported || h.wasReady != ok
+	h.reported, h.wasReady = true, ok
+	h.mu.Unlock()
+	if changed {
+		if ok {
+			log.Info("ready")
+		} else {
+			log.Warnf("not ready: %v", checks)
+		}
+	}
+	return checks, ok
+}
+
+// update publishes readiness to gRPC health clients.
+func (h *healthChecker) update() {
+	_, ok := h.ready(context.Background())
+	st := healthpb.HealthCheckResponse_SERVING
+	if !ok {
+		st = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	h.grpc.SetServingStatus("", st)
+	h.grpc.SetServingStatus("farmstats.FarmStats", st)
+}
+
+// watch keeps the gRPC health status current until stop is closed.
+func (h *healthChecker) watch(stop <-chan struct{}) {
+	for {
+		h.update()
+		select {
+		case <-stop:
+			return
+		case <-time.After(defaultHealthInterval):
+		}
+	}
+}
+
+// liveHandler serves /healthz: if we can answer, we are alive.
+func (h *healthChecker) liveHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+// readyHandler serves /readyz with the result of each check.
+func (h *healthChecker) readyHandler(w http.ResponseWriter, r *http.Request) {
+	checks, ok := h.ready(r.Context())
+	if !ok {
+		render.Status(r, http.StatusServiceUnavailable)
+	}
+	render.JSON(w, r, checks)
+}
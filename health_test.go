@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"golang.org/x/net/context"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type fakePinger struct {
+	err   error
+	block chan struct{}
+}
+
+func (p *fakePinger) Ping() *redis.StatusCmd {
+	if p.block != nil {
+		<-p.block
+	}
+	return redis.NewStatusResult("PONG", p.err)
+}
+
+func TestHealth(t *testing.T) {
+	Convey("Given a health checker with a working upstream", t, func() {
+		upstreamRequests := 0
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upstreamRequests++
+		}))
+		defer upstream.Close()
+
+		allFarms.stats = map[string]svStats{}
+		allFarms.setLoaded(true)
+		redisdb := &fakePinger{}
+		h := newHealthChecker(redisdb, 1)
+		h.upstream = upstream.URL
+		h.workerStarted()
+
+		Convey("everything passing means ready", func() {
+			checks, ok := h.ready(context.Background())
+			So(ok, ShouldBeTrue)
+			So(len(checks), ShouldEqual, 4)
+
+			w := httptest.NewRecorder()
+			h.readyHandler(w, httptest.NewRequest("GET", "/readyz", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+
+			h.update()
+			res, err := h.grpc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "farmstats.FarmStats"})
+			So(err, ShouldBeNil)
+			So(res.Status, ShouldEqual, healthpb.HealthCheckResponse_SERVING)
+		})
+
+		Convey("a store that has not finished loading means not ready", func() {
+			allFarms.setLoaded(false)
+			checks, ok := h.ready(context.Background())
+			So(ok, ShouldBeFalse)
+			So(checks[1], ShouldResemble, healthCheck{Name: "store", Detail: "not loaded"})
+		})
+
+		Convey("a redis that does not answer within budget means not ready", func() {
+			redisdb.block = make(chan struct{})
+			defer close(redisdb.block)
+			h.budget = 10 * time.Millisecond
+			checks, ok := h.ready(context.Background())
+			So(ok, ShouldBeFalse)
+			So(checks[0], ShouldResemble, healthCheck{Name: "redis", Detail: context.DeadlineExceeded.Error()})
+		})
+
+		Convey("readiness is only logged when it changes", func() {
+			hook := test.NewGlobal()
+			defer hook.Reset()
+			probe := func() {
+				h.readyHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/readyz", nil))
+			}
+
+			probe()
+			probe()
+			So(hook.Entries, ShouldHaveLength, 1)
+			So(hook.LastEntry().Level, ShouldEqual, log.InfoLevel)
+
+			allFarms.setLoaded(false)
+			probe()
+			probe()
+			So(hook.Entries, ShouldHaveLength, 2)
+			So(hook.LastEntry().Level, ShouldEqual, log.WarnLevel)
+		})
+
+		Convey("upstream checks are reused within cacheFor", func() {
+			h.ready(context.Background())
+			h.ready(context.Background())
+			So(upstreamRequests, ShouldEqual, 1)
+		})
+
+		Convey("a redis failure or a dead worker means not ready", func() {
+			redisdb.err = errors.New("connection refused")
+			h.workerStopped()
+
+			w := httptest.NewRecorder()
+			h.readyHandler(w, httptest.NewRequest("GET", "/readyz", nil))
+			So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+			var checks []healthCheck
+			So(json.Unmarshal(w.Body.Bytes(), &checks), ShouldBeNil)
+			So(checks[0], ShouldResemble, healthCheck{Name: "redis", Detail: "connection refused"})
+			So(checks[2], ShouldResemble, healthCheck{Name: "workers", Detail: "0 of 1 running"})
+
+			h.update()
+			res, _ := h.grpc.Check(context.Background(), &healthpb.HealthCheckRequest{})
+			So(res.Status, ShouldEqual, healthpb.HealthCheckResponse_NOT_SERVING)
+
+			w = httptest.NewRecorder()
+			h.liveHandler(w, httptest.NewRequest("GET", "/healthz", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+	})
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+var errFarmNotFound = errors.New("farm not found")
+
+// villagers lists the villager names tracked in svStats, in field order.
+var villagers = func() []string {
+	var names []string
+	t := reflect.TypeOf(svStats{})
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type.Kind() == reflect.Uint32 {
+			names = append(names, t.Field(i).Name)
+		}
+	}
+	return names
+}()
+
+// villager returns the friendship level for the named villager.
+func (stats svStats) villager(name string) uint32 {
+	return uint32(reflect.ValueOf(stats).FieldByName(name).Uint())
+}
+
+type farmSnapshot struct {
+	Version   int               `json:"version"`
+	Fetched   time.Time         `json:"fetched"`
+	PageHash  string            `json:"pageHash"`
+	Villagers map[string]uint32 `json:"villagers"`
+	stats     svStats
+}
+
+type villagerDelta struct {
+	Villager string `json:"villager"`
+	From     uint32 `json:"from"`
+	To       uint32 `json:"to"`
+	Delta    int    `json:"delta"`
+}
+
+type farmHistory struct {
+	FarmID    string          `json:"farmID"`
+	Snapshots []farmSnapshot  `json:"snapshots"`
+	From      int             `json:"from"`
+	To        int             `json:"to"`
+	Deltas    []villagerDelta `json:"deltas"`
+}
+
+// farmHistory returns every stored version of a farm along with the villagers
+// whose friendship changed between versions from and to. Versions count from
+// 1; 0 means the first version for from, and the latest for to.
+func (s *farmStats) farmHistory(farmID FarmID, from, to int) (farmHistory, error) {
+	s.mu.Lock()
+	versions := append([]svStats(nil), s.history[string(farmID)]...)
+	s.mu.Unlock()
+
+	if len(versions) == 0 {
+		return farmHistory{}, errFarmNotFound
+	}
+	if from == 0 {
+		from = 1
+	}
+	if to == 0 {
+		to = len(versions)
+	}
+	if from < 1 || from > len(versions) || to < 1 || to > len(versions) {
+		return farmHistory{}, fmt.Errorf("versions must be between 1 and %d", len(versions))
+	}
+
+	h := farmHistory{FarmID: string(farmID), From: from, To: to}
+	for i, stats := range versions {
+		snapshot := farmSnapshot{
+			Version:   i + 1,
+			Fetched:   stats.Fetched,
+			PageHash:  stats.PageHash,
+			Villagers: make(map[string]uint32),
+			stats:     stats,
+		}
+		for _, name := range villagers {
+			snapshot.Villagers[name] = stats.villager(name)
+		}
+		h.Snapshots = append(h.Snapshots, snapshot)
+	}
+
+	before, after := versions[from-1], versions[to-1]
+	for _, name := range villagers {
+		if before.villager(name) == after.villager(name) {
+			continue
+		}
+		h.Deltas = append(h.Deltas, villagerDelta{
+			Villager: name,
+			From:     before.villager(name),
+			To:       after.villager(name),
+			Delta:    int(after.villager(name)) - int(before.villager(name)),
+		})
+	}
+	return h, nil
+}
+
+func (s *farmStats) GetFarmHistory(ctx context.Context, req *pb.FarmHistoryRequest) (*pb.FarmHistory, error) {
+	farmID, err := ParseFarmID(req.Id)
+	if err != nil {
+		return nil, grpcstatus.Error(codes.InvalidArgument, err.Error())
+	}
+	h, err := allFarms.farmHistory(farmID, int(req.From), int(req.To))
+	if err == errFarmNotFound {
+		return nil, grpcstatus.Errorf(codes.NotFound, "farm %s not found", req.Id)
+	}
+	if err != nil {
+		return nil, grpcstatus.Error(codes.InvalidArgument, err.Error())
+	}
+
+	res := &pb.FarmHistory{Id: h.FarmID, From: uint32(h.From), To: uint32(h.To)}
+	for _, snapshot := range h.Snapshots {
+		res.Snapshots = append(res.Snapshots, &pb.Snapshot{
+			Version:  uint32(snapshot.Version),
+			Fetched:  snapshot.Fetched.Unix(),
+			PageHash: snapshot.PageHash,
+			Farm:     snapshot.stats.toProto(),
+		})
+	}
+	for _, d := range h.Deltas {
+		res.Deltas = append(res.Deltas, &pb.VillagerDelta{
+			Villager: d.Villager,
+			From:     d.From,
+			To:       d.To,
+			Delta:    int32(d.Delta),
+		})
+	}
+	return res, nil
+}
+
+// farmHistoryHandler serves GET /api/v1/farms/{farmID}/history?from=1&to=3
+func farmHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	farmID, err := ParseFarmID(chi.URLParam(r, "farmID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var versions [2]int
+	for i, param := range []string{"from", "to"} {
+		v := r.URL.Query().Get(param)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s version", param), http.StatusBadRequest)
+			return
+		}
+		versions[i] = n
+	}
+
+	h, err := allFarms.farmHistory(farmID, versions[0], versions[1])
+	if err == errFarmNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	render.JSON(w, r, h)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
+	"github.com/go-chi/chi"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFarmHistory(t *testing.T) {
+	Convey("Given a farm scraped three times", t, func() {
+		allFarms.stats = map[string]svStats{}
+		allFarms.history = map[string][]svStats{}
+		now := time.Now()
+		allFarms.store(svStats{FarmID: "1AAAAA", Fetched: now, PageHash: "a", Abigail: 2, Sam: 5})
+		allFarms.store(svStats{FarmID: "1AAAAA", Fetched: now.Add(time.Hour), PageHash: "a", Abigail: 2, Sam: 5})
+		allFarms.store(svStats{FarmID: "1AAAAA", Fetched: now.Add(2 * time.Hour), PageHash: "b", Abigail: 4, Sam: 5})
+		allFarms.store(svStats{FarmID: "1AAAAA", Fetched: now.Add(3 * time.Hour), PageHash: "c", Abigail: 4, Sam: 1})
+
+		Convey("an unchanged page does not add a version", func() {
+			So(len(allFarms.history["1AAAAA"]), ShouldEqual, 3)
+			So(allFarms.stats["1AAAAA"].PageHash, ShouldEqual, "c")
+		})
+
+		Convey("deltas default to first vs latest", func() {
+			h, err := allFarms.farmHistory("1AAAAA", 0, 0)
+			So(err, ShouldBeNil)
+			So(h.From, ShouldEqual, 1)
+			So(h.To, ShouldEqual, 3)
+			So(h.Deltas, ShouldResemble, []villagerDelta{
+				{Villager: "Abigail", From: 2, To: 4, Delta: 2},
+				{Villager: "Sam", From: 5, To: 1, Delta: -4},
+			})
+		})
+
+		Convey("any two versions can be compared", func() {
+			h, err := allFarms.farmHistory("1AAAAA", 3, 2)
+			So(err, ShouldBeNil)
+			So(h.Deltas, ShouldResemble, []villagerDelta{
+				{Villager: "Sam", From: 1, To: 5, Delta: 4},
+			})
+
+			_, err = allFarms.farmHistory("1AAAAA", 1, 4)
+			So(err, ShouldNotBeNil)
+			_, err = allFarms.farmHistory("1ZZZZZ", 0, 0)
+			So(err, ShouldEqual, errFarmNotFound)
+		})
+
+		Convey("the gRPC service returns snapshots and typed errors", func() {
+			h, err := allFarms.GetFarmHistory(context.Background(), &pb.FarmHistoryRequest{Id: "1AAAAA", From: 2})
+			So(err, ShouldBeNil)
+			So(len(h.Snapshots), ShouldEqual, 3)
+			So(h.Snapshots[2].Farm.Sam, ShouldEqual, 1)
+			So(len(h.Deltas), ShouldEqual, 1)
+
+			_, err = allFarms.GetFarmHistory(context.Background(), &pb.FarmHistoryRequest{Id: "1ZZZZZ"})
+			So(grpcstatus.Code(err), ShouldEqual, codes.NotFound)
+			_, err = allFarms.GetFarmHistory(context.Background(), &pb.FarmHistoryRequest{Id: "1AAAAA", To: 9})
+			So(grpcstatus.Code(err), ShouldEqual, codes.InvalidArgument)
+			_, err = allFarms.GetFarmHistory(context.Background(), &pb.FarmHistoryRequest{Id: "1bc-12"})
+			So(grpcstatus.Code(err), ShouldEqual, codes.InvalidArgument)
+		})
+
+		Convey("the HTTP endpoint serves the same history", func() {
+			r := chi.NewRouter()
+			r.Get("/api/v1/farms/{farmID}/history", farmHistoryHandler)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/farms/1AAAAA/history?from=1&to=2", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+			var h farmHistory
+			So(json.Unmarshal(w.Body.Bytes(), &h), ShouldBeNil)
+			So(len(h.Snapshots), ShouldEqual, 3)
+			So(h.Snapshots[0].Villagers["Abigail"], ShouldEqual, 2)
+			So(h.Deltas, ShouldResemble, []villagerDelta{
+				{Villager: "Abigail", From: 2, To: 4, Delta: 2},
+			})
+
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/farms/1ZZZZZ/history", nil))
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/farms/1AAAAA/history?from=x", nil))
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/farms/1AAA/history", nil))
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(w.Body.String(), ShouldContainSubstring, "must be 6 characters")
+		})
+	})
+}
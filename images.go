@@ -0,0 +1,584 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math/bits"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+const (
+	defaultThumbWidth = 200
+	// defaultDuplicateDistance is the most bits two renders' hashes may
+	// differ by for the farms to count as the same save.
+	defaultDuplicateDistance = 4
+	imageQueueSize           = 100
+)
+
+var errImageNotFound = errors.New("image not downloaded")
+
+// imagesConfig sets where farm renders and their thumbnails are kept.
+// MaxDistance is a pointer so 0, exact matches only, can be told apart from
+// leaving it out.
+type imagesConfig struct {
+	Disabled    bool   `json:"disabled"`
+	Dir         string `json:"dir"`
+	ThumbWidth  int    `json:"thumb_width"`
+	MaxDistance *int   `json:"max_distance"`
+}
+
+// imageHash is a 64 bit difference hash of a farm render. Renders of the
+// same save hash the same, or nearly so, whatever their size.
+type imageHash uint64
+
+func (h imageHash) String() string {
+	return fmt.Sprintf("%016x", uint64(h))
+}
+
+// distance is the number of bits two hashes differ by.
+func (h imageHash) distance(other imageHash) int {
+	return bits.OnesCount64(uint64(h ^ other))
+}
+
+func (h imageHash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *imageHash) UnmarshalText(text []byte) error {
+	n, err := strconv.ParseUint(string(text), 16, 64)
+	if err != nil {
+		return fmt.Errorf("invalid image hash [%s]", text)
+	}
+	*h = imageHash(n)
+	return nil
+}
+
+// imageRecord describes a downloaded farm render.
+type imageRecord struct {
+	FarmID FarmID    `json:"farmID"`
+	Hash   imageHash `json:"hash"`
+	Width  int       `json:"width"`
+	Height int       `json:"height"`
+	// PageHash is the farm page the render was fetched for, so a re-upload
+	// fetches it again.
+	PageHash string    `json:"pageHash"`
+	Fetched  time.Time `json:"fetched"`
+	// DuplicateOf is the closest farm whose render looked the same when
+	// this one was downloaded.
+	DuplicateOf FarmID `json:"duplicateOf,omitempty"`
+}
+
+type imageDuplicate struct {
+	FarmID   FarmID    `json:"farmID"`
+	Hash     imageHash `json:"hash"`
+	Distance int       `json:"distance"`
+}
+
+// imageStore downloads the render upload.farm makes of each stored farm,
+// keeps it on disk with a thumbnail, and hashes it so repeated uploads of
+// the same save can be found. Farms are downloaded one at a time, as they
+// are stored, to keep the load on upload.farm down.
+type imageStore struct {
+	dir         string
+	thumbWidth  int
+	maxDistance int
+	requests    chan FarmID
+
+	mu         sync.Mutex
+	records    map[FarmID]imageRecord
+	pending    map[FarmID]bool
+	downloaded int
+	failed     int
+}
+
+// images is nil while the image pipeline is disabled.
+var images *imageStore
+
+// newImageStore returns nil if the pipeline is disabled, and otherwise loads
+// the records of images already downloaded.
+func newImageStore(cfg imagesConfig) (*imageStore, error) {
+	if cfg.Disabled || cfg.Dir == "" {
+		return nil, nil
+	}
+	if cfg.ThumbWidth < 1 {
+		return nil, fmt.Errorf("images: thumb_width must be positive, got %d", cfg.ThumbWidth)
+	}
+	maxDistance := defaultDuplicateDistance
+	if cfg.MaxDistance != nil {
+		maxDistance = *cfg.MaxDistance
+	}
+	if maxDistance < 0 || maxDistance > 64 {
+		return nil, fmt.Errorf("images: max_distance must be between 0 and 64, got %d", maxDistance)
+	}
+	err := os.MkdirAll(cfg.Dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &imageStore{
+		dir:         cfg.Dir,
+		thumbWidth:  cfg.ThumbWidth,
+		maxDistance: maxDistance,
+		requests:    make(chan FarmID, imageQueueSize),
+		records:     make(map[FarmID]imageRecord),
+		pending:     make(map[FarmID]bool),
+	}
+	paths, err := filepath.Glob(filepath.Join(cfg.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		var record imageRecord
+		body, err := ioutil.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(body, &record)
+		}
+		if err != nil || record.FarmID == "" {
+			log.Warnf("skipping image record %s: %v", path, err)
+			continue
+		}
+		s.records[record.FarmID] = record
+	}
+	log.Infof("loaded %d image records from %s", len(s.records), cfg.Dir)
+	return s, nil
+}
+
+// imageURL is where upload.farm serves its render of a farm.
+func imageURL(farmID FarmID) string {
+	return fmt.Sprintf("%s/all/%s-f.png", farmBaseURL, farmID)
+}
+
+// path is where a farm's render, thumbnail or record is kept.
+func (s *imageStore) path(farmID FarmID, kind string) string {
+	switch kind {
+	case "thumbnail":
+		return filepath.Join(s.dir, string(farmID)+"-thumb.png")
+	case "record":
+		return filepath.Join(s.dir, string(farmID)+".json")
+	}
+	return filepath.Join(s.dir, string(farmID)+".png")
+}
+
+// record returns what we know of a farm's render.
+func (s *imageStore) record(farmID FarmID) (imageRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[farmID]
+	return record, ok
+}
+
+// stale reports whether a stored farm's render needs downloading.
+func (s *imageStore) stale(stats svStats) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[FarmID(stats.FarmID)]
+	return !ok || (stats.PageHash != "" && record.PageHash != stats.PageHash)
+}
+
+// request queues a farm's render for download, returning false if the
+// download queue is full. Farms already waiting are not queued twice.
+func (s *imageStore) request(farmID FarmID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending[farmID] {
+		return true
+	}
+	select {
+	case s.requests <- farmID:
+		s.pending[farmID] = true
+		return true
+	default:
+		return false
+	}
+}
+
+// start downloads the render of every farm stored from now on until stop
+// is closed. catchUp finds any missed while the queue was full.
+func (s *imageStore) start(stop <-chan struct{}) {
+	sub := farmEvents.subscribe(func(event interface{}) bool {
+		stats, ok := event.(svStats)
+		return ok && s.stale(stats)
+	})
+	go func() {
+		defer farmEvents.unsubscribe(sub)
+		for {
+			select {
+			case <-stop:
+				return
+			case event := <-sub.events:
+				stats := event.(svStats)
+				if !s.request(FarmID(stats.FarmID)) {
+					log.WithField("farmID", stats.FarmID).Debug("image queue full, leaving farm for catch up")
+				}
+			}
+		}
+	}()
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case farmID := <-s.requests:
+				ctx := withReqID(context.Background(), newReqID("image"))
+				s.download(ctx, farmID)
+			}
+		}
+	}()
+}
+
+// catchUp queues the stored farms whose render is missing or out of date,
+// for as many as fit in the download queue.
+func (s *imageStore) catchUp(stop <-chan struct{}) error {
+	// copy out what stale needs, so the store is not held while we look
+	allFarms.mu.Lock()
+	stored := make([]svStats, 0, len(allFarms.stats))
+	for farmID, stats := range allFarms.stats {
+		stored = append(stored, svStats{FarmID: farmID, PageHash: stats.PageHash})
+	}
+	allFarms.mu.Unlock()
+
+	var farmIDs []string
+	for _, stats := range stored {
+		if s.stale(stats) {
+			farmIDs = append(farmIDs, stats.FarmID)
+		}
+	}
+	sort.Strings(farmIDs)
+
+	queued := 0
+	for _, farmID := range farmIDs {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		if !s.request(FarmID(farmID)) {
+			break
+		}
+		queued++
+	}
+	log.Infof("queued %d of %d farm images to download", queued, len(farmIDs))
+	return nil
+}
+
+// download fetches a farm's render from upload.farm and stores it.
+func (s *imageStore) download(ctx context.Context, farmID FarmID) (imageRecord, error) {
+	logger := log.WithFields(log.Fields{"reqID": reqIDFromContext(ctx), "farmID": farmID})
+	_, sp := startSpan(ctx, "image")
+	sp.setAttr("farmID", string(farmID))
+
+	body, res, err := getURL(imageURL(farmID), nil)
+	if err == nil && res.StatusCode != http.StatusOK {
+		err = &httpStatusError{StatusCode: res.StatusCode, Status: res.Status}
+	}
+	var record imageRecord
+	if err == nil {
+		record, err = s.store(farmID, body)
+	}
+	sp.finish(err)
+
+	s.mu.Lock()
+	delete(s.pending, farmID)
+	if err != nil {
+		s.failed++
+	} else {
+		s.downloaded++
+	}
+	s.mu.Unlock()
+	if err != nil {
+		logger.WithError(err).Warn("could not download farm image")
+		return record, err
+	}
+	logger.WithField("hash", record.Hash).Debug("downloaded farm image")
+	return record, nil
+}
+
+// store keeps a farm's render with its thumbnail and hash, and flags it if
+// it looks like another farm's.
+func (s *imageStore) store(farmID FarmID, body []byte) (imageRecord, error) {
+	img, err := png.Decode(bytes.NewReader(body))
+	if err != nil {
+		return imageRecord{}, &parseError{FarmID: farmID, Reason: "render is not a png: " + err.Error()}
+	}
+	bounds := img.Bounds()
+	record := imageRecord{
+		FarmID:  farmID,
+		Hash:    hashImage(img),
+		Width:   bounds.Dx(),
+		Height:  bounds.Dy(),
+		Fetched: time.Now(),
+	}
+	allFarms.mu.Lock()
+	record.PageHash = allFarms.stats[string(farmID)].PageHash
+	allFarms.mu.Unlock()
+
+	var thumb bytes.Buffer
+	err = png.Encode(&thumb, thumbnail(img, s.thumbWidth))
+	if err != nil {
+		return imageRecord{}, err
+	}
+	err = replaceFile(s.path(farmID, "image"), body)
+	if err == nil {
+		err = replaceFile(s.path(farmID, "thumbnail"), thumb.Bytes())
+	}
+	if err != nil {
+		return imageRecord{}, err
+	}
+
+	if dups := s.closest(farmID, record.Hash, s.maxDistance); len(dups) > 0 {
+		record.DuplicateOf = dups[0].FarmID
+		duplicateImages.Inc()
+		log.WithFields(log.Fields{
+			"farmID":   farmID,
+			"original": dups[0].FarmID,
+			"distance": dups[0].Distance,
+		}).Info("farm looks like a re-upload")
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return imageRecord{}, err
+	}
+	err = replaceFile(s.path(farmID, "record"), recordJSON)
+	if err != nil {
+		return imageRecord{}, err
+	}
+
+	s.mu.Lock()
+	s.records[farmID] = record
+	s.mu.Unlock()
+	return record, nil
+}
+
+// closest lists the other farms whose renders are within maxDistance of
+// hash, closest first.
+func (s *imageStore) closest(farmID FarmID, hash imageHash, maxDistance int) []imageDuplicate {
+	s.mu.Lock()
+	var dups []imageDuplicate
+	for other, record := range s.records {
+		if other == farmID {
+			continue
+		}
+		if d := hash.distance(record.Hash); d <= maxDistance {
+			dups = append(dups, imageDuplicate{FarmID: other, Hash: record.Hash, Distance: d})
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(dups, func(i, j int) bool {
+		if dups[i].Distance == dups[j].Distance {
+			return dups[i].FarmID < dups[j].FarmID
+		}
+		return dups[i].Distance < dups[j].Distance
+	})
+	return dups
+}
+
+// duplicates lists the farms whose renders look like farmID's.
+func (s *imageStore) duplicates(farmID FarmID, maxDistance int) ([]imageDuplicate, error) {
+	record, ok := s.record(farmID)
+	if !ok {
+		return nil, errImageNotFound
+	}
+	return s.closest(farmID, record.Hash, maxDistance), nil
+}
+
+func (s *imageStore) String() string {
+	if s == nil {
+		return "farm images disabled"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dups := 0
+	for _, record := range s.records {
+		if record.DuplicateOf != "" {
+			dups++
+		}
+	}
+	return fmt.Sprintf("%d farm images (%d look like re-uploads), %d downloaded and %d failed since start, %d waiting",
+		len(s.records), dups, s.downloaded, s.failed, len(s.requests))
+}
+
+// Fetch asks for a stored farm's render to be downloaded. It answers 200 if
+// the render is already here, and 202 once it is queued. Farms we have not
+// stored are NotFound, so callers cannot fill the queue with any id.
+func (s *imageStore) Fetch(ctx context.Context, in *pb.FarmID) (*pb.Response, error) {
+	farmID, err := ParseFarmID(in.Id)
+	if err != nil {
+		return nil, grpcstatus.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, ok := s.record(farmID); ok {
+		return &pb.Response{ResponseCode: http.StatusOK, Id: string(farmID)}, nil
+	}
+	allFarms.mu.Lock()
+	_, stored := allFarms.stats[string(farmID)]
+	allFarms.mu.Unlock()
+	if !stored {
+		return nil, grpcstatus.Errorf(codes.NotFound, "farm %s not found", farmID)
+	}
+	if !s.request(farmID) {
+		return nil, grpcstatus.Error(codes.Unavailable, "image download queue is full")
+	}
+	return &pb.Response{ResponseCode: http.StatusAccepted, Id: string(farmID)}, nil
+}
+
+// scale resizes an image by averaging the pixels that fall in each new one.
+func scale(src image.Image, width, height int) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := b.Min.Y + (y+1)*b.Dy()/height
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := b.Min.X + (x+1)*b.Dx()/width
+			if x1 == x0 {
+				x1++
+			}
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+// thumbnail scales an image down to width, keeping its shape. Images
+// narrower than width are kept at their own size.
+func thumbnail(img image.Image, width int) *image.RGBA {
+	b := img.Bounds()
+	if b.Dx() < width {
+		width = b.Dx()
+	}
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	return scale(img, width, height)
+}
+
+// hashImage is the difference hash of an image: shrunk to 9x8 and made
+// grey, each bit says whether a pixel is darker than the one to its right.
+func hashImage(img image.Image) imageHash {
+	small := scale(img, 9, 8)
+	var h imageHash
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			h <<= 1
+			if luminance(small.RGBAAt(x, y)) < luminance(small.RGBAAt(x+1, y)) {
+				h |= 1
+			}
+		}
+	}
+	return h
+}
+
+func luminance(c color.RGBA) int {
+	return 299*int(c.R) + 587*int(c.G) + 114*int(c.B)
+}
+
+// farmImageHandler serves GET /api/v1/farms/{farmID}/image and /thumbnail.
+// Stored farms whose render is missing are queued for download.
+func farmImageHandler(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		farmID, err := ParseFarmID(chi.URLParam(r, "farmID"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if images == nil {
+			http.Error(w, "farm images are disabled", http.StatusNotFound)
+			return
+		}
+		if _, ok := images.record(farmID); !ok {
+			allFarms.mu.Lock()
+			_, stored := allFarms.stats[string(farmID)]
+			allFarms.mu.Unlock()
+			if stored && images.request(farmID) {
+				http.Error(w, fmt.Sprintf("image of farm %s is not downloaded yet, queued it", farmID), http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("no image of farm %s", farmID), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		http.ServeFile(w, r, images.path(farmID, kind))
+	}
+}
+
+type farmDuplicates struct {
+	FarmID      FarmID           `json:"farmID"`
+	Hash        imageHash        `json:"hash"`
+	MaxDistance int              `json:"maxDistance"`
+	Duplicates  []imageDuplicate `json:"duplicates"`
+}
+
+// farmDuplicatesHandler serves GET /api/v1/farms/{farmID}/duplicates?distance=4
+func farmDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	farmID, err := ParseFarmID(chi.URLParam(r, "farmID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if images == nil {
+		http.Error(w, "farm images are disabled", http.StatusNotFound)
+		return
+	}
+	maxDistance := images.maxDistance
+	if v := r.URL.Query().Get("distance"); v != "" {
+		maxDistance, err = strconv.Atoi(v)
+		if err != nil || maxDistance < 0 || maxDistance > 64 {
+			http.Error(w, "distance must be a whole number from 0 to 64", http.StatusBadRequest)
+			return
+		}
+	}
+
+	dups, err := images.duplicates(farmID, maxDistance)
+	if err == errImageNotFound {
+		http.Error(w, fmt.Sprintf("no image of farm %s", farmID), http.StatusNotFound)
+		return
+	}
+	record, _ := images.record(farmID)
+	if dups == nil {
+		dups = []imageDuplicate{}
+	}
+	render.JSON(w, r, farmDuplicates{
+		FarmID:      farmID,
+		Hash:        record.Hash,
+		MaxDistance: maxDistance,
+		Duplicates:  dups,
+	})
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
+	"github.com/go-chi/chi"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// farmRender draws a 16x16 grid of grey cells chosen by seed, each cell
+// size pixels across, so one seed at different sizes is the same picture.
+func farmRender(seed int64, size int) []byte {
+	r := rand.New(rand.NewSource(seed))
+	var cells [16][16]uint8
+	for y := range cells {
+		for x := range cells[y] {
+			cells[y][x] = uint8(r.Intn(256))
+		}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 16*size, 16*size))
+	for y := 0; y < 16*size; y++ {
+		for x := 0; x < 16*size; x++ {
+			grey := cells[y/size][x/size]
+			img.SetRGBA(x, y, color.RGBA{grey, grey, grey, 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func decodeRender(body []byte) image.Image {
+	img, _ := png.Decode(bytes.NewReader(body))
+	return img
+}
+
+func TestImageHashing(t *testing.T) {
+	Convey("Renders of one save hash alike whatever their size", t, func() {
+		small := hashImage(decodeRender(farmRender(1, 4)))
+		large := hashImage(decodeRender(farmRender(1, 10)))
+		other := hashImage(decodeRender(farmRender(2, 4)))
+		So(small.distance(large), ShouldBeLessThanOrEqualTo, defaultDuplicateDistance)
+		So(small.distance(other), ShouldBeGreaterThan, 16)
+	})
+
+	Convey("Hashes are written as hex", t, func() {
+		h := imageHash(0xf00d)
+		text, err := h.MarshalText()
+		So(err, ShouldBeNil)
+		So(string(text), ShouldEqual, "000000000000f00d")
+		var back imageHash
+		So(back.UnmarshalText(text), ShouldBeNil)
+		So(back, ShouldEqual, h)
+		So(back.UnmarshalText([]byte("xyz")), ShouldNotBeNil)
+	})
+
+	Convey("A max distance of 0 in the config means exact matches only", t, func() {
+		dir, err := ioutil.TempDir("", "images")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "config.json")
+
+		So(ioutil.WriteFile(path, []byte(`{"images": {"dir": "`+dir+`", "max_distance": 0}}`), 0644), ShouldBeNil)
+		cfg, err := loadConfig(path)
+		So(err, ShouldBeNil)
+		s, err := newImageStore(cfg.Images)
+		So(err, ShouldBeNil)
+		So(s.maxDistance, ShouldEqual, 0)
+
+		So(ioutil.WriteFile(path, []byte(`{"images": {"dir": "`+dir+`"}}`), 0644), ShouldBeNil)
+		cfg, err = loadConfig(path)
+		So(err, ShouldBeNil)
+		s, err = newImageStore(cfg.Images)
+		So(err, ShouldBeNil)
+		So(s.maxDistance, ShouldEqual, defaultDuplicateDistance)
+	})
+
+	Convey("Thumbnails keep the render's shape and never grow it", t, func() {
+		img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+		So(thumbnail(img, 200).Bounds().Size(), ShouldResemble, image.Pt(200, 150))
+		So(thumbnail(img, 1000).Bounds().Size(), ShouldResemble, image.Pt(400, 300))
+	})
+
+	Convey("Images need sensible settings", t, func() {
+		s, err := newImageStore(imagesConfig{Disabled: true, Dir: "x"})
+		So(s, ShouldBeNil)
+		So(err, ShouldBeNil)
+		So(s.String(), ShouldEqual, "farm images disabled")
+		_, err = newImageStore(imagesConfig{Dir: "x", ThumbWidth: 0})
+		So(err, ShouldNotBeNil)
+		tooFar := 65
+		_, err = newImageStore(imagesConfig{Dir: "x", ThumbWidth: 10, MaxDistance: &tooFar})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestImageStore(t *testing.T) {
+	Convey("Given an image store and upload.farm renders", t, func() {
+		renders := map[string][]byte{
+			"/all/1AAAAA-f.png": farmRender(1, 4),
+			"/all/1BBBBB-f.png": farmRender(1, 6),
+			"/all/1CCCCC-f.png": farmRender(2, 4),
+			"/all/1BROKE-f.png": []byte("not a png"),
+		}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, ok := renders[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(body)
+		}))
+		defer srv.Close()
+		farmBaseURL = srv.URL
+		defer func() { farmBaseURL = "https://upload.farm" }()
+
+		dir, err := ioutil.TempDir("", "images")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		cfg := imagesConfig{Dir: dir, ThumbWidth: 16}
+		s, err := newImageStore(cfg)
+		So(err, ShouldBeNil)
+		allFarms.stats = map[string]svStats{"1AAAAA": {FarmID: "1AAAAA", PageHash: "v1"}}
+		ctx := context.Background()
+
+		Convey("a downloaded render is kept with its thumbnail and hash", func() {
+			record, err := s.download(ctx, "1AAAAA")
+			So(err, ShouldBeNil)
+			So(record.Width, ShouldEqual, 64)
+			So(record.PageHash, ShouldEqual, "v1")
+			So(record.DuplicateOf, ShouldEqual, "")
+
+			body, err := ioutil.ReadFile(s.path("1AAAAA", "image"))
+			So(err, ShouldBeNil)
+			So(body, ShouldResemble, renders["/all/1AAAAA-f.png"])
+			body, err = ioutil.ReadFile(s.path("1AAAAA", "thumbnail"))
+			So(err, ShouldBeNil)
+			So(decodeRender(body).Bounds().Dx(), ShouldEqual, 16)
+
+			Convey("...and is still known after a restart", func() {
+				again, err := newImageStore(cfg)
+				So(err, ShouldBeNil)
+				reloaded, ok := again.record("1AAAAA")
+				So(ok, ShouldBeTrue)
+				So(reloaded.Hash, ShouldEqual, record.Hash)
+			})
+
+			Convey("...and only goes stale when the farm is re-uploaded", func() {
+				So(s.stale(svStats{FarmID: "1AAAAA", PageHash: "v1"}), ShouldBeFalse)
+				So(s.stale(svStats{FarmID: "1AAAAA", PageHash: "v2"}), ShouldBeTrue)
+				So(s.stale(svStats{FarmID: "1CCCCC"}), ShouldBeTrue)
+			})
+		})
+
+		Convey("a re-upload of the same save is flagged as a duplicate", func() {
+			_, err := s.download(ctx, "1AAAAA")
+			So(err, ShouldBeNil)
+			record, err := s.download(ctx, "1BBBBB")
+			So(err, ShouldBeNil)
+			So(record.DuplicateOf, ShouldEqual, "1AAAAA")
+			_, err = s.download(ctx, "1CCCCC")
+			So(err, ShouldBeNil)
+
+			dups, err := s.duplicates("1AAAAA", s.maxDistance)
+			So(err, ShouldBeNil)
+			So(len(dups), ShouldEqual, 1)
+			So(dups[0].FarmID, ShouldEqual, "1BBBBB")
+			dups, _ = s.duplicates("1AAAAA", 64)
+			So(len(dups), ShouldEqual, 2)
+			_, err = s.duplicates("1DDDDD", 64)
+			So(err, ShouldEqual, errImageNotFound)
+			So(s.String(), ShouldStartWith, "3 farm images (1 look like re-uploads), 3 downloaded and 0 failed")
+		})
+
+		Convey("missing and broken renders are failures", func() {
+			_, err := s.download(ctx, "1GONE1")
+			So(err, ShouldHaveSameTypeAs, &httpStatusError{})
+			_, err = s.download(ctx, "1BROKE")
+			So(err, ShouldHaveSameTypeAs, &parseError{})
+			_, ok := s.record("1BROKE")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("catch up queues stored farms without a render, once each", func() {
+			allFarms.stats["1BBBBB"] = svStats{FarmID: "1BBBBB"}
+			So(s.catchUp(nil), ShouldBeNil)
+			So(s.catchUp(nil), ShouldBeNil)
+			So(len(s.requests), ShouldEqual, 2)
+			So(<-s.requests, ShouldEqual, "1AAAAA")
+		})
+
+		Convey("farms are downloaded as they are stored", func() {
+			stop := make(chan struct{})
+			defer close(stop)
+			s.start(stop)
+			farmEvents.publish(svStats{FarmID: "1CCCCC"})
+			var ok bool
+			for i := 0; i < 100 && !ok; i++ {
+				time.Sleep(10 * time.Millisecond)
+				_, ok = s.record("1CCCCC")
+			}
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("Fetch queues a download over gRPC", func() {
+			_, err := s.Fetch(ctx, &pb.FarmID{Id: "1CCCCC"})
+			So(grpcstatus.Code(err), ShouldEqual, codes.NotFound)
+			So(s.requests, ShouldBeEmpty)
+
+			allFarms.stats["1CCCCC"] = svStats{FarmID: "1CCCCC"}
+			res, err := s.Fetch(ctx, &pb.FarmID{Id: "1CCCCC"})
+			So(err, ShouldBeNil)
+			So(res.ResponseCode, ShouldEqual, http.StatusAccepted)
+			s.download(ctx, <-s.requests)
+			res, err = s.Fetch(ctx, &pb.FarmID{Id: "1CCCCC"})
+			So(err, ShouldBeNil)
+			So(res.ResponseCode, ShouldEqual, http.StatusOK)
+			_, err = s.Fetch(ctx, &pb.FarmID{Id: "bogus"})
+			So(grpcstatus.Code(err), ShouldEqual, codes.InvalidArgument)
+		})
+
+		Convey("the HTTP endpoints serve images and duplicates", func() {
+			images = s
+			defer func() { images = nil }()
+			s.download(ctx, "1AAAAA")
+			s.download(ctx, "1BBBBB")
+			r := chi.NewRouter()
+			r.Get("/api/v1/farms/{farmID}/image", farmImageHandler("image"))
+			r.Get("/api/v1/farms/{farmID}/thumbnail", farmImageHandler("thumbnail"))
+			r.Get("/api/v1/farms/{farmID}/duplicates", farmDuplicatesHandler)
+			get := func(path string) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+				return w
+			}
+
+			w := get("/api/v1/farms/1AAAAA/image")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Header().Get("Content-Type"), ShouldEqual, "image/png")
+			So(w.Body.Bytes(), ShouldResemble, renders["/all/1AAAAA-f.png"])
+			w = get("/api/v1/farms/1AAAAA/thumbnail")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(decodeRender(w.Body.Bytes()).Bounds().Dx(), ShouldEqual, 16)
+
+			w = get("/api/v1/farms/1BBBBB/duplicates")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldContainSubstring, `"duplicates":[{"farmID":"1AAAAA"`)
+			w = get("/api/v1/farms/1BBBBB/duplicates?distance=0")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(get("/api/v1/farms/1BBBBB/duplicates?distance=99").Code, ShouldEqual, http.StatusBadRequest)
+			So(get("/api/v1/farms/1ZZZZZ/duplicates").Code, ShouldEqual, http.StatusNotFound)
+
+			allFarms.stats["1CCCCC"] = svStats{FarmID: "1CCCCC"}
+			w = get("/api/v1/farms/1CCCCC/image")
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+			So(strings.TrimSpace(w.Body.String()), ShouldEndWith, "queued it")
+			So(<-s.requests, ShouldEqual, "1CCCCC")
+			So(get("/api/v1/farms/1ZZZZZ/image").Code, ShouldEqual, http.StatusNotFound)
+			So(get("/api/v1/farms/1bc-12/image").Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// importReportEvery is how many entries are processed between progress
+// reports.
+const importReportEvery = 100
+
+// importColumns are CSV header names that mark the column holding the farm
+// id or URL.
+var importColumns = map[string]bool{
+	"farmid":  true,
+	"farm_id": true,
+	"id":      true,
+	"url":     true,
+}
+
+// parseImport reads farm ids or upload.farm URLs from data, which can be a
+// JSON array of strings (like _mini_recents), CSV, or plain text with one
+// entry per line. In CSV with a header naming an id or url column, that
+// column is used; otherwise the first field that looks like a farm is.
+func parseImport(data []byte) ([]string, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var entries []string
+		err := json.Unmarshal(data, &entries)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse json: %v", err)
+		}
+		return entries, nil
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	column := -1
+	var entries []string
+	for line := 0; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse csv: %v", err)
+		}
+		if line == 0 && len(record) > 1 {
+			for i, name := range record {
+				if importColumns[strings.ToLower(strings.TrimSpace(name))] {
+					column = i
+				}
+			}
+			if column >= 0 {
+				continue
+			}
+		}
+		entries = append(entries, importEntry(record, column))
+	}
+	return entries, nil
+}
+
+// importEntry picks the field of a CSV record that holds the farm.
+func importEntry(record []string, column int) string {
+	if column >= 0 {
+		if column < len(record) {
+			return record[column]
+		}
+		return ""
+	}
+	for _, field := range record {
+		if _, err := parseFarmInput(field); err == nil {
+			return field
+		}
+	}
+	return record[0]
+}
+
+// rejectedEntry is an entry that did not hold a valid farm id.
+type rejectedEntry struct {
+	Entry  string
+	Reason string
+}
+
+// importProgress counts how far an import has got.
+type importProgress struct {
+	Entries    int
+	Processed  int
+	Queued     int
+	Duplicates int
+	Rejected   int
+}
+
+func (p importProgress) String() string {
+	return fmt.Sprintf("%d/%d entries: %d queued, %d duplicates, %d rejected",
+		p.Processed, p.Entries, p.Queued, p.Duplicates, p.Rejected)
+}
+
+// importFarms validates, dedupes and queues entries. report is called every
+// importReportEvery entries and once more, with done set, at the end; each
+// call gets the entries rejected since the previous one. If ctx ends, even
+// while waiting for room in the queue, it stops and reports how far it got.
+func importFarms(ctx context.Context, queue *farmQueue, entries []string, report func(p importProgress, rejected []rejectedEntry, done bool)) (importProgress, error) {
+	progress := importProgress{Entries: len(entries)}
+	seen := make(map[FarmID]bool)
+	var rejected []rejectedEntry
+	stopped := func(err error) (importProgress, error) {
+		report(progress, rejected, true)
+		log.WithFields(log.Fields{
+			"reqID":      reqIDFromContext(ctx),
+			"queued":     progress.Queued,
+			"duplicates": progress.Duplicates,
+			"rejected":   progress.Rejected,
+		}).WithError(err).Warnf("import stopped after %d of %d entries", progress.Processed, progress.Entries)
+		return progress, err
+	}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return stopped(err)
+		}
+		farmID, err := parseFarmInput(entry)
+		switch {
+		case err != nil:
+			progress.Rejected++
+			rejected = append(rejected, rejectedEntry{Entry: entry, Reason: err.Error()})
+		case seen[farmID]:
+			progress.Duplicates++
+		default:
+			if err := enqueue(ctx, queue, laneBackfill, farmID); err != nil {
+				return stopped(err)
+			}
+			seen[farmID] = true
+			progress.Queued++
+		}
+		progress.Processed++
+		if progress.Processed%importReportEvery == 0 && progress.Processed < len(entries) {
+			report(progress, rejected, false)
+			rejected = nil
+		}
+	}
+	report(progress, rejected, true)
+	log.WithFields(log.Fields{
+		"reqID":      reqIDFromContext(ctx),
+		"queued":     progress.Queued,
+		"duplicates": progress.Duplicates,
+		"rejected":   progress.Rejected,
+	}).Info("imported farms")
+	return progress, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseImport(t *testing.T) {
+	Convey("Imports can be", t, func() {
+		Convey("plain text, one entry per line", func() {
+			entries, err := parseImport([]byte("1AAAAA\n\nhttps://upload.farm/1BBBBB\n"))
+			So(err, ShouldBeNil)
+			So(entries, ShouldResemble, []string{"1AAAAA", "https://upload.farm/1BBBBB"})
+		})
+
+		Convey("a JSON array like _mini_recents", func() {
+			entries, err := parseImport([]byte(` ["1AAAAA-farm.png", "1BBBBB"]`))
+			So(err, ShouldBeNil)
+			So(entries, ShouldResemble, []string{"1AAAAA-farm.png", "1BBBBB"})
+
+			_, err = parseImport([]byte(`["1AAAAA",`))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("CSV with a header naming the id column", func() {
+			entries, err := parseImport([]byte("name,farm_id\n100000,1AAAAA\nx,1BBBBB\n"))
+			So(err, ShouldBeNil)
+			So(entries, ShouldResemble, []string{"1AAAAA", "1BBBBB"})
+		})
+
+		Convey("CSV without a header, using the first field that looks like a farm", func() {
+			entries, err := parseImport([]byte("Abby's farm, 1AAAAA\nnothing here,at all\n"))
+			So(err, ShouldBeNil)
+			So(entries, ShouldResemble, []string{"1AAAAA", "nothing here"})
+		})
+	})
+
+	Convey("Farm ids are taken from ids or upload.farm URLs", t, func() {
+		for entry, want := range map[string]string{
+			"1AAAAA":                           "1AAAAA",
+			" 1AAAAA ":                         "1AAAAA",
+			"https://upload.farm/1AAAAA":       "1AAAAA",
+			"https://upload.farm/1AAAAA/":      "1AAAAA",
+			"upload.farm/1AAAAA":               "1AAAAA",
+			"https://upload.farm/all/1AAAAA-f": "1AAAAA",
+		} {
+			farmID, err := parseFarmInput(entry)
+			So(err, ShouldBeNil)
+			So(farmID, ShouldEqual, want)
+		}
+		_, err := parseFarmInput("https://upload.farm/")
+		So(err, ShouldNotBeNil)
+		_, err = parseFarmInput("2AAAAA")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestImportFarms(t *testing.T) {
+	Convey("Given a queue", t, func() {
+		farmQueue := newFarmQueue(300)
+		queue := farmQueue.lane(laneBackfill)
+		var reports []importProgress
+		var rejected []rejectedEntry
+		report := func(p importProgress, r []rejectedEntry, done bool) {
+			reports = append(reports, p)
+			rejected = append(rejected, r...)
+			So(done, ShouldEqual, p.Processed == p.Entries)
+		}
+
+		Convey("valid farms are queued once and the rest counted", func() {
+			p, err := importFarms(context.Background(), farmQueue, []string{"1AAAAA", "bogus", "https://upload.farm/1AAAAA", "1BBBBB"}, report)
+			So(err, ShouldBeNil)
+			So(p, ShouldResemble, importProgress{Entries: 4, Processed: 4, Queued: 2, Duplicates: 1, Rejected: 1})
+			So(len(queue), ShouldEqual, 2)
+			So((<-queue).FarmID, ShouldEqual, "1AAAAA")
+			So((<-queue).FarmID, ShouldEqual, "1BBBBB")
+			So(rejected, ShouldHaveLength, 1)
+			So(rejected[0].Entry, ShouldEqual, "bogus")
+			So(reports, ShouldHaveLength, 1)
+		})
+
+		Convey("progress is reported as it goes", func() {
+			var entries []string
+			for i := 0; i < 250; i++ {
+				entries = append(entries, fmt.Sprintf("1A%04d", i))
+			}
+			_, err := importFarms(context.Background(), farmQueue, entries, report)
+			So(err, ShouldBeNil)
+			So(reports, ShouldHaveLength, 3)
+			So(reports[0].Processed, ShouldEqual, 100)
+			So(reports[2].Queued, ShouldEqual, 250)
+		})
+
+		Convey("a cancelled import stops", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			p, err := importFarms(ctx, farmQueue, []string{"1AAAAA"}, func(importProgress, []rejectedEntry, bool) {})
+			So(err, ShouldEqual, context.Canceled)
+			So(p.Queued, ShouldEqual, 0)
+		})
+
+		Convey("an import waiting on a full queue stops when ctx ends", func() {
+			full := newFarmQueue(1)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			var last importProgress
+			p, err := importFarms(ctx, full, []string{"1AAAAA", "1BBBBB", "1CCCCC"}, func(p importProgress, _ []rejectedEntry, done bool) {
+				So(done, ShouldBeTrue)
+				last = p
+			})
+			So(err == context.DeadlineExceeded, ShouldBeTrue)
+			So(p, ShouldResemble, importProgress{Entries: 3, Processed: 1, Queued: 1})
+			So(last, ShouldResemble, p)
+			queued, _ := tracker.state("1BBBBB")
+			So(queued, ShouldBeFalse)
+		})
+	})
+}
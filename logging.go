@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const reqIDHeader = "X-Request-Id"
+
+// logConfig picks the log format ("text" or "json") and starting level.
+type logConfig struct {
+	Format string `json:"format"`
+	Level  string `json:"level"`
+}
+
+var reqIDPrefix = func() string {
+	b := make([]byte, 6)
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := range b {
+		b[i] = chars[r.Intn(len(chars))]
+	}
+	return string(b)
+}()
+var reqIDSeq uint64
+
+// newReqID returns a correlation ID naming where the request came from, eg
+// telnet-Xu4fa2-000012.
+func newReqID(source string) string {
+	return fmt.Sprintf("%s-%s-%06d", source, reqIDPrefix, atomic.AddUint64(&reqIDSeq, 1))
+}
+
+type reqIDKey struct{}
+
+func withReqID(ctx context.Context, reqID string) context.Context {
+	return context.WithValue(ctx, reqIDKey{}, reqID)
+}
+
+// reqIDFromContext finds the correlation ID set by our gRPC interceptor or by
+// chi's RequestID middleware.
+func reqIDFromContext(ctx context.Context) string {
+	if reqID, ok := ctx.Value(reqIDKey{}).(string); ok {
+		return reqID
+	}
+	return middleware.GetReqID(ctx)
+}
+
+func setupLogging(cfg logConfig) error {
+	switch cfg.Format {
+	case "", "text":
+		log.SetFormatter(&log.TextFormatter{})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("unknown log format [%s]", cfg.Format)
+	}
+	if cfg.Level == "" {
+		return nil
+	}
+	return setLogLevel(cfg.Level)
+}
+
+func setLogLevel(level string) error {
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	log.SetLevel(lvl)
+	log.Infof("log level set to %s", lvl)
+	return nil
+}
+
+// grpcRequestID takes the correlation ID from the x-request-id metadata, or
+// makes one up, and hands it back in the response headers.
+func grpcRequestID(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	reqID := grpcReqID(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(reqIDHeader), reqID))
+
+	startTime := time.Now()
+	res, err := handler(withReqID(ctx, reqID), req)
+	logGRPCRequest(reqID, info.FullMethod, startTime, err)
+	return res, err
+}
+
+// grpcStreamRequestID is grpcRequestID for streaming calls, logged once the
+// stream ends.
+func grpcStreamRequestID(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	reqID := grpcReqID(ss.Context())
+	ss.SetHeader(metadata.Pairs(strings.ToLower(reqIDHeader), reqID))
+
+	startTime := time.Now()
+	err := handler(srv, withStreamContext(ss, withReqID(ss.Context(), reqID)))
+	logGRPCRequest(reqID, info.FullMethod, startTime, err)
+	return err
+}
+
+// grpcReqID takes the caller's correlation ID from the metadata, or makes
+// a new one.
+func grpcReqID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(strings.ToLower(reqIDHeader)); len(ids) > 0 {
+			return ids[0]
+		}
+	}
+	return newReqID("grpc")
+}
+
+func logGRPCRequest(reqID, method string, startTime time.Time, err error) {
+	entry := log.WithFields(log.Fields{
+		"reqID":    reqID,
+		"method":   method,
+		"duration": time.Since(startTime),
+	})
+	if err != nil {
+		entry.WithError(err).Info("grpc request failed")
+	} else {
+		entry.Debug("grpc request")
+	}
+}
+
+// requestLogger replaces chi's middleware.Logger, logging each request
+// through logrus with its correlation ID.
+func requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID := middleware.GetReqID(r.Context())
+		w.Header().Set(reqIDHeader, reqID)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		startTime := time.Now()
+		next.ServeHTTP(ww, r)
+		log.WithFields(log.Fields{
+			"reqID":    reqID,
+			"method":   r.Method,
+			"path":     r.URL.Path,
+			"status":   ww.Status(),
+			"bytes":    ww.BytesWritten(),
+			"duration": time.Since(startTime),
+		}).Info("http request")
+	})
+}
+
+// logLevelHandler serves GET and PUT /api/v1/loglevel.
+func logLevelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "PUT" || r.Method == "POST" {
+		level := r.URL.Query().Get("level")
+		err := setLogLevel(level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	fmt.Fprintf(w, "%s\n", log.GetLevel())
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/middleware"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLogging(t *testing.T) {
+	Convey("Correlation IDs name their source and are unique", t, func() {
+		a, b := newReqID("telnet"), newReqID("telnet")
+		So(a, ShouldStartWith, "telnet-"+reqIDPrefix+"-")
+		So(a, ShouldNotEqual, b)
+	})
+
+	Convey("A farm's journey is logged under the request's correlation ID", t, func() {
+		hook := test.NewGlobal()
+		defer hook.Reset()
+		level := log.GetLevel()
+		log.SetLevel(log.DebugLevel)
+		defer log.SetLevel(level)
+
+		queue := newFarmQueue(1)
+		enqueue(withReqID(context.Background(), "telnet-abc-000001"), queue, laneInteractive, "1AAAAA")
+		req, _ := queue.pop(nil)
+		So(req.FarmID, ShouldEqual, "1AAAAA")
+		So(req.ReqID, ShouldEqual, "telnet-abc-000001")
+		So(hook.LastEntry().Data["reqID"], ShouldEqual, "telnet-abc-000001")
+		So(hook.LastEntry().Data["farmID"], ShouldEqual, "1AAAAA")
+	})
+
+	Convey("gRPC requests reuse an incoming x-request-id", t, func() {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "client-42"))
+		var seen string
+		info := &grpc.UnaryServerInfo{FullMethod: "/farmstats.FarmStats/GetStats"}
+		grpcRequestID(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			seen = reqIDFromContext(ctx)
+			return nil, nil
+		})
+		So(seen, ShouldEqual, "client-42")
+
+		grpcRequestID(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			seen = reqIDFromContext(ctx)
+			return nil, nil
+		})
+		So(seen, ShouldStartWith, "grpc-")
+	})
+
+	Convey("HTTP requests are logged with their request ID", t, func() {
+		hook := test.NewGlobal()
+		defer hook.Reset()
+		h := middleware.RequestID(requestLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})))
+		r := httptest.NewRequest("GET", "/brew", nil)
+		r.Header.Set(reqIDHeader, "web-7")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		So(w.Header().Get(reqIDHeader), ShouldEqual, "web-7")
+		So(hook.LastEntry().Data["reqID"], ShouldEqual, "web-7")
+		So(hook.LastEntry().Data["status"], ShouldEqual, http.StatusTeapot)
+	})
+
+	Convey("The log level can be changed at runtime", t, func() {
+		level := log.GetLevel()
+		defer log.SetLevel(level)
+
+		w := httptest.NewRecorder()
+		logLevelHandler(w, httptest.NewRequest("PUT", "/api/v1/loglevel?level=warn", nil))
+		So(strings.TrimSpace(w.Body.String()), ShouldEqual, "warning")
+		So(log.GetLevel(), ShouldEqual, log.WarnLevel)
+
+		w = httptest.NewRecorder()
+		logLevelHandler(w, httptest.NewRequest("PUT", "/api/v1/loglevel?level=loud", nil))
+		So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+		So(setupLogging(logConfig{Format: "xml"}), ShouldNotBeNil)
+		So(setupLogging(logConfig{Format: "json", Level: "info"}), ShouldBeNil)
+		_, isJSON := log.StandardLogger().Formatter.(*log.JSONFormatter)
+		So(isJSON, ShouldBeTrue)
+		So(setupLogging(logConfig{}), ShouldBeNil)
+	})
+}
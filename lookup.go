@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+const (
+	// defaultScrapeTime is the guess at how long a scrape takes until one
+	// has been timed.
+	defaultScrapeTime = 2 * time.Second
+	// errorDomain names this service in ErrorInfo details.
+	errorDomain = "farmstats"
+)
+
+// httpStatusError is a fetch that got a response other than 200 OK.
+type httpStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *httpStatusError) Error() string {
+	return e.Status
+}
+
+// parseError is a farm page we could not read the villagers from.
+type parseError struct {
+	FarmID FarmID
+	Reason string
+}
+
+func (e *parseError) Error() string {
+	return fmt.Sprintf("%s for %s", e.Reason, e.FarmID)
+}
+
+// farmFailure is why the last scrape of a farm failed.
+type farmFailure struct {
+	FarmID FarmID
+	Err    error
+	At     time.Time
+}
+
+func (f farmFailure) Error() string {
+	return fmt.Sprintf("cannot fetch farm %s: %v", f.FarmID, f.Err)
+}
+
+// farmFailureJSON is how a farmFailure is written to snapshots, keeping
+// enough of the error for failureError to classify it after a restore.
+type farmFailureJSON struct {
+	FarmID      FarmID    `json:"farmID"`
+	Error       string    `json:"error"`
+	StatusCode  int       `json:"statusCode,omitempty"`
+	ParseReason string    `json:"parseReason,omitempty"`
+	At          time.Time `json:"at"`
+}
+
+func (f farmFailure) MarshalJSON() ([]byte, error) {
+	j := farmFailureJSON{FarmID: f.FarmID, Error: f.Err.Error(), At: f.At}
+	switch err := f.Err.(type) {
+	case *httpStatusError:
+		j.StatusCode = err.StatusCode
+	case *parseError:
+		j.ParseReason = err.Reason
+	}
+	return json.Marshal(j)
+}
+
+func (f *farmFailure) UnmarshalJSON(data []byte) error {
+	var j farmFailureJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	f.FarmID, f.At = j.FarmID, j.At
+	switch {
+	case j.StatusCode != 0:
+		f.Err = &httpStatusError{StatusCode: j.StatusCode, Status: j.Error}
+	case j.ParseReason != "":
+		f.Err = &parseError{FarmID: j.FarmID, Reason: j.ParseReason}
+	default:
+		f.Err = errors.New(j.Error)
+	}
+	return nil
+}
+
+// queuedFarm is when a farm was queued, and in which lane.
+type queuedFarm struct {
+	At   time.Time
+	Lane lane
+}
+
+// farmTracker follows farms between being queued and stored, so that
+// GetStats can say more than "not found".
+type farmTracker struct {
+	mu         sync.Mutex
+	queued     map[FarmID]queuedFarm
+	failed     map[FarmID]farmFailure
+	scrapeTime time.Duration
+}
+
+func newFarmTracker() *farmTracker {
+	return &farmTracker{
+		queued:     make(map[FarmID]queuedFarm),
+		failed:     make(map[FarmID]farmFailure),
+		scrapeTime: defaultScrapeTime,
+	}
+}
+
+var tracker = newFarmTracker()
+
+// farmFailures carries a farmFailure each time a scrape fails.
+var farmFailures = newHub()
+
+// markQueued records a farm going into lane l. A farm queued twice is
+// tracked in whichever lane will reach it sooner.
+func (t *farmTracker) markQueued(farmID FarmID, l lane) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	q, ok := t.queued[farmID]
+	if !ok {
+		t.queued[farmID] = queuedFarm{At: time.Now(), Lane: l}
+	} else if laneWeights[l] > laneWeights[q.Lane] {
+		q.Lane = l
+		t.queued[farmID] = q
+	}
+}
+
+// queuedIn reports the lane a queued farm is waiting in.
+func (t *farmTracker) queuedIn(farmID FarmID) (lane, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	q, ok := t.queued[farmID]
+	return q.Lane, ok
+}
+
+// unqueue undoes markQueued for a farm that never made it onto the queue.
+func (t *farmTracker) unqueue(farmID FarmID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.queued, farmID)
+}
+
+// markDone forgets a farm once it is stored, or turns out not to need
+// scraping.
+func (t *farmTracker) markDone(farmID FarmID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.queued, farmID)
+	delete(t.failed, farmID)
+}
+
+func (t *farmTracker) markFailed(farmID FarmID, err error) {
+	failure := farmFailure{FarmID: farmID, Err: err, At: time.Now()}
+	t.mu.Lock()
+	delete(t.queued, farmID)
+	t.failed[farmID] = failure
+	t.mu.Unlock()
+	farmFailures.publish(failure)
+}
+
+// failures returns every farm whose last scrape failed, by farm id.
+func (t *farmTracker) failures() []farmFailure {
+	t.mu.Lock()
+	failures := make([]farmFailure, 0, len(t.failed))
+	for _, failure := range t.failed {
+		failures = append(failures, failure)
+	}
+	t.mu.Unlock()
+	sort.Slice(failures, func(i, j int) bool { return failures[i].FarmID < failures[j].FarmID })
+	return failures
+}
+
+// restoreFailure records a failure read back from a snapshot.
+func (t *farmTracker) restoreFailure(failure farmFailure) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.failed[failure.FarmID] = failure
+}
+
+// observe folds a scrape's duration into the moving average.
+func (t *farmTracker) observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scrapeTime = (t.scrapeTime*7 + d) / 8
+}
+
+// state reports whether a farm is queued and how its last scrape failed.
+func (t *farmTracker) state(farmID FarmID) (bool, *farmFailure) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, queued := t.queued[farmID]
+	if failure, ok := t.failed[farmID]; ok {
+		return queued, &failure
+	}
+	return queued, nil
+}
+
+// average is the moving average scrape time.
+func (t *farmTracker) average() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.scrapeTime
+}
+
+// retryAfter estimates how long a farm at the back of a queue of depth
+// farms will wait to be scraped.
+func (t *farmTracker) retryAfter(depth int) time.Duration {
+	n := activeWorkers()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	wait := t.scrapeTime * time.Duration(depth+1) / time.Duration(n)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait.Round(time.Second)
+}
+
+// withDetails attaches error details to a status, falling back to the
+// plain status if they cannot be encoded.
+func withDetails(st *grpcstatus.Status, details ...proto.Message) error {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		log.WithError(err).Warn("cannot add error details")
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func retryInfo(d time.Duration) *errdetails.RetryInfo {
+	return &errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(d)}
+}
+
+func farmResource(farmID FarmID, description string) *errdetails.ResourceInfo {
+	return &errdetails.ResourceInfo{ResourceType: "farm", ResourceName: string(farmID), Description: description}
+}
+
+// queuedError tells the caller to come back once the farm has been
+// scraped, estimating the wait from the lane it is queued in.
+func (s *farmStats) queuedError(farmID FarmID) error {
+	depth := 0
+	if s.queue != nil {
+		l, ok := tracker.queuedIn(farmID)
+		if !ok {
+			l = laneInteractive
+		}
+		depth = s.queue.ahead(l)
+	}
+	retry := tracker.retryAfter(depth)
+	st := grpcstatus.Newf(codes.Unavailable, "farm %s is queued, retry after %v", farmID, retry)
+	return withDetails(st, retryInfo(retry), farmResource(farmID, "queued to be fetched"))
+}
+
+// failureError describes a failed scrape: farms missing from upload.farm
+// are not found, pages we cannot read are internal errors, and anything
+// else is worth retrying.
+func failureError(f farmFailure) error {
+	info := &errdetails.ErrorInfo{
+		Domain:   errorDomain,
+		Metadata: map[string]string{"farmID": string(f.FarmID), "error": f.Err.Error(), "at": f.At.UTC().Format(time.RFC3339)},
+	}
+	switch err := f.Err.(type) {
+	case *httpStatusError:
+		if err.StatusCode == 404 {
+			info.Type = "FARM_NOT_ON_UPLOAD_FARM"
+			st := grpcstatus.Newf(codes.NotFound, "farm %s is not on upload.farm", f.FarmID)
+			return withDetails(st, info)
+		}
+	case *parseError:
+		info.Type = "PARSE_FAILED"
+		st := grpcstatus.Newf(codes.Internal, "farm %s failed to parse: %s", f.FarmID, err.Reason)
+		return withDetails(st, info)
+	}
+	info.Type = "FETCH_FAILED"
+	st := grpcstatus.Newf(codes.Unavailable, "cannot fetch farm %s: %v", f.FarmID, f.Err)
+	return withDetails(st, info, retryInfo(tracker.retryAfter(0)))
+}
+
+// GetStats returns a stored farm. Farms that are not stored can be queued
+// with req.Enqueue, and fetched while the request waits with req.Wait or in
+// on-demand mode.
+func (s *farmStats) GetStats(ctx context.Context, req *pb.FarmID) (*pb.Farm, error) {
+	farmID, err := ParseFarmID(req.Id)
+	if err != nil {
+		st := grpcstatus.New(codes.InvalidArgument, err.Error())
+		return nil, withDetails(st, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "id", Description: err.Error()}},
+		})
+	}
+
+	s.mu.Lock()
+	stats, ok := s.stats[string(farmID)]
+	if s.lookups != nil {
+		s.lookups[string(farmID)]++
+	}
+	s.mu.Unlock()
+	if ok {
+		return stats.toProto(), nil
+	}
+
+	if (s.onDemand || req.Enqueue && req.Wait) && s.fetcher != nil {
+		stats, err := s.fetcher.fetch(ctx, farmID)
+		if err == nil {
+			return stats.toProto(), nil
+		}
+		if failure, ok := err.(farmFailure); ok {
+			return nil, failureError(failure)
+		}
+		if err == context.Canceled {
+			return nil, grpcstatus.FromContextError(err).Err()
+		}
+		retry := tracker.retryAfter(s.fetcher.queue.ahead(laneInteractive))
+		st := grpcstatus.Newf(codes.DeadlineExceeded, "farm %s is still queued, retry after %v", farmID, retry)
+		return nil, withDetails(st, retryInfo(retry), farmResource(farmID, "queued to be fetched"))
+	}
+
+	queued, failure := tracker.state(farmID)
+	if !req.Enqueue || s.queue == nil {
+		switch {
+		case queued:
+			return nil, s.queuedError(farmID)
+		case failure != nil:
+			return nil, failureError(*failure)
+		}
+		st := grpcstatus.Newf(codes.NotFound, "farm %s not found", farmID)
+		return nil, withDetails(st, farmResource(farmID, "not stored; set enqueue to fetch it"))
+	}
+
+	if !queued {
+		farmReq, enqueueSpan := newFarmRequest(ctx, farmID)
+		tracker.markQueued(farmID, laneInteractive)
+		select {
+		case <-ctx.Done():
+			tracker.unqueue(farmID)
+			enqueueSpan.finish(ctx.Err())
+			return nil, grpcstatus.FromContextError(ctx.Err()).Err()
+		case s.queue.lane(laneInteractive) <- farmReq:
+			enqueueSpan.finish(nil)
+			farmReq.logger().Debug("queued farm for GetStats")
+		}
+	}
+	return nil, s.queuedError(farmID)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
+	"github.com/golang/protobuf/ptypes"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// detail returns the first detail of err's status with the same type as
+// like, or nil.
+func detail(err error, like interface{}) interface{} {
+	for _, d := range grpcstatus.Convert(err).Details() {
+		if reflect.TypeOf(d) == reflect.TypeOf(like) {
+			return d
+		}
+	}
+	return nil
+}
+
+func TestGetStats(t *testing.T) {
+	Convey("Given a farm store with a queue", t, func() {
+		tracker = newFarmTracker()
+		farmQueue := newFarmQueue(10)
+		farms := &farmStats{stats: map[string]svStats{"1AAAAA": {FarmID: "1AAAAA", Abigail: 3}}, queue: farmQueue}
+		farms.fetcher = newFetcher(farms, farmQueue)
+		queue := farmQueue.lane(laneInteractive)
+		ctx := context.Background()
+
+		Convey("a stored farm is returned", func() {
+			farm, err := farms.GetStats(ctx, &pb.FarmID{Id: "1AAAAA"})
+			So(err, ShouldBeNil)
+			So(farm.Abigail, ShouldEqual, 3)
+		})
+
+		Convey("an invalid id is InvalidArgument with the field at fault", func() {
+			_, err := farms.GetStats(ctx, &pb.FarmID{Id: "1bc-12"})
+			So(grpcstatus.Code(err), ShouldEqual, codes.InvalidArgument)
+			bad := detail(err, &errdetails.BadRequest{}).(*errdetails.BadRequest)
+			So(bad.FieldViolations[0].Field, ShouldEqual, "id")
+		})
+
+		Convey("an unknown farm is NotFound and is not queued", func() {
+			_, err := farms.GetStats(ctx, &pb.FarmID{Id: "1BBBBB"})
+			So(grpcstatus.Code(err), ShouldEqual, codes.NotFound)
+			So(detail(err, &errdetails.ResourceInfo{}), ShouldNotBeNil)
+			So(len(queue), ShouldEqual, 0)
+		})
+
+		Convey("a queued farm is Unavailable with a retry delay", func() {
+			tracker.markQueued("1BBBBB", laneInteractive)
+			_, err := farms.GetStats(ctx, &pb.FarmID{Id: "1BBBBB"})
+			So(grpcstatus.Code(err), ShouldEqual, codes.Unavailable)
+			retry := detail(err, &errdetails.RetryInfo{}).(*errdetails.RetryInfo)
+			delay, _ := ptypes.Duration(retry.RetryDelay)
+			So(delay, ShouldBeGreaterThanOrEqualTo, time.Second)
+
+			Convey("...estimated from the lane it waits in", func() {
+				for i := 0; i < 9; i++ {
+					farmQueue.lane(laneBackfill) <- farmRequest{FarmID: FarmID(fmt.Sprintf("1C%04d", i))}
+				}
+				tracker.markQueued("1DDDDD", laneBackfill)
+				_, err := farms.GetStats(ctx, &pb.FarmID{Id: "1DDDDD"})
+				retry := detail(err, &errdetails.RetryInfo{}).(*errdetails.RetryInfo)
+				backfillDelay, _ := ptypes.Duration(retry.RetryDelay)
+				So(backfillDelay, ShouldBeGreaterThan, delay)
+
+				tracker.markQueued("1DDDDD", laneInteractive)
+				lane, _ := tracker.queuedIn("1DDDDD")
+				So(lane, ShouldEqual, laneInteractive)
+			})
+		})
+
+		Convey("a farm missing from upload.farm is NotFound", func() {
+			tracker.markFailed("1BBBBB", &httpStatusError{StatusCode: 404, Status: "404 Not Found"})
+			_, err := farms.GetStats(ctx, &pb.FarmID{Id: "1BBBBB"})
+			So(grpcstatus.Code(err), ShouldEqual, codes.NotFound)
+			info := detail(err, &errdetails.ErrorInfo{}).(*errdetails.ErrorInfo)
+			So(info.Type, ShouldEqual, "FARM_NOT_ON_UPLOAD_FARM")
+		})
+
+		Convey("a farm that failed to parse is Internal with the reason", func() {
+			tracker.markFailed("1BBBBB", &parseError{FarmID: "1BBBBB", Reason: "no villagers found"})
+			_, err := farms.GetStats(ctx, &pb.FarmID{Id: "1BBBBB"})
+			So(grpcstatus.Code(err), ShouldEqual, codes.Internal)
+			So(grpcstatus.Convert(err).Message(), ShouldContainSubstring, "failed to parse: no villagers found")
+			info := detail(err, &errdetails.ErrorInfo{}).(*errdetails.ErrorInfo)
+			So(info.Type, ShouldEqual, "PARSE_FAILED")
+		})
+
+		Convey("a farm that could not be fetched is Unavailable", func() {
+			tracker.markFailed("1BBBBB", errors.New("connection refused"))
+			_, err := farms.GetStats(ctx, &pb.FarmID{Id: "1BBBBB"})
+			So(grpcstatus.Code(err), ShouldEqual, codes.Unavailable)
+			So(detail(err, &errdetails.RetryInfo{}), ShouldNotBeNil)
+		})
+
+		Convey("enqueue queues an unknown farm once", func() {
+			_, err := farms.GetStats(ctx, &pb.FarmID{Id: "1BBBBB", Enqueue: true})
+			So(grpcstatus.Code(err), ShouldEqual, codes.Unavailable)
+			So(len(queue), ShouldEqual, 1)
+			req := <-queue
+			So(req.FarmID, ShouldEqual, FarmID("1BBBBB"))
+
+			_, err = farms.GetStats(ctx, &pb.FarmID{Id: "1BBBBB", Enqueue: true})
+			So(grpcstatus.Code(err), ShouldEqual, codes.Unavailable)
+			So(len(queue), ShouldEqual, 0)
+		})
+
+		Convey("enqueue and wait returns the farm once it is stored", func() {
+			go func() {
+				req := <-queue
+				stats := svStats{FarmID: string(req.FarmID), Sam: 7}
+				farms.store(stats)
+				tracker.markDone(req.FarmID)
+				farmEvents.publish(stats)
+			}()
+			farm, err := farms.GetStats(ctx, &pb.FarmID{Id: "1BBBBB", Enqueue: true, Wait: true})
+			So(err, ShouldBeNil)
+			So(farm.Sam, ShouldEqual, 7)
+		})
+
+		Convey("enqueue and wait reports a failed scrape", func() {
+			go func() {
+				req := <-queue
+				tracker.markFailed(req.FarmID, &parseError{FarmID: req.FarmID, Reason: "no villagers found"})
+			}()
+			_, err := farms.GetStats(ctx, &pb.FarmID{Id: "1BBBBB", Enqueue: true, Wait: true})
+			So(grpcstatus.Code(err), ShouldEqual, codes.Internal)
+		})
+
+		Convey("enqueue and wait gives up at the deadline", func() {
+			ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer cancel()
+			_, err := farms.GetStats(ctx, &pb.FarmID{Id: "1BBBBB", Enqueue: true, Wait: true})
+			So(grpcstatus.Code(err), ShouldEqual, codes.DeadlineExceeded)
+			So(detail(err, &errdetails.RetryInfo{}), ShouldNotBeNil)
+		})
+	})
+
+	Convey("Retry delays grow with the queue", t, func() {
+		tr := newFarmTracker()
+		So(tr.retryAfter(0), ShouldEqual, time.Second)
+		So(tr.retryAfter(9), ShouldEqual, 10*time.Second)
+		tr.observe(10 * time.Second)
+		So(tr.retryAfter(9), ShouldBeGreaterThan, 10*time.Second)
+	})
+}
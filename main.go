@@ -1,7 +1,9 @@
 package main
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -19,15 +21,17 @@ import (
 	"time"
 
 	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
-	"github.com/firstrow/tcp_server"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-redis/redis"
 	resque "github.com/kavu/go-resque"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type statistics struct {
@@ -40,10 +44,13 @@ type statistics struct {
 const (
 	defaultHTTPport   = ":8080"
 	defaultTelnetPort = "3333"
+	defaultCrawlPages = 10
 )
 
 type svStats struct {
 	FarmID                           string
+	Fetched                          time.Time
+	PageHash                         string
 	Abigail, Alex, Caroline, Clint   uint32
 	Demetrius, Dwarf, Elliott, Emily uint32
 	Evelyn, George, Gus, Haley       uint32
@@ -55,9 +62,91 @@ type svStats struct {
 	Willy, Wizard                    uint32
 }
 
+// farmRequest is a farm waiting to be fetched. ReqID ties together the log
+// lines of the request that queued it, from telnet, HTTP, gRPC or a job, and
+// Trace is the enqueue span its processing spans hang from.
+type farmRequest struct {
+	FarmID FarmID
+	ReqID  string
+	Trace  spanContext
+	Queued time.Time
+	// Lane is the lane the farm was taken from.
+	Lane lane
+	// done, if set, is told how processing went.
+	done func(error)
+}
+
+// farmResult is a scraped farm on its way to the store.
+type farmResult struct {
+	Stats svStats
+	ReqID string
+	Trace spanContext
+}
+
+func (req farmRequest) logger() *log.Entry {
+	return log.WithFields(log.Fields{"reqID": req.ReqID, "farmID": req.FarmID})
+}
+
+// finish reports how processing went to whoever queued the farm, if they
+// asked.
+func (req farmRequest) finish(err error) {
+	if req.done != nil {
+		req.done(err)
+	}
+}
+
+// context rebuilds the request's correlation ID and trace on parent, for
+// the code processing it.
+func (req farmRequest) context(parent context.Context) context.Context {
+	return contextWithSpan(withReqID(parent, req.ReqID), req.Trace)
+}
+
+// newFarmRequest starts the enqueue span for a farm, as part of the request
+// and trace in ctx. The caller finishes the span once the farm is queued.
+func newFarmRequest(ctx context.Context, farmID FarmID) (farmRequest, *span) {
+	_, s := startSpan(ctx, "enqueue")
+	s.setAttr("farmID", string(farmID))
+	req := farmRequest{
+		FarmID: farmID,
+		ReqID:  reqIDFromContext(ctx),
+		Trace:  s.context(),
+		Queued: time.Now(),
+	}
+	return req, s
+}
+
+// enqueue adds a farm to a lane of the processing queue, waiting for room
+// unless ctx ends first.
+func enqueue(ctx context.Context, queue *farmQueue, l lane, farmID FarmID) error {
+	req, s := newFarmRequest(ctx, farmID)
+	s.setAttr("lane", l.String())
+	req.logger().Debugf("queueing farm in %s lane, %d waiting", l, len(queue.lane(l)))
+	tracker.markQueued(farmID, l)
+	select {
+	case queue.lane(l) <- req:
+		s.finish(nil)
+		return nil
+	case <-ctx.Done():
+		tracker.unqueue(farmID)
+		s.finish(ctx.Err())
+		return ctx.Err()
+	}
+}
+
 type farmStats struct {
-	mu    sync.Mutex
-	stats map[string]svStats
+	mu      sync.Mutex
+	stats   map[string]svStats
+	history map[string][]svStats
+	lookups map[string]int
+	// queue is where GetStats sends farms it is asked to fetch.
+	queue *farmQueue
+	// fetcher scrapes farms that requests wait for, and onDemand has it
+	// do so for every request for an unknown farm.
+	fetcher  *fetcher
+	onDemand bool
+	// loaded is set once the startup restore has finished, and cleared
+	// while a restore runs.
+	loaded bool
 }
 type spiderStatus struct {
 	mu         sync.Mutex
@@ -65,12 +154,37 @@ type spiderStatus struct {
 	numRunning int
 }
 
+func (s *spiderStatus) running() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.numRunning, s.stop
+}
+
+// requestStop asks every running multi-page spider to stop, returning how
+// many there were. With none running there is nothing to stop, and the next
+// spider must not see a stale request.
+func (s *spiderStatus) requestStop() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.numRunning > 0 {
+		s.stop = true
+	}
+	return s.numRunning
+}
+
 var allFarms farmStats
 var status spiderStatus
+var backfill *backfiller
+var refresh *refresher
+var jobs *scheduler
+var pages *pageCache
+var probes *healthChecker
+var workers *workerPool
 
 var serverCtx context.Context
 var chars = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 var httpClient = http.DefaultClient
+var farmBaseURL = "https://upload.farm"
 
 func setupHTTPClient() {
 	proxyStr := os.Getenv("http_proxy")
@@ -92,49 +206,105 @@ func setupHTTPClient() {
 }
 
 func main() {
+	configPath := flag.String("config", "", "path to JSON config file")
+	tokenToHash := flag.String("hash-token", "", "print the hash of an API token for the config, and exit")
+	flag.Parse()
+	if *tokenToHash != "" {
+		fmt.Println(hashToken(*tokenToHash))
+		return
+	}
+
 	//log.SetOutput(ioutil.Discard)
 	log.SetLevel(log.DebugLevel)
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("cannot load config: %v", err)
+	}
+	err = setupLogging(cfg.Log)
+	if err != nil {
+		log.Fatalf("cannot set up logging: %v", err)
+	}
+	err = setupTracing(cfg.Trace)
+	if err != nil {
+		log.Fatalf("cannot set up tracing: %v", err)
+	}
+	auth, err = newAuthenticator(cfg.Auth)
+	if err != nil {
+		log.Fatalf("cannot set up authentication: %v", err)
+	}
+	if !auth.enabled() {
+		log.Warn("no API tokens configured, so telnet, HTTP and gRPC are open to anyone")
+	}
 	log.Infof("Starting Innocuous server %s %d", "v1.0", runtime.GOMAXPROCS(0))
 	redisdb := redis.NewClient(&redis.Options{
 		Addr:     ":6379",
 		PoolSize: 0,
 	})
 	setupHTTPClient()
+	pages, err = newPageCache(cfg.Cache)
+	if err != nil {
+		log.Fatalf("cannot set up page cache: %v", err)
+	}
 
-	allFarms.stats = make(map[string]svStats)
+	images, err = newImageStore(cfg.Images)
+	if err != nil {
+		log.Fatalf("cannot set up farm images: %v", err)
+	}
 
-	queue := make(chan string, 100)
-	statsQueue := make(chan svStats, 100)
+	allFarms.stats = make(map[string]svStats)
+	allFarms.history = make(map[string][]svStats)
+	allFarms.lookups = make(map[string]int)
+
+	queue := newFarmQueue(100)
+	statsQueue := make(chan farmResult, 100)
+	allFarms.queue = queue
+	allFarms.fetcher = newFetcher(&allFarms, queue)
+	allFarms.onDemand = cfg.OnDemand
+	registerQueueMetrics(queue, statsQueue)
+	// the farm workers, plus the stats consumer
+	probes = newHealthChecker(redisdb, cfg.Workers.Min+1)
+	backfill = newBackfiller(redisdb, queue)
+	refresh = newRefresher(queue)
 
 	go func() {
-		for stats := range statsQueue {
-			log.Debugf("processing stats %v", stats)
-			allFarms.mu.Lock()
-			allFarms.stats[stats.FarmID] = stats
-			log.Debugf("processed stats %v", len(allFarms.stats))
-			allFarms.mu.Unlock()
+		probes.workerStarted()
+		defer probes.workerStopped()
+		for result := range statsQueue {
+			_, s := startSpan(contextWithSpan(context.Background(), result.Trace), "store")
+			s.setAttr("farmID", result.Stats.FarmID)
+			allFarms.store(result.Stats)
+			s.finish(nil)
+			tracker.markDone(FarmID(result.Stats.FarmID))
+			farmEvents.publish(result.Stats)
+			log.WithFields(log.Fields{
+				"reqID":  result.ReqID,
+				"farmID": result.Stats.FarmID,
+			}).Info("stored farm")
 		}
 	}()
 
-	farmIDProcessor := func() {
-		log.Debugf("processing farm ids[%d]\n", len(queue))
-		for farmID := range queue {
-			processFarmID(farmID, statsQueue)
-		}
+	workers, err = newWorkerPool(queue, statsQueue, cfg.Workers)
+	if err != nil {
+		log.Fatalf("cannot set up workers: %v", err)
+	}
+	workers.start(nil)
+	if images != nil {
+		images.start(nil)
 	}
-	go farmIDProcessor()
-	go farmIDProcessor()
 	go telnetServer(defaultTelnetPort, queue, redisdb)
-	go httpServer()
-	go grpcServer()
+	go httpServer(cfg.TLS.HTTP, redisdb)
+	go grpcServer(newAdminServer(queue, statsQueue, redisdb), cfg.TLS.GRPC)
 
+	jobs, err = setupJobs(cfg, queue, redisdb)
+	if err != nil {
+		log.Fatalf("cannot schedule jobs: %v", err)
+	}
+	jobs.start()
+	go probes.watch(nil)
 	go func() {
-		for {
-			fetchRecents(queue)
-			time.Sleep(30 * time.Second)
-			fetchRecents(queue)
-			time.Sleep(30 * time.Second)
-			break
+		err := loadStore(cfg.Restore, redisdb)
+		if err != nil {
+			log.WithError(err).Error("cannot load the store, staying not ready")
 		}
 	}()
 
@@ -152,31 +322,99 @@ func main() {
 
 }
 
+// jobContext starts the correlation ID and root span for one run of a job.
+func jobContext(name string) (context.Context, *span) {
+	return startSpan(withReqID(context.Background(), newReqID(name)), name)
+}
+
+// setupJobs registers the recurring jobs with their configured schedules.
+func setupJobs(cfg config, queue *farmQueue, redisdb *redis.Client) (*scheduler, error) {
+	s := newScheduler()
+	jobFuncs := map[string]jobFunc{
+		"recents": func(stop <-chan struct{}) error {
+			ctx, s := jobContext("recents")
+			_, err := fetchRecents(ctx, queue)
+			s.finish(err)
+			return err
+		},
+		"crawl": func(stop <-chan struct{}) error {
+			ctx, s := jobContext("crawl")
+			err := crawlRecent(ctx, queue, redisdb, stop)
+			s.finish(err)
+			return err
+		},
+		"refresh": func(stop <-chan struct{}) error {
+			refresh.refreshOnce(stop)
+			return nil
+		},
+		"backfill": backfill.job,
+	}
+	if images != nil {
+		jobFuncs["images"] = images.catchUp
+	}
+	for name, run := range jobFuncs {
+		jobCfg, ok := cfg.Jobs[name]
+		if !ok {
+			continue
+		}
+		err := s.add(name, jobCfg, run)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
 func enqueueRedis(enqueuer *resque.RedisEnqueuer, farmID string) error {
 	_, err := enqueuer.Enqueue("farm", "Process::Farm", farmID)
 	return err
 }
 
-func grpcServer() {
+func grpcServer(admin *adminServer, tlsCfg tlsConfig) {
 	lis, err := net.Listen("tcp", "localhost:3334")
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
 	var opts []grpc.ServerOption
+	serverTLS, err := setupTLS("grpc", tlsCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if serverTLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(serverTLS)))
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(grpcRequestID, grpcTracing, grpcMetrics, grpcAuth))
+	opts = append(opts, grpc.ChainStreamInterceptor(grpcStreamRequestID, grpcStreamTracing, grpcStreamMetrics, grpcStreamAuth))
 	grpcServer := grpc.NewServer(opts...)
 	pb.RegisterFarmStatsServer(grpcServer, &allFarms)
+	pb.RegisterAdminServer(grpcServer, admin)
+	if images != nil {
+		pb.RegisterImgDownloadServer(grpcServer, images)
+	}
+	healthpb.RegisterHealthServer(grpcServer, probes.grpc)
 	grpcServer.Serve(lis)
 }
 
-func (s *farmStats) GetStats(ctx context.Context, farmID *pb.FarmID) (*pb.Farm, error) {
-	allFarms.mu.Lock()
-	stats, ok := allFarms.stats[farmID.Id]
-	allFarms.mu.Unlock()
-	if !ok {
-		return nil, fmt.Errorf("404 not found")
+// store records a fresh scrape, keeping earlier scrapes of the same farm in
+// its history. A scrape of an unchanged page only updates the fetch time.
+func (s *farmStats) store(stats svStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.history == nil {
+		s.history = make(map[string][]svStats)
+	}
+	s.stats[stats.FarmID] = stats
+	versions := s.history[stats.FarmID]
+	if len(versions) > 0 && stats.PageHash != "" && versions[len(versions)-1].PageHash == stats.PageHash {
+		return
 	}
+	s.history[stats.FarmID] = append(versions, stats)
+	log.Debugf("processed stats %v", len(s.stats))
+}
+
+func (stats svStats) toProto() *pb.Farm {
 	return &pb.Farm{
-		Id:        farmID.Id,
+		Id:        stats.FarmID,
 		Abigail:   stats.Abigail,
 		Alex:      stats.Alex,
 		Caroline:  stats.Caroline,
@@ -211,201 +449,139 @@ func (s *farmStats) GetStats(ctx context.Context, farmID *pb.FarmID) (*pb.Farm,
 		Vincent:   stats.Vincent,
 		Willy:     stats.Willy,
 		Wizard:    stats.Wizard,
-	}, nil
+	}
 }
 
-func httpServer() {
+func httpServer(tlsCfg tlsConfig, redisdb snapshotStore) {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
-	r.Use(middleware.Logger)
+	r.Use(requestLogger)
+	r.Use(httpTracing)
 	r.Use(middleware.Recoverer)
-	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("X-Request-Id", middleware.GetReqID(r.Context()))
-		allFarms.mu.Lock()
-		render.JSON(w, r, allFarms.stats)
-		allFarms.mu.Unlock()
+	r.Group(func(r chi.Router) {
+		r.Use(apiAuth)
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			allFarms.mu.Lock()
+			render.JSON(w, r, allFarms.stats)
+			allFarms.mu.Unlock()
 
+		})
+
+		r.Get("/api/v1/farms/{farmID}", farmHandler)
+		r.Get("/api/v1/farms/{farmID}/history", farmHistoryHandler)
+		r.Get("/api/v1/farms/{farmID}/image", farmImageHandler("image"))
+		r.Get("/api/v1/farms/{farmID}/thumbnail", farmImageHandler("thumbnail"))
+		r.Get("/api/v1/farms/{farmID}/duplicates", farmDuplicatesHandler)
+		r.Get("/api/v1/export", exportHandler)
+		r.With(requireRole(roleAdmin)).Get("/api/v1/snapshot", snapshotHandler(redisdb))
+		r.Post("/api/v1/restore", restoreHandler(redisdb))
+		r.Mount("/api/v1/jobs", jobs.jobsRouter())
+		r.Get("/api/v1/loglevel", logLevelHandler)
+		r.Put("/api/v1/loglevel", logLevelHandler)
 	})
+	r.Handle("/metrics", promhttp.Handler())
+	r.Get("/healthz", probes.liveHandler)
+	r.Get("/readyz", probes.readyHandler)
 
 	r.Get("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "public/favicon.ico")
 	})
 
-	http.ListenAndServe(":8080", r)
+	serverTLS, err := setupTLS("http", tlsCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	srv := &http.Server{Addr: ":8080", Handler: r, TLSConfig: serverTLS}
+	if serverTLS != nil {
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	log.WithError(err).Error("http server stopped")
 }
 
-func telnetServer(telnetPort string, queue chan string, redisdb *redis.Client) {
-	telnetSvr := tcp_server.New("127.0.0.1:" + telnetPort)
-	telnetSvr.OnNewClient(func(c *tcp_server.Client) {
-		// log.Println("new connection")
-		c.Send("welcome\n")
-		// log.Println("sent welcome message")
-	})
-	telnetSvr.OnNewMessage(func(c *tcp_server.Client, message string) {
-		// log.Printf("received message %s", message)
-		message = strings.TrimRight(message, "\r\n")
-		if len(message) == 0 {
-			// empty line, so message[0] is not present :-) issue #1
-			c.Send("/help for help\n")
-			return
-		}
+// fetchRecents queues every farm on the recent farms list, returning their
+// ids.
+func fetchRecents(ctx context.Context, queue *farmQueue) ([]FarmID, error) {
+	body, err := fetchURL("https://upload.farm/_mini_recents")
+	if err != nil {
+		return nil, err
+	}
 
-		if message[0] == '/' {
-			switch {
-			case message == "/ping":
-				c.Send("pong\n")
-			case message == "/help":
-				c.Send("usage:\n" +
-					"1F4Tjc - fetch farm 1F4Tjc if it's a valid id\n" +
-					"/ping - check connection, returns 'pong'\n" +
-					"/qsize - size of farm-fetching queue\n" +
-					"/show - show current stats\n" +
-					"/spiderstatus - show number of running spiders\n" +
-					"/fetch - fetch latest farm list and process new ones\n" +
-					"/spider - grab latest farms and add to queue\n" +
-					"/spider 3 - grab page 3 of historical farms and add to queue\n" +
-					"/spiderall 3 - grab from page 3 to 1 of historical farms & add to known farms list in redis\n" +
-					"/stopspider - tell \"spiderall\" spiders to stop\n" +
-					"/quit - terminate connection\n")
-			case message == "/fetch":
-				fetchRecents(queue)
-				c.Send("fetched recent farms\n")
-			case message == "/qsize":
-				c.Send(fmt.Sprintf("queue size is [%d]\n", len(queue)))
-			case message == "/quit":
-				c.Close()
-			case message == "/show":
-				c.Send(fmt.Sprintf("stats:\n"))
-				allFarms.mu.Lock()
-				for _, stats := range allFarms.stats {
-					c.Send(fmt.Sprintf("%s likes Abigail %d/10\n", stats.FarmID, stats.Abigail))
-				}
-				allFarms.mu.Unlock()
-			case message == "/spiderstatus":
-				status.mu.Lock()
-				numSpiders := status.numRunning
-				status.mu.Unlock()
-
-				c.Send(fmt.Sprintf("status: %d spiders running\n", numSpiders))
-			case message == "/stopspider":
-				status.mu.Lock()
-				numSpiders := status.numRunning
-				status.stop = true
-				status.mu.Unlock()
-
-				c.Send(fmt.Sprintf("asked %d spiders to stop\n", numSpiders))
-			case message == "/spider":
-				go func() {
-					fetchMany(queue, redisdb)
-				}()
-				c.Send("simple spider of homepage farms\n")
-			case strings.HasPrefix(message, "/spiderall "):
-				pageNum, err := strconv.Atoi(strings.TrimPrefix(message, "/spiderall "))
-				if err != nil {
-					c.Send("invalid last page number")
-					return
-				}
-
-				c.Send(fmt.Sprintf("spidering from page %d", pageNum))
-				go func() {
-					status.mu.Lock()
-					status.numRunning++
-					status.mu.Unlock()
-
-					var seenIDs []string
-					var seenPages int
-					for i := pageNum; i >= 0; i-- {
-						status.mu.Lock()
-						shouldStop := status.stop
-						status.mu.Unlock()
-
-						if shouldStop == true {
-							log.Info("received stop signal!")
-							break
-						}
-						idsFromPage, err := fetchPage(redisdb, i)
-						if err != nil {
-							continue
-						}
-						seenIDs = append(seenIDs, idsFromPage...)
-						seenPages++
-					}
-					status.mu.Lock()
-					status.numRunning--
-					if status.numRunning == 0 {
-						status.stop = false
-					}
-					status.mu.Unlock()
-
-					log.Infof("finished reading pages, saw %d farms on %d pages", len(seenIDs), seenPages)
-				}()
-				c.Send("multipage spider\n")
-			case strings.HasPrefix(message, "/spider "):
-				pageNum, err := strconv.Atoi(strings.TrimPrefix(message, "/spider "))
-				if err != nil {
-					c.Send("invalid page number")
-					return
-				}
-
-				c.Send(fmt.Sprintf("valid page number %d", pageNum))
-
-				go func() {
-					idsFromPage, err := fetchPage(redisdb, pageNum)
-					if err == nil {
-						for _, farmID := range idsFromPage {
-							fmt.Printf("queueing farmID [%s] [%d]", farmID, len(queue))
-							queue <- farmID
-							fmt.Printf("queued farmID [%s] [%d]", farmID, len(queue))
-						}
-					}
-				}()
-				c.Send("complex spider\n")
-			default:
-				c.Send(fmt.Sprintf("unknown command [%s] \n", message))
-			}
-			return
-		}
+	farmIDs, err := extractFarmIDs(body)
+	if err != nil {
+		return nil, err
+	}
 
-		farmID, err := extractFarmID(message)
-		if err == nil {
-			queue <- farmID
-			c.Send(fmt.Sprintf("queued farm id %s\n", farmID))
-			return
+	for i, farmID := range farmIDs {
+		if err := enqueue(ctx, queue, laneRecents, farmID); err != nil {
+			return farmIDs[:i], err
 		}
-		c.Send("invalid farm id (/help for help)\n")
-		return
-	})
-	telnetSvr.Listen()
+	}
+	return farmIDs, nil
 }
 
-func fetchRecents(queue chan string) {
-	body, err := fetchURL("https://upload.farm/_mini_recents")
+// spiderPage queues the farms on one page of the listing.
+func spiderPage(ctx context.Context, queue *farmQueue, redisdb zAddNXer, pageNum int) {
+	idsFromPage, err := fetchPage(redisdb, pageNum)
 	if err != nil {
 		return
 	}
-
-	farmIDs, err := extractFarmIDs(body)
-	if err != nil {
-		return
+	for _, farmID := range idsFromPage {
+		if enqueue(ctx, queue, laneBackfill, farmID) != nil {
+			return
+		}
 	}
+}
 
-	for _, farmID := range farmIDs {
-		queue <- farmID
+// startSpiderAll records the farms on every page of the listing from
+// lastPage back to page 0 in redis, in the background, until asked to stop.
+// The spider is counted as running before this returns.
+func startSpiderAll(ctx context.Context, redisdb zAddNXer, lastPage int) {
+	status.mu.Lock()
+	status.numRunning++
+	status.mu.Unlock()
+	go spiderAll(ctx, redisdb, lastPage)
+}
+
+func spiderAll(ctx context.Context, redisdb zAddNXer, lastPage int) {
+	logger := log.WithField("reqID", reqIDFromContext(ctx))
+	var seenIDs []FarmID
+	var seenPages int
+	for i := lastPage; i >= 0; i-- {
+		if _, shouldStop := status.running(); shouldStop {
+			logger.Info("received stop signal!")
+			break
+		}
+		idsFromPage, err := fetchPage(redisdb, i)
+		if err != nil {
+			continue
+		}
+		seenIDs = append(seenIDs, idsFromPage...)
+		seenPages++
+	}
+	status.mu.Lock()
+	status.numRunning--
+	if status.numRunning == 0 {
+		status.stop = false
 	}
+	status.mu.Unlock()
+
+	logger.Infof("finished reading pages, saw %d farms on %d pages", len(seenIDs), seenPages)
 }
 
-func fetchPage(redisdb zAddNXer, pageNum int) ([]string, error) {
+func fetchPage(redisdb zAddNXer, pageNum int) ([]FarmID, error) {
 	v := url.Values{}
 	v.Set("sort", "recent")
 	v.Set("p", strconv.Itoa(pageNum))
-	u := &url.URL{
-		Scheme:   "https",
-		Host:     "upload.farm",
-		Path:     "/all",
-		RawQuery: v.Encode(),
+	u, err := url.Parse(farmBaseURL)
+	if err != nil {
+		return nil, err
 	}
+	u.Path = "/all"
+	u.RawQuery = v.Encode()
 
-	var farmIDs []string
+	var farmIDs []FarmID
 
 	body, err := fetchURL(u.String())
 	if err != nil {
@@ -419,40 +595,66 @@ func fetchPage(redisdb zAddNXer, pageNum int) ([]string, error) {
 		log.Debugf("[%d] %s", pageNum, body)
 		return farmIDs, err
 	}
+	spiderPages.Inc()
 
 	var zids []redis.Z
 	for _, farmID := range farmIDs {
-
-		idScore, err := idToNum(farmID)
-		if err != nil {
-			fmt.Printf("cannot convert id? [%s] [%v]", farmID, err)
-			continue
-		}
-		zid := redis.Z{Score: float64(idScore), Member: farmID}
+		zid := redis.Z{Score: float64(farmID.num()), Member: string(farmID)}
 		zids = append(zids, zid)
 	}
 
-	if len(zids) == 0 {
-		fmt.Printf("no farms found!\n")
-		return farmIDs, err
-	}
-
 	result, err := redisdb.ZAddNX("spidered", zids...).Result()
 	if err != nil {
 		log.Warnf("could not add farmIDs to redis [spidered]: %v", err)
 	}
-	fmt.Printf("zadd: [%v] [%v]\n", result, err)
+	log.Debugf("[%d] zadd: [%v] [%v]", pageNum, result, err)
 	// TODO when result == 0, no new items were added. Stop spidering...
 
 	return farmIDs, nil
 }
 
+// crawlRecent reads the newest pages of the farm listing, queueing every farm
+// it finds, until it reaches a page of farms we have already stored.
+func crawlRecent(ctx context.Context, queue *farmQueue, redisdb zAddNXer, stop <-chan struct{}) error {
+	for pageNum := 0; pageNum < defaultCrawlPages; pageNum++ {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		farmIDs, err := fetchPage(redisdb, pageNum)
+		if err != nil {
+			return err
+		}
+
+		newFarms := 0
+		for _, farmID := range farmIDs {
+			allFarms.mu.Lock()
+			_, known := allFarms.stats[string(farmID)]
+			allFarms.mu.Unlock()
+			if known {
+				continue
+			}
+			newFarms++
+			if err := enqueue(ctx, queue, laneRecents, farmID); err != nil {
+				return err
+			}
+		}
+		if newFarms == 0 {
+			log.Debugf("crawl caught up at page %d", pageNum)
+			return nil
+		}
+	}
+	return nil
+}
+
 type zAddNXer interface {
 	ZAddNX(key string, members ...redis.Z) *redis.IntCmd
 	PoolStats() *redis.PoolStats
 }
 
-func fetchMany(queue chan string, redisdb zAddNXer) {
+func fetchMany(ctx context.Context, queue *farmQueue, redisdb zAddNXer) {
 	body, err := fetchURL("https://upload.farm/all?p=4695&sort=recent")
 	if err != nil {
 		return
@@ -463,41 +665,38 @@ func fetchMany(queue chan string, redisdb zAddNXer) {
 		log.Warnf("could not find farmIDs: %s\n", err)
 		return
 	}
+	spiderPages.Inc()
 
 	var zids []redis.Z
 
 	for _, farmID := range farmIDs {
-		log.Debugf("queueing farmID [%s] [%d]", farmID, len(queue))
-		queue <- farmID
-		log.Debugf("queued farmID [%s] [%d]", farmID, len(queue))
-		idScore, err := idToNum(farmID)
-		if err != nil {
-			fmt.Printf("cannot convert id? [%s] [%v]", farmID, err)
-			continue
+		if enqueue(ctx, queue, laneBackfill, farmID) != nil {
+			break
 		}
-		zid := redis.Z{Score: float64(idScore), Member: farmID}
+		zid := redis.Z{Score: float64(farmID.num()), Member: string(farmID)}
 		zids = append(zids, zid)
 	}
-	if len(zids) == 0 {
-		fmt.Printf("no farms found!\n")
-		return
-	}
 	result, err := redisdb.ZAddNX("spidered", zids...).Result()
-	fmt.Printf("zadd: [%v] [%v]\n", result, err)
-	// fmt.Printf("%#v", redisdb.PoolStats())
+	log.Debugf("zadd: [%v] [%v]", result, err)
+	// log.Debugf("%#v", redisdb.PoolStats())
 }
 
-func farmIDsFromSearch(body []byte) ([]string, error) {
+func farmIDsFromSearch(body []byte) ([]FarmID, error) {
 	re := regexp.MustCompile("/([A-Za-z0-9]{6})-f.png")
 	result := re.FindAllStringSubmatch(string(body), -1)
 	if result == nil {
 		log.Warnf("could not find any farms in %s", body)
+		parseFailures.WithLabelValues("listing").Inc()
 		return nil, fmt.Errorf("no farms found")
 	}
 
-	var farmIDs []string
+	var farmIDs []FarmID
 	for _, match := range result {
-		farmID := match[1]
+		farmID, err := ParseFarmID(match[1])
+		if err != nil {
+			log.Infof("unexpected farm in listing: %v", err)
+			continue
+		}
 		farmIDs = append(farmIDs, farmID)
 	}
 
@@ -508,20 +707,21 @@ func farmIDsFromSearch(body []byte) ([]string, error) {
 	return farmIDs, nil
 }
 
-func extractFarmIDs(body []byte) ([]string, error) {
+func extractFarmIDs(body []byte) ([]FarmID, error) {
 
 	var entries []string
 	err := json.Unmarshal(body, &entries)
 	if err != nil {
 		log.Infof("cannot parse json %s (%v)", body, err)
+		parseFailures.WithLabelValues("recents").Inc()
 		return nil, err
 	}
 
-	var farmIDs []string
+	var farmIDs []FarmID
 	for _, entry := range entries {
-		farmID, err := extractFarmID(entry)
+		farmID, err := farmIDFromRecent(entry)
 		if err != nil {
-			log.Infof("unexpected entry [%s]", entry)
+			log.Infof("unexpected entry: %v", err)
 			continue
 		}
 		log.Debugf("extract farmID: %s -> %s (%v)", entry, farmID, err)
@@ -531,54 +731,126 @@ func extractFarmIDs(body []byte) ([]string, error) {
 	return farmIDs, nil
 }
 
+// fetchURL fetches a page, going through the page cache when one is set up.
 func fetchURL(url string) ([]byte, error) {
+	if pages != nil {
+		return pages.fetch(url)
+	}
+
+	body, res, err := getURL(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusOK {
+		return body, nil
+	}
+	return nil, &httpStatusError{StatusCode: res.StatusCode, Status: res.Status}
+}
+
+// getURL does a GET with the given extra headers, returning the body and
+// response whatever the status code.
+func getURL(url string, header http.Header) ([]byte, *http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
 	startTime := time.Now()
-	res, err := httpClient.Get(url)
+	res, err := httpClient.Do(req)
 	dur := time.Since(startTime)
 	log.Infof("fetched %s in %v", url, dur)
 	if err != nil {
-		return nil, err
+		observeFetch(url, "error", dur)
+		return nil, nil, err
 	}
+	observeFetch(url, strconv.Itoa(res.StatusCode), dur)
 
 	body, err := ioutil.ReadAll(res.Body)
 	res.Body.Close()
 	if err != nil {
-		return nil, err
-	}
-
-	if res.StatusCode == http.StatusOK {
-		return body, nil
+		return nil, nil, err
 	}
-	return nil, fmt.Errorf(res.Status)
-
+	return body, res, nil
 }
 
-func processFarmID(farmID string, statsQueue chan svStats) {
-	log.Debugf("processing farmID %s", farmID)
+// processFarmID scrapes a queued farm and sends it to be stored, returning
+// why it could not. ctx carries the worker processing it, if any.
+func processFarmID(ctx context.Context, req farmRequest, statsQueue chan farmResult) error {
+	logger := req.logger()
+	logger.Debug("processing farm")
+
+	ctx = req.context(ctx)
+	if !req.Queued.IsZero() {
+		_, wait := startSpanAt(ctx, "dequeue", req.Queued)
+		wait.finish(nil)
+	}
+	ctx, s := startSpan(ctx, "process")
+	s.setAttr("farmID", string(req.FarmID))
+	s.setAttr("lane", req.Lane.String())
 
 	allFarms.mu.Lock()
-	_, ok := allFarms.stats[farmID]
+	_, ok := allFarms.stats[string(req.FarmID)]
 	allFarms.mu.Unlock()
-	if ok {
-		log.Debugf("skipping %s - already processed", farmID)
-		return
+	if ok && req.Lane != laneRefresh {
+		logger.Debug("skipping - already processed")
+		tracker.markDone(req.FarmID)
+		s.setAttr("skipped", "true")
+		s.finish(nil)
+		req.finish(nil)
+		return nil
+	}
+
+	startTime := time.Now()
+	stats, err := scrapeFarm(ctx, req.FarmID)
+	s.finish(err)
+	tracker.observe(time.Since(startTime))
+	if err != nil {
+		logger.WithError(err).Warn("could not scrape farm")
+		tracker.markFailed(req.FarmID, err)
+		req.finish(err)
+		return err
 	}
+	logger.WithField("duration", time.Since(startTime)).Debug("scraped farm")
+
+	statsQueue <- farmResult{Stats: stats, ReqID: req.ReqID, Trace: s.context()}
+	req.finish(nil)
+	return nil
+}
 
-	u, _ := url.Parse("https://upload.farm")
-	u.Path = path.Join(u.Path, farmID)
+// scrapeFarm fetches a farm page and reads the villager friendship levels
+// from it.
+func scrapeFarm(ctx context.Context, farmID FarmID) (svStats, error) {
+	u, _ := url.Parse(farmBaseURL)
+	u.Path = path.Join(u.Path, string(farmID))
 
+	setWorkerState(ctx, workerFetching, farmID)
+	_, fetch := startSpan(ctx, "fetch")
+	fetch.setAttr("url", u.String())
 	body, err := fetchURL(u.String())
+	fetch.finish(err)
 	if err != nil {
-		return
+		return svStats{}, err
 	}
 
+	setWorkerState(ctx, workerParsing, farmID)
+	_, parse := startSpan(ctx, "parse")
+	defer func() { parse.finish(err) }()
 	re := regexp.MustCompile("><br>([A-Z][a-z]+): ([0-9]+)/10'>")
 	result := re.FindAllStringSubmatch(string(body), -1)
 	if result == nil {
-		return
+		parseFailures.WithLabelValues("farm").Inc()
+		err = &parseError{FarmID: farmID, Reason: "no villagers found"}
+		return svStats{}, err
 	}
 
-	stats := svStats{FarmID: farmID}
+	stats := svStats{
+		FarmID:   string(farmID),
+		Fetched:  time.Now(),
+		PageHash: fmt.Sprintf("%x", sha256.Sum256(body)),
+	}
 	v := reflect.ValueOf(&stats).Elem()
 
 	for _, match := range result {
@@ -599,18 +871,7 @@ func processFarmID(farmID string, statsQueue chan svStats) {
 		f.SetUint(i)
 	}
 
-	statsQueue <- stats
-}
-
-func extractFarmID(miniRecent string) (string, error) {
-	if len(miniRecent) < 6 {
-		return "", fmt.Errorf("invalid farmID, must be at least 6 chars long")
-	}
-	if miniRecent[0] != '1' {
-		return "", fmt.Errorf("invalid FarmID; should start with 1")
-	}
-	id := miniRecent[:6]
-	return id, nil
+	return stats, nil
 }
 
 func idToNum(id string) (int64, error) {
@@ -32,18 +32,18 @@ func TestExtraction(t *testing.T) {
 		})
 	})
 	Convey("Given a valid mini_recents entry", t, func() {
-		farmID, err := extractFarmID("1BC123.otherstuff")
+		farmID, err := farmIDFromRecent("1BC123.otherstuff")
 		So(err, ShouldBeNil)
-		So(farmID, ShouldEqual, "1BC123")
+		So(farmID, ShouldEqual, FarmID("1BC123"))
 	})
 
 	Convey("Given an empty mini_recents entry", t, func() {
-		_, err := extractFarmID("")
+		_, err := farmIDFromRecent("")
 		So(err, ShouldNotBeNil)
 	})
 
 	Convey("Given a short mini_recents entry", t, func() {
-		_, err := extractFarmID("short")
+		_, err := farmIDFromRecent("short")
 		So(err, ShouldNotBeNil)
 	})
 }
@@ -120,7 +120,7 @@ func TestNumToID(t *testing.T) {
 
 func TestTelnet(t *testing.T) {
 	Convey("When the telnet server is run", t, func() {
-		queue := make(chan string, 100)
+		queue := newFarmQueue(100)
 		nilRedis := redis.NewClient(&redis.Options{
 			Addr:     ":6379",
 			PoolSize: 0,
@@ -157,18 +157,18 @@ func TestTelnet(t *testing.T) {
 
 	})
 	Convey("Given a valid mini_recents entry", t, func() {
-		farmID, err := extractFarmID("1BC123.otherstuff")
+		farmID, err := farmIDFromRecent("1BC123.otherstuff")
 		So(err, ShouldBeNil)
-		So(farmID, ShouldEqual, "1BC123")
+		So(farmID, ShouldEqual, FarmID("1BC123"))
 	})
 
 	Convey("Given an empty mini_recents entry", t, func() {
-		_, err := extractFarmID("")
+		_, err := farmIDFromRecent("")
 		So(err, ShouldNotBeNil)
 	})
 
 	Convey("Given a short mini_recents entry", t, func() {
-		_, err := extractFarmID("short")
+		_, err := farmIDFromRecent("short")
 		So(err, ShouldNotBeNil)
 	})
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+var (
+	fetchesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "farmstats_fetches_total",
+		Help: "Pages requested from upload.farm, by URL class and HTTP status.",
+	}, []string{"class", "status"})
+	fetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "farmstats_fetch_duration_seconds",
+		Help:    "Time taken to fetch pages from upload.farm.",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"class"})
+	parseFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "farmstats_parse_failures_total",
+		Help: "Fetched pages we could not find farm data in, by URL class.",
+	}, []string{"class"})
+	spiderPages = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "farmstats_spider_pages_total",
+		Help: "Listing pages read by spiders and crawls.",
+	})
+	grpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "farmstats_grpc_requests_total",
+		Help: "gRPC requests handled, by method and status code.",
+	}, []string{"method", "code"})
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "farmstats_grpc_request_duration_seconds",
+		Help: "Time taken to handle gRPC requests.",
+	}, []string{"method"})
+	duplicateImages = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "farmstats_duplicate_images_total",
+		Help: "Farm renders downloaded that look like another farm's.",
+	})
+	telnetConnections = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "farmstats_telnet_connections_total",
+		Help: "Telnet connections accepted.",
+	})
+	telnetClients = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "farmstats_telnet_clients",
+		Help: "Telnet connections currently open.",
+	})
+)
+
+func init() {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "farmstats_farms_stored",
+		Help: "Farms with stats in the store.",
+	}, func() float64 {
+		allFarms.mu.Lock()
+		defer allFarms.mu.Unlock()
+		return float64(len(allFarms.stats))
+	})
+}
+
+// registerQueueMetrics exports the depth of the processing queues and of
+// each lane of the farm queue.
+func registerQueueMetrics(queue *farmQueue, statsQueue chan farmResult) {
+	for name, depth := range map[string]func() int{
+		"farms": queue.len,
+		"stats": func() int { return len(statsQueue) },
+	} {
+		depth := depth
+		promauto.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "farmstats_queue_depth",
+			Help:        "Items waiting in a processing queue.",
+			ConstLabels: prometheus.Labels{"queue": name},
+		}, func() float64 { return float64(depth()) })
+	}
+	for l := lane(0); l < numLanes; l++ {
+		ch := queue.lane(l)
+		promauto.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "farmstats_lane_depth",
+			Help:        "Farms waiting in each lane of the farm queue.",
+			ConstLabels: prometheus.Labels{"lane": l.String()},
+		}, func() float64 { return float64(len(ch)) })
+	}
+}
+
+// observeFetch records one request to upload.farm; status is the HTTP
+// status code, or "error" if the request failed.
+func observeFetch(url string, status string, dur time.Duration) {
+	class := urlClass(url)
+	fetchesTotal.WithLabelValues(class, status).Inc()
+	fetchDuration.WithLabelValues(class).Observe(dur.Seconds())
+}
+
+// grpcMetrics counts and times every unary gRPC call.
+func grpcMetrics(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	startTime := time.Now()
+	res, err := handler(ctx, req)
+	grpcRequests.WithLabelValues(info.FullMethod, grpcstatus.Code(err).String()).Inc()
+	grpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(startTime).Seconds())
+	return res, err
+}
+
+// grpcStreamMetrics is grpcMetrics for streaming calls, counted when the
+// stream ends.
+func grpcStreamMetrics(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	startTime := time.Now()
+	err := handler(srv, ss)
+	grpcRequests.WithLabelValues(info.FullMethod, grpcstatus.Code(err).String()).Inc()
+	grpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(startTime).Seconds())
+	return err
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetrics(t *testing.T) {
+	Convey("Fetches are counted by URL class and status", t, func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/1GONE1" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte("no villagers here"))
+		}))
+		defer srv.Close()
+		farmBaseURL = srv.URL
+		defer func() { farmBaseURL = "https://upload.farm" }()
+
+		notFound := testutil.ToFloat64(fetchesTotal.WithLabelValues("farm", "404"))
+		ok := testutil.ToFloat64(fetchesTotal.WithLabelValues("farm", "200"))
+		failed := testutil.ToFloat64(parseFailures.WithLabelValues("farm"))
+
+		_, err := scrapeFarm(context.Background(), "1GONE1")
+		So(err, ShouldNotBeNil)
+		_, err = scrapeFarm(context.Background(), "1EMPTY")
+		So(err, ShouldNotBeNil)
+
+		So(testutil.ToFloat64(fetchesTotal.WithLabelValues("farm", "404")), ShouldEqual, notFound+1)
+		So(testutil.ToFloat64(fetchesTotal.WithLabelValues("farm", "200")), ShouldEqual, ok+1)
+		So(testutil.ToFloat64(parseFailures.WithLabelValues("farm")), ShouldEqual, failed+1)
+	})
+
+	Convey("gRPC calls are counted by method and code", t, func() {
+		allFarms.stats = map[string]svStats{}
+		info := &grpc.UnaryServerInfo{FullMethod: "/farmstats.FarmStats/GetStats"}
+		before := testutil.ToFloat64(grpcRequests.WithLabelValues(info.FullMethod, "NotFound"))
+		_, err := grpcMetrics(context.Background(), &pb.FarmID{Id: "1ZZZZZ"}, info,
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return allFarms.GetStats(ctx, req.(*pb.FarmID))
+			})
+		So(err, ShouldNotBeNil)
+		So(testutil.ToFloat64(grpcRequests.WithLabelValues(info.FullMethod, "NotFound")), ShouldEqual, before+1)
+
+		invalid := testutil.ToFloat64(grpcRequests.WithLabelValues(info.FullMethod, "InvalidArgument"))
+		_, err = grpcMetrics(context.Background(), &pb.FarmID{Id: "1bc-12"}, info,
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return allFarms.GetStats(ctx, req.(*pb.FarmID))
+			})
+		So(err, ShouldNotBeNil)
+		So(testutil.ToFloat64(grpcRequests.WithLabelValues(info.FullMethod, "InvalidArgument")), ShouldEqual, invalid+1)
+	})
+
+	Convey("The /metrics endpoint exports queue depth and stored farms", t, func() {
+		registerQueueMetrics(newFarmQueue(3), make(chan farmResult, 3))
+		w := httptest.NewRecorder()
+		promhttp.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		body := w.Body.String()
+		So(body, ShouldContainSubstring, `farmstats_queue_depth{queue="farms"} 0`)
+		So(body, ShouldContainSubstring, `farmstats_lane_depth{lane="interactive"} 0`)
+		So(body, ShouldContainSubstring, "farmstats_farms_stored")
+		So(strings.Contains(body, "farmstats_fetch_duration_seconds_bucket"), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// onDemandTimeout bounds how long an on-demand fetch waits for its farm,
+// whether or not anyone is still waiting for the answer.
+const onDemandTimeout = time.Minute
+
+// fetchCall is one on-demand fetch, shared by every request for the farm
+// that arrives while it runs.
+type fetchCall struct {
+	done  chan struct{}
+	stats svStats
+	err   error
+}
+
+// fetcher scrapes farms that clients ask for but we have not stored. It
+// sends them to the interactive lane, which has the largest share of the
+// workers while other lanes have farms waiting, and coalesces concurrent
+// requests for a farm into one fetch.
+type fetcher struct {
+	farms *farmStats
+	queue *farmQueue
+
+	mu    sync.Mutex
+	calls map[FarmID]*fetchCall
+}
+
+func newFetcher(farms *farmStats, queue *farmQueue) *fetcher {
+	return &fetcher{farms: farms, queue: queue, calls: make(map[FarmID]*fetchCall)}
+}
+
+// fetch returns a farm, scraping it first if need be. It gives up when ctx
+// is done, leaving the scrape to finish for later requests. A failed scrape
+// is returned as a farmFailure.
+func (f *fetcher) fetch(ctx context.Context, farmID FarmID) (svStats, error) {
+	f.mu.Lock()
+	call, ok := f.calls[farmID]
+	if !ok {
+		call = &fetchCall{done: make(chan struct{})}
+		f.calls[farmID] = call
+		go f.run(withReqID(context.Background(), reqIDFromContext(ctx)), farmID, call)
+	}
+	f.mu.Unlock()
+	if ok {
+		log.WithFields(log.Fields{"reqID": reqIDFromContext(ctx), "farmID": farmID}).Debug("joining on-demand fetch")
+	}
+
+	select {
+	case <-call.done:
+		return call.stats, call.err
+	case <-ctx.Done():
+		return svStats{}, ctx.Err()
+	}
+}
+
+func (f *fetcher) run(ctx context.Context, farmID FarmID, call *fetchCall) {
+	call.stats, call.err = f.scrape(ctx, farmID)
+	f.mu.Lock()
+	delete(f.calls, farmID)
+	f.mu.Unlock()
+	close(call.done)
+}
+
+// scrape queues the farm and waits for it to be stored or to fail.
+func (f *fetcher) scrape(ctx context.Context, farmID FarmID) (svStats, error) {
+	// subscribe before looking, so a farm stored in between is not missed
+	stored := farmEvents.subscribe(func(event interface{}) bool {
+		return event.(svStats).FarmID == string(farmID)
+	})
+	defer farmEvents.unsubscribe(stored)
+	failed := farmFailures.subscribe(func(event interface{}) bool {
+		return event.(farmFailure).FarmID == farmID
+	})
+	defer farmFailures.unsubscribe(failed)
+
+	f.farms.mu.Lock()
+	stats, ok := f.farms.stats[string(farmID)]
+	f.farms.mu.Unlock()
+	if ok {
+		return stats, nil
+	}
+
+	timeout := time.NewTimer(onDemandTimeout)
+	defer timeout.Stop()
+	req, s := newFarmRequest(ctx, farmID)
+	tracker.markQueued(farmID, laneInteractive)
+	select {
+	case f.queue.lane(laneInteractive) <- req:
+		s.finish(nil)
+		req.logger().Debug("queued farm on demand")
+	case <-timeout.C:
+		tracker.unqueue(farmID)
+		s.finish(context.DeadlineExceeded)
+		return svStats{}, context.DeadlineExceeded
+	}
+
+	select {
+	case event := <-stored.events:
+		return event.(svStats), nil
+	case event := <-failed.events:
+		return svStats{}, event.(farmFailure)
+	case <-timeout.C:
+		return svStats{}, context.DeadlineExceeded
+	}
+}
+
+// farmHandler serves GET /api/v1/farms/{farmID}. With on-demand fetching,
+// an unknown farm is scraped while the request waits.
+func farmHandler(w http.ResponseWriter, r *http.Request) {
+	farmID, err := ParseFarmID(chi.URLParam(r, "farmID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	allFarms.mu.Lock()
+	stats, ok := allFarms.stats[string(farmID)]
+	if allFarms.lookups != nil {
+		allFarms.lookups[string(farmID)]++
+	}
+	allFarms.mu.Unlock()
+	if ok {
+		render.JSON(w, r, stats)
+		return
+	}
+	if !allFarms.onDemand || allFarms.fetcher == nil {
+		http.Error(w, fmt.Sprintf("farm %s not found", farmID), http.StatusNotFound)
+		return
+	}
+
+	stats, err = allFarms.fetcher.fetch(r.Context(), farmID)
+	if err == nil {
+		render.JSON(w, r, stats)
+		return
+	}
+	if failure, ok := err.(farmFailure); ok {
+		if statusErr, ok := failure.Err.(*httpStatusError); ok && statusErr.StatusCode == http.StatusNotFound {
+			http.Error(w, fmt.Sprintf("farm %s is not on upload.farm", farmID), http.StatusNotFound)
+			return
+		}
+		if parseErr, ok := failure.Err.(*parseError); ok {
+			http.Error(w, fmt.Sprintf("farm %s failed to parse: %s", farmID, parseErr.Reason), http.StatusBadGateway)
+			return
+		}
+	}
+	retry := tracker.retryAfter(allFarms.fetcher.queue.ahead(laneInteractive))
+	w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())))
+	http.Error(w, fmt.Sprintf("farm %s is not fetched yet: %v", farmID, err), http.StatusServiceUnavailable)
+}
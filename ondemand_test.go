@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/adamlounds/stardew-farm-stats/farmstats"
+	"github.com/go-chi/chi"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeWorker stores every farm sent to queue as if it had been scraped,
+// failing those in fail, and counts what it was sent.
+func fakeWorker(farms *farmStats, queue *farmQueue, stop chan struct{}, fail map[FarmID]error) *int {
+	var mu sync.Mutex
+	count := new(int)
+	go func() {
+		for {
+			req, ok := queue.pop(stop)
+			if !ok {
+				return
+			}
+			mu.Lock()
+			*count++
+			mu.Unlock()
+			// give coalesced requests time to pile up
+			time.Sleep(10 * time.Millisecond)
+			if err := fail[req.FarmID]; err != nil {
+				tracker.markFailed(req.FarmID, err)
+				continue
+			}
+			stats := svStats{FarmID: string(req.FarmID), Sam: 7}
+			farms.store(stats)
+			tracker.markDone(req.FarmID)
+			farmEvents.publish(stats)
+		}
+	}()
+	return count
+}
+
+func TestOnDemand(t *testing.T) {
+	Convey("Given a fetcher with a worker", t, func() {
+		queue := newFarmQueue(10)
+		stop := make(chan struct{})
+		defer close(stop)
+		farms := &farmStats{stats: map[string]svStats{}, queue: queue, onDemand: true}
+		farms.fetcher = newFetcher(farms, queue)
+		fetched := fakeWorker(farms, queue, stop, map[FarmID]error{
+			"1GONE1": &httpStatusError{StatusCode: 404, Status: "404 Not Found"},
+			"1EMPTY": &parseError{FarmID: "1EMPTY", Reason: "no villagers found"},
+		})
+
+		Convey("concurrent requests for a farm share one fetch", func() {
+			var wg sync.WaitGroup
+			results := make([]svStats, 5)
+			for i := range results {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					results[i], _ = farms.fetcher.fetch(context.Background(), "1BBBBB")
+				}(i)
+			}
+			wg.Wait()
+			So(*fetched, ShouldEqual, 1)
+			for _, stats := range results {
+				So(stats.Sam, ShouldEqual, 7)
+			}
+		})
+
+		Convey("a stored farm is not fetched again", func() {
+			farms.store(svStats{FarmID: "1BBBBB", Sam: 2})
+			stats, err := farms.fetcher.fetch(context.Background(), "1BBBBB")
+			So(err, ShouldBeNil)
+			So(stats.Sam, ShouldEqual, 2)
+			So(*fetched, ShouldEqual, 0)
+		})
+
+		Convey("a failed fetch is returned as a farmFailure", func() {
+			_, err := farms.fetcher.fetch(context.Background(), "1EMPTY")
+			failure, ok := err.(farmFailure)
+			So(ok, ShouldBeTrue)
+			So(failure.Err, ShouldHaveSameTypeAs, &parseError{})
+		})
+
+		Convey("a caller stops waiting at its deadline", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+			defer cancel()
+			_, err := farms.fetcher.fetch(ctx, "1BBBBB")
+			So(err == context.DeadlineExceeded, ShouldBeTrue)
+		})
+
+		Convey("in on-demand mode GetStats fetches unknown farms", func() {
+			farm, err := farms.GetStats(context.Background(), &pb.FarmID{Id: "1BBBBB"})
+			So(err, ShouldBeNil)
+			So(farm.Sam, ShouldEqual, 7)
+
+			_, err = farms.GetStats(context.Background(), &pb.FarmID{Id: "1GONE1"})
+			So(grpcstatus.Code(err), ShouldEqual, codes.NotFound)
+		})
+
+		Convey("the HTTP farm endpoint fetches unknown farms", func() {
+			allFarms.stats = map[string]svStats{}
+			allFarms.onDemand = true
+			allFarms.fetcher = newFetcher(&allFarms, queue)
+			defer func() { allFarms.onDemand, allFarms.fetcher = false, nil }()
+			r := chi.NewRouter()
+			r.Get("/api/v1/farms/{farmID}", farmHandler)
+			get := func(path string) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+				return w
+			}
+
+			w := get("/api/v1/farms/1BBBBB")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldContainSubstring, `"FarmID":"1BBBBB"`)
+			So(get("/api/v1/farms/1GONE1").Code, ShouldEqual, http.StatusNotFound)
+			So(get("/api/v1/farms/1EMPTY").Code, ShouldEqual, http.StatusBadGateway)
+			So(get("/api/v1/farms/1bc-12").Code, ShouldEqual, http.StatusBadRequest)
+
+			allFarms.onDemand = false
+			So(get("/api/v1/farms/1CCCCC").Code, ShouldEqual, http.StatusNotFound)
+			So(*fetched, ShouldEqual, 3)
+		})
+	})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var farmPagePath = regexp.MustCompile("^/[A-Za-z0-9]{6}$")
+var farmImagePath = regexp.MustCompile("^/all/[A-Za-z0-9]{6}-f\\.png$")
+
+// urlClass groups upload.farm URLs that change at similar rates.
+func urlClass(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "other"
+	}
+	switch {
+	case u.Path == "/_mini_recents":
+		return "recents"
+	case u.Path == "/all":
+		return "listing"
+	case farmPagePath.MatchString(u.Path):
+		return "farm"
+	case farmImagePath.MatchString(u.Path):
+		return "image"
+	}
+	return "other"
+}
+
+type cachedPage struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag"`
+	LastModified string    `json:"lastModified"`
+	Fetched      time.Time `json:"fetched"`
+	Body         []byte    `json:"body"`
+}
+
+// pageCache keeps fetched pages on disk. Pages younger than their class's
+// TTL are served without a request; older ones are revalidated with
+// If-None-Match / If-Modified-Since so unchanged pages cost a 304.
+type pageCache struct {
+	dir  string
+	ttls map[string]time.Duration
+
+	mu          sync.Mutex
+	hits        int
+	revalidated int
+	misses      int
+}
+
+// newPageCache returns nil if the cache is disabled.
+func newPageCache(cfg cacheConfig) (*pageCache, error) {
+	if cfg.Disabled || cfg.Dir == "" {
+		return nil, nil
+	}
+	err := os.MkdirAll(cfg.Dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &pageCache{dir: cfg.Dir, ttls: make(map[string]time.Duration)}
+	for class, ttl := range cfg.TTLs {
+		c.ttls[class], err = time.ParseDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ttl [%s] for %s pages", ttl, class)
+		}
+	}
+	return c, nil
+}
+
+func (c *pageCache) path(rawURL string) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%x.json", sha256.Sum256([]byte(rawURL))))
+}
+
+func (c *pageCache) load(rawURL string) (cachedPage, bool) {
+	var page cachedPage
+	body, err := ioutil.ReadFile(c.path(rawURL))
+	if err != nil {
+		return page, false
+	}
+	err = json.Unmarshal(body, &page)
+	if err != nil || page.URL != rawURL {
+		return page, false
+	}
+	return page, true
+}
+
+func (c *pageCache) save(page cachedPage) {
+	body, err := json.Marshal(page)
+	if err != nil {
+		return
+	}
+	err = replaceFile(c.path(page.URL), body)
+	if err != nil {
+		log.Warnf("could not cache %s: %v", page.URL, err)
+	}
+}
+
+// replaceFile writes a file through a temporary file of its own in the same
+// directory, then renames it into place, so readers never see half a file
+// and concurrent writers never rename each other's.
+func replaceFile(path string, body []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func (c *pageCache) fetch(rawURL string) ([]byte, error) {
+	page, cached := c.load(rawURL)
+	if cached && time.Since(page.Fetched) < c.ttls[urlClass(rawURL)] {
+		c.count(&c.hits)
+		fetchesTotal.WithLabelValues(urlClass(rawURL), "cached").Inc()
+		return page.Body, nil
+	}
+
+	header := http.Header{}
+	if cached && page.ETag != "" {
+		header.Set("If-None-Match", page.ETag)
+	}
+	if cached && page.LastModified != "" {
+		header.Set("If-Modified-Since", page.LastModified)
+	}
+
+	body, res, err := getURL(rawURL, header)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && cached:
+		c.count(&c.revalidated)
+		page.Fetched = time.Now()
+		c.save(page)
+		return page.Body, nil
+	case res.StatusCode == http.StatusOK:
+		c.count(&c.misses)
+		c.save(cachedPage{
+			URL:          rawURL,
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+			Fetched:      time.Now(),
+			Body:         body,
+		})
+		return body, nil
+	}
+	return nil, &httpStatusError{StatusCode: res.StatusCode, Status: res.Status}
+}
+
+func (c *pageCache) count(counter *int) {
+	c.mu.Lock()
+	*counter++
+	c.mu.Unlock()
+}
+
+func (c *pageCache) String() string {
+	if c == nil {
+		return "page cache disabled"
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	total := c.hits + c.revalidated + c.misses
+	ratio := 0.0
+	if total > 0 {
+		ratio = float64(c.hits+c.revalidated) / float64(total)
+	}
+	return fmt.Sprintf("page cache: %d hits, %d not modified, %d misses (%.1f%% served from cache)",
+		c.hits, c.revalidated, c.misses, 100*ratio)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPageCache(t *testing.T) {
+	Convey("URLs are grouped into classes", t, func() {
+		So(urlClass("https://upload.farm/_mini_recents"), ShouldEqual, "recents")
+		So(urlClass("https://upload.farm/all?p=3&sort=recent"), ShouldEqual, "listing")
+		So(urlClass("https://upload.farm/1FkeUV"), ShouldEqual, "farm")
+		So(urlClass("https://upload.farm/all/1FkeUV-f.png"), ShouldEqual, "image")
+		So(urlClass("https://upload.farm/about"), ShouldEqual, "other")
+	})
+
+	Convey("Given a page cache in front of a server that supports ETags", t, func() {
+		requests := 0
+		var conditional []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			conditional = append(conditional, r.Header.Get("If-None-Match"))
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte("farm page"))
+		}))
+		defer srv.Close()
+
+		dir, _ := ioutil.TempDir("", "pagecache")
+		defer os.RemoveAll(dir)
+		c, err := newPageCache(cacheConfig{Dir: dir, TTLs: map[string]string{"farm": "1h", "other": "0s"}})
+		So(err, ShouldBeNil)
+
+		Convey("fresh pages are served without a request", func() {
+			body, err := c.fetch(srv.URL + "/1AAAAA")
+			So(err, ShouldBeNil)
+			So(string(body), ShouldEqual, "farm page")
+			body, err = c.fetch(srv.URL + "/1AAAAA")
+			So(err, ShouldBeNil)
+			So(string(body), ShouldEqual, "farm page")
+			So(requests, ShouldEqual, 1)
+			So(c.hits, ShouldEqual, 1)
+			So(c.misses, ShouldEqual, 1)
+		})
+
+		Convey("stale pages are revalidated with If-None-Match", func() {
+			_, err := c.fetch(srv.URL + "/about")
+			So(err, ShouldBeNil)
+			body, err := c.fetch(srv.URL + "/about")
+			So(err, ShouldBeNil)
+			So(string(body), ShouldEqual, "farm page")
+			So(conditional, ShouldResemble, []string{"", `"v1"`})
+			So(c.revalidated, ShouldEqual, 1)
+			So(c.String(), ShouldContainSubstring, "50.0% served from cache")
+		})
+
+		Convey("the cache survives a restart", func() {
+			_, err := c.fetch(srv.URL + "/1AAAAA")
+			So(err, ShouldBeNil)
+			c2, _ := newPageCache(cacheConfig{Dir: dir, TTLs: map[string]string{"farm": "1h"}})
+			_, err = c2.fetch(srv.URL + "/1AAAAA")
+			So(err, ShouldBeNil)
+			So(requests, ShouldEqual, 1)
+		})
+	})
+
+	Convey("Concurrent writers of a file never leave half of one in place", t, func() {
+		dir, err := ioutil.TempDir("", "replace")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "page.json")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				replaceFile(path, []byte(strings.Repeat(string('a'+rune(i)), 64*1024)))
+			}(i)
+		}
+		wg.Wait()
+		body, err := ioutil.ReadFile(path)
+		So(err, ShouldBeNil)
+		So(len(body), ShouldEqual, 64*1024)
+		So(strings.Count(string(body), string(body[0])), ShouldEqual, len(body))
+		files, _ := ioutil.ReadDir(dir)
+		So(len(files), ShouldEqual, 1)
+	})
+
+	Convey("A disabled cache is nil", t, func() {
+		c, err := newPageCache(cacheConfig{Disabled: true, Dir: "cache"})
+		So(err, ShouldBeNil)
+		So(c, ShouldBeNil)
+		So(c.String(), ShouldEqual, "page cache disabled")
+	})
+}
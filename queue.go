@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// lane is a priority level in the farm queue.
+type lane int
+
+const (
+	// laneInteractive is farms people asked for, over telnet, gRPC or HTTP.
+	laneInteractive lane = iota
+	// laneRecents is farms from the recent farms list and listing crawls.
+	laneRecents
+	// laneBackfill is bulk work: spiders, imports and the backfill.
+	laneBackfill
+	// laneRefresh is stored farms being fetched again.
+	laneRefresh
+	numLanes
+)
+
+var laneNames = [numLanes]string{"interactive", "recents", "backfill", "refresh"}
+
+// laneWeights is each lane's share of the workers while several lanes have
+// farms waiting.
+var laneWeights = [numLanes]int{8, 4, 2, 1}
+
+func (l lane) String() string {
+	if l < 0 || l >= numLanes {
+		return fmt.Sprintf("lane(%d)", int(l))
+	}
+	return laneNames[l]
+}
+
+// farmQueue holds the farms waiting to be fetched, one channel per lane.
+// Workers share out their time between the lanes with farms waiting by
+// weighted round robin, so bulk work never starves the interactive lane and
+// the interactive lane cannot starve the rest.
+type farmQueue struct {
+	lanes [numLanes]chan farmRequest
+
+	mu      sync.Mutex
+	current [numLanes]int
+}
+
+func newFarmQueue(size int) *farmQueue {
+	q := &farmQueue{}
+	for l := range q.lanes {
+		q.lanes[l] = make(chan farmRequest, size)
+	}
+	return q
+}
+
+// lane returns the channel that queues farms in a lane, for callers that
+// need to select on sending.
+func (q *farmQueue) lane(l lane) chan farmRequest {
+	return q.lanes[l]
+}
+
+// len is the number of farms waiting in every lane.
+func (q *farmQueue) len() int {
+	n := 0
+	for _, ch := range q.lanes {
+		n += len(ch)
+	}
+	return n
+}
+
+// cap is the number of farms every lane can hold.
+func (q *farmQueue) cap() int {
+	n := 0
+	for _, ch := range q.lanes {
+		n += cap(ch)
+	}
+	return n
+}
+
+// ahead estimates how many farms will be taken before one added to lane l
+// now: those already in the lane, and the other lanes' share of the workers
+// meanwhile.
+func (q *farmQueue) ahead(l lane) int {
+	mine := len(q.lanes[l])
+	n := mine
+	for other, ch := range q.lanes {
+		if lane(other) == l {
+			continue
+		}
+		share := (mine + 1) * laneWeights[other] / laneWeights[l]
+		if share > len(ch) {
+			share = len(ch)
+		}
+		n += share
+	}
+	return n
+}
+
+func (q *farmQueue) String() string {
+	depths := make([]string, numLanes)
+	for l, ch := range q.lanes {
+		depths[l] = fmt.Sprintf("%s %d", lane(l), len(ch))
+	}
+	return strings.Join(depths, ", ")
+}
+
+// next picks the lane to take from with smooth weighted round robin over
+// the lanes that have farms waiting, or returns false if none do.
+func (q *farmQueue) next() (lane, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	best, total := lane(-1), 0
+	for l, ch := range q.lanes {
+		if len(ch) == 0 {
+			continue
+		}
+		q.current[l] += laneWeights[l]
+		total += laneWeights[l]
+		if best < 0 || q.current[l] > q.current[best] {
+			best = lane(l)
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	q.current[best] -= total
+	return best, true
+}
+
+// pop takes the next farm to fetch, waiting for one if every lane is
+// empty. It returns false once stop is closed.
+func (q *farmQueue) pop(stop <-chan struct{}) (farmRequest, bool) {
+	select {
+	case <-stop:
+		return farmRequest{}, false
+	default:
+	}
+	for {
+		l, ok := q.next()
+		if !ok {
+			break
+		}
+		select {
+		case req := <-q.lanes[l]:
+			req.Lane = l
+			return req, true
+		default:
+			// another worker took it first
+		}
+	}
+
+	var req farmRequest
+	var l lane
+	select {
+	case <-stop:
+		return farmRequest{}, false
+	case req = <-q.lanes[laneInteractive]:
+		l = laneInteractive
+	case req = <-q.lanes[laneRecents]:
+		l = laneRecents
+	case req = <-q.lanes[laneBackfill]:
+		l = laneBackfill
+	case req = <-q.lanes[laneRefresh]:
+		l = laneRefresh
+	}
+	req.Lane = l
+	return req, true
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFarmQueue(t *testing.T) {
+	Convey("Given a queue with farms waiting in every lane", t, func() {
+		q := newFarmQueue(100)
+		for l := lane(0); l < numLanes; l++ {
+			for i := 0; i < 30; i++ {
+				q.lane(l) <- farmRequest{FarmID: "1AAAAA"}
+			}
+		}
+		So(q.len(), ShouldEqual, 120)
+		So(q.cap(), ShouldEqual, 400)
+
+		Convey("workers take from each lane in proportion to its weight", func() {
+			var taken [numLanes]int
+			for i := 0; i < 15; i++ {
+				req, ok := q.pop(nil)
+				So(ok, ShouldBeTrue)
+				taken[req.Lane]++
+			}
+			So(taken, ShouldResemble, [numLanes]int{8, 4, 2, 1})
+		})
+
+		Convey("an empty lane's share goes to the others", func() {
+			for len(q.lane(laneInteractive)) > 0 {
+				<-q.lane(laneInteractive)
+			}
+			var taken [numLanes]int
+			for i := 0; i < 7; i++ {
+				req, _ := q.pop(nil)
+				taken[req.Lane]++
+			}
+			So(taken, ShouldResemble, [numLanes]int{0, 4, 2, 1})
+		})
+
+		Convey("depths are shown by lane", func() {
+			So(q.String(), ShouldEqual, "interactive 30, recents 30, backfill 30, refresh 30")
+		})
+
+		Convey("a farm's wait counts the other lanes' share", func() {
+			So(q.ahead(laneInteractive), ShouldEqual, 30+15+7+3)
+			So(q.ahead(laneRefresh), ShouldEqual, 120)
+		})
+	})
+
+	Convey("Given an empty queue", t, func() {
+		q := newFarmQueue(1)
+
+		Convey("pop waits for a farm", func() {
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				q.lane(laneBackfill) <- farmRequest{FarmID: "1BBBBB"}
+			}()
+			req, ok := q.pop(nil)
+			So(ok, ShouldBeTrue)
+			So(req.FarmID, ShouldEqual, FarmID("1BBBBB"))
+			So(req.Lane, ShouldEqual, laneBackfill)
+		})
+
+		Convey("pop gives up when stopped", func() {
+			stop := make(chan struct{})
+			close(stop)
+			_, ok := q.pop(stop)
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
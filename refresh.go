@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultRefreshMinAge    = 6 * time.Hour
+	defaultRefreshMaxAge    = 7 * 24 * time.Hour
+	defaultRefreshInterval  = 10 * time.Second
+	defaultRefreshCheck     = time.Minute
+	defaultRefreshBatchSize = 50
+)
+
+// refresher re-fetches stored farms as they age, so we notice when players
+// re-upload a farm with new friendship levels. Farms that are looked up more
+// often are re-fetched sooner, but never more often than minAge. They are
+// fetched through the refresh lane of the queue.
+type refresher struct {
+	queue     *farmQueue
+	minAge    time.Duration
+	maxAge    time.Duration
+	interval  time.Duration
+	batchSize int
+
+	mu        sync.Mutex
+	refreshed int
+	failed    int
+	lastRun   time.Time
+}
+
+func newRefresher(queue *farmQueue) *refresher {
+	return &refresher{
+		queue:     queue,
+		minAge:    defaultRefreshMinAge,
+		maxAge:    defaultRefreshMaxAge,
+		interval:  defaultRefreshInterval,
+		batchSize: defaultRefreshBatchSize,
+	}
+}
+
+// refreshAge is how old a farm may get before it is fetched again.
+func (r *refresher) refreshAge(lookups int) time.Duration {
+	age := r.maxAge / time.Duration(1+lookups)
+	if age < r.minAge {
+		return r.minAge
+	}
+	return age
+}
+
+// due lists the farms that need fetching again, most overdue first.
+func (r *refresher) due(now time.Time) []string {
+	type candidate struct {
+		farmID  string
+		overdue float64
+	}
+	var candidates []candidate
+
+	allFarms.mu.Lock()
+	for farmID, stats := range allFarms.stats {
+		maxAge := r.refreshAge(allFarms.lookups[farmID])
+		age := now.Sub(stats.Fetched)
+		if age < maxAge {
+			continue
+		}
+		candidates = append(candidates, candidate{farmID, float64(age) / float64(maxAge)})
+	}
+	allFarms.mu.Unlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].overdue == candidates[j].overdue {
+			return candidates[i].farmID < candidates[j].farmID
+		}
+		return candidates[i].overdue > candidates[j].overdue
+	})
+
+	var farmIDs []string
+	for i := 0; i < len(candidates) && i < r.batchSize; i++ {
+		farmIDs = append(farmIDs, candidates[i].farmID)
+	}
+	return farmIDs
+}
+
+// refreshOnce queues each farm that is due, pausing between farms. It
+// returns early if stop is closed.
+func (r *refresher) refreshOnce(stop <-chan struct{}) {
+	farmIDs := r.due(time.Now())
+	if len(farmIDs) > 0 {
+		log.Infof("refreshing %d farms", len(farmIDs))
+	}
+	for _, farmID := range farmIDs {
+		select {
+		case <-stop:
+			return
+		case <-time.After(r.interval):
+		}
+
+		// stored farms were scraped under a valid id
+		ctx := withReqID(context.Background(), newReqID("refresh"))
+		req, s := newFarmRequest(ctx, FarmID(farmID))
+		s.setAttr("lane", laneRefresh.String())
+		req.done = r.record
+		select {
+		case <-stop:
+			s.finish(nil)
+			return
+		case r.queue.lane(laneRefresh) <- req:
+			s.finish(nil)
+		}
+	}
+	r.mu.Lock()
+	r.lastRun = time.Now()
+	r.mu.Unlock()
+}
+
+// record counts a refresh once a worker has fetched the farm.
+func (r *refresher) record(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.failed++
+	} else {
+		r.refreshed++
+	}
+}
+
+func (r *refresher) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lastRun := "never"
+	if !r.lastRun.IsZero() {
+		lastRun = r.lastRun.Format(time.RFC3339)
+	}
+	return fmt.Sprintf("refresh: %d farms re-fetched, %d failed, last pass %s",
+		r.refreshed, r.failed, lastRun)
+}
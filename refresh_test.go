@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// farmPage serves a minimal upload.farm farm page with the given Abigail
+// friendship level.
+func farmPage(abigail *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<img title='x'><br>Abigail: %d/10'><img title='y'><br>Sam: 3/10'>", *abigail)
+	}))
+}
+
+func TestRefresh(t *testing.T) {
+	Convey("Given a refresher", t, func() {
+		r := newRefresher(newFarmQueue(10))
+		r.interval = time.Millisecond
+
+		Convey("popular farms are refreshed sooner, down to minAge", func() {
+			So(r.refreshAge(0), ShouldEqual, defaultRefreshMaxAge)
+			So(r.refreshAge(6), ShouldEqual, 24*time.Hour)
+			So(r.refreshAge(1000), ShouldEqual, defaultRefreshMinAge)
+		})
+
+		Convey("only farms older than their refresh age are due, most overdue first", func() {
+			now := time.Now()
+			allFarms.stats = map[string]svStats{
+				"1AAAAA": {FarmID: "1AAAAA", Fetched: now.Add(-8 * 24 * time.Hour)},
+				"1BBBBB": {FarmID: "1BBBBB", Fetched: now.Add(-time.Hour)},
+				"1CCCCC": {FarmID: "1CCCCC", Fetched: now.Add(-2 * 24 * time.Hour)},
+			}
+			allFarms.lookups = map[string]int{"1CCCCC": 6}
+			So(r.due(now), ShouldResemble, []string{"1CCCCC", "1AAAAA"})
+		})
+
+		Convey("a refreshed farm keeps its earlier snapshots", func() {
+			abigail := 4
+			srv := farmPage(&abigail)
+			defer srv.Close()
+			farmBaseURL = srv.URL
+			defer func() { farmBaseURL = "https://upload.farm" }()
+
+			allFarms.stats = map[string]svStats{}
+			allFarms.history = map[string][]svStats{}
+			first, err := scrapeFarm(context.Background(), "1AAAAA")
+			So(err, ShouldBeNil)
+			So(first.Abigail, ShouldEqual, 4)
+			So(first.Sam, ShouldEqual, 3)
+			first.Fetched = time.Now().Add(-8 * 24 * time.Hour)
+			allFarms.store(first)
+
+			abigail = 7
+			r.refreshOnce(nil)
+			So(len(r.queue.lane(laneRefresh)), ShouldEqual, 1)
+			req, _ := r.queue.pop(nil)
+			statsQueue := make(chan farmResult, 1)
+			processFarmID(context.Background(), req, statsQueue)
+			So(len(statsQueue), ShouldEqual, 1)
+			allFarms.store((<-statsQueue).Stats)
+
+			So(allFarms.stats["1AAAAA"].Abigail, ShouldEqual, 7)
+			So(len(allFarms.history["1AAAAA"]), ShouldEqual, 2)
+			So(allFarms.history["1AAAAA"][0].Abigail, ShouldEqual, 4)
+			So(r.refreshed, ShouldEqual, 1)
+		})
+	})
+}
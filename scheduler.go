@@ -0,0 +1,343 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+var errUnknownJob = errors.New("unknown job")
+
+// jobConfig sets when a job runs: either every interval or on a cron
+// schedule, plus up to jitter of random delay.
+type jobConfig struct {
+	Every  string `json:"every"`
+	Cron   string `json:"cron"`
+	Jitter string `json:"jitter"`
+	Paused bool   `json:"paused"`
+}
+
+// jobFunc does one run of a job, returning early if stop is closed.
+type jobFunc func(stop <-chan struct{}) error
+
+type job struct {
+	name     string
+	run      jobFunc
+	every    time.Duration
+	spec     string
+	schedule cron.Schedule
+	jitter   time.Duration
+	trigger  chan struct{}
+
+	mu           sync.Mutex
+	paused       bool
+	running      bool
+	next         time.Time
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastErr      error
+	runs         int
+}
+
+// jobStatus is a point-in-time copy of a job's state.
+type jobStatus struct {
+	Name         string        `json:"name"`
+	Schedule     string        `json:"schedule"`
+	Paused       bool          `json:"paused"`
+	Running      bool          `json:"running"`
+	Next         time.Time     `json:"next"`
+	LastRun      time.Time     `json:"lastRun"`
+	LastDuration time.Duration `json:"lastDuration"`
+	LastError    string        `json:"lastError,omitempty"`
+	Runs         int           `json:"runs"`
+}
+
+// scheduler runs recurring jobs. Each job has its own goroutine, so a job
+// never overlaps with itself and a slow job does not delay the others.
+type scheduler struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+	stop chan struct{}
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		jobs: make(map[string]*job),
+		stop: make(chan struct{}),
+	}
+}
+
+func newJob(name string, cfg jobConfig, run jobFunc) (*job, error) {
+	j := &job{
+		name:    name,
+		run:     run,
+		spec:    cfg.Cron,
+		paused:  cfg.Paused,
+		trigger: make(chan struct{}, 1),
+	}
+
+	var err error
+	switch {
+	case cfg.Cron != "":
+		j.schedule, err = cron.ParseStandard(cfg.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: invalid cron [%s]: %v", name, cfg.Cron, err)
+		}
+	case cfg.Every != "":
+		j.every, err = time.ParseDuration(cfg.Every)
+		if err != nil || j.every <= 0 {
+			return nil, fmt.Errorf("job %s: invalid interval [%s]", name, cfg.Every)
+		}
+	default:
+		return nil, fmt.Errorf("job %s: needs an interval or a cron schedule", name)
+	}
+
+	if cfg.Jitter != "" {
+		j.jitter, err = time.ParseDuration(cfg.Jitter)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: invalid jitter [%s]", name, cfg.Jitter)
+		}
+	}
+	return j, nil
+}
+
+// add registers a job. Jobs start running when the scheduler is started.
+func (s *scheduler) add(name string, cfg jobConfig, run jobFunc) error {
+	j, err := newJob(name, cfg, run)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.jobs[name] = j
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *scheduler) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		go j.loop(s.stop)
+	}
+}
+
+// shutdown stops every job loop and asks running jobs to return.
+func (s *scheduler) shutdown() {
+	close(s.stop)
+}
+
+func (s *scheduler) job(name string) (*job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w [%s]", errUnknownJob, name)
+	}
+	return j, nil
+}
+
+func (s *scheduler) pause(name string) error {
+	j, err := s.job(name)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.paused = true
+	j.mu.Unlock()
+	return nil
+}
+
+func (s *scheduler) resume(name string) error {
+	j, err := s.job(name)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.paused = false
+	j.mu.Unlock()
+	return nil
+}
+
+// trigger runs a job now, even if it is paused.
+func (s *scheduler) trigger(name string) error {
+	j, err := s.job(name)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	running := j.running
+	j.mu.Unlock()
+	if running {
+		return fmt.Errorf("job %s is already running", name)
+	}
+	select {
+	case j.trigger <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (s *scheduler) list() []jobStatus {
+	s.mu.Lock()
+	var statuses []jobStatus
+	for _, j := range s.jobs {
+		statuses = append(statuses, j.status())
+	}
+	s.mu.Unlock()
+	sort.Slice(statuses, func(i, k int) bool { return statuses[i].Name < statuses[k].Name })
+	return statuses
+}
+
+// nextRun works out when the job should next run, including jitter.
+func (j *job) nextRun(now time.Time) time.Time {
+	var next time.Time
+	if j.schedule != nil {
+		next = j.schedule.Next(now)
+	} else {
+		next = now.Add(j.every)
+	}
+	if j.jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(j.jitter))))
+	}
+	return next
+}
+
+func (j *job) loop(stop chan struct{}) {
+	for {
+		next := j.nextRun(time.Now())
+		j.mu.Lock()
+		j.next = next
+		j.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		triggered := false
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		case <-j.trigger:
+			timer.Stop()
+			triggered = true
+		}
+
+		j.mu.Lock()
+		paused := j.paused
+		j.mu.Unlock()
+		if paused && !triggered {
+			continue
+		}
+		j.execute(stop)
+	}
+}
+
+func (j *job) execute(stop chan struct{}) {
+	j.mu.Lock()
+	j.running = true
+	j.mu.Unlock()
+
+	log.Debugf("running job %s", j.name)
+	startTime := time.Now()
+	err := j.run(stop)
+	dur := time.Since(startTime)
+	if err != nil {
+		log.Warnf("job %s failed after %v: %v", j.name, dur, err)
+	}
+
+	j.mu.Lock()
+	j.running = false
+	j.lastRun = startTime
+	j.lastDuration = dur
+	j.lastErr = err
+	j.runs++
+	j.mu.Unlock()
+}
+
+func (j *job) status() jobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	schedule := "every " + j.every.String()
+	if j.schedule != nil {
+		schedule = "cron " + j.spec
+	}
+	if j.jitter > 0 {
+		schedule += " +" + j.jitter.String()
+	}
+	st := jobStatus{
+		Name:         j.name,
+		Schedule:     schedule,
+		Paused:       j.paused,
+		Running:      j.running,
+		Next:         j.next,
+		LastRun:      j.lastRun,
+		LastDuration: j.lastDuration,
+		Runs:         j.runs,
+	}
+	if j.lastErr != nil {
+		st.LastError = j.lastErr.Error()
+	}
+	return st
+}
+
+func (st jobStatus) String() string {
+	state := "waiting"
+	switch {
+	case st.Running:
+		state = "running"
+	case st.Paused:
+		state = "paused"
+	}
+	lastRun := "never"
+	if !st.LastRun.IsZero() {
+		lastRun = fmt.Sprintf("%s (took %v)", st.LastRun.Format(time.RFC3339), st.LastDuration)
+	}
+	line := fmt.Sprintf("%s: %s, %s, %d runs, last %s, next %s",
+		st.Name, st.Schedule, state, st.Runs, lastRun, st.Next.Format(time.RFC3339))
+	if st.LastError != "" {
+		line += ", last error: " + st.LastError
+	}
+	return line
+}
+
+// jobsRouter serves the scheduler under /api/v1/jobs.
+func (s *scheduler) jobsRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, s.list())
+	})
+	r.Post("/{name}/{action}", func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		var err error
+		switch chi.URLParam(r, "action") {
+		case "pause":
+			err = s.pause(name)
+		case "resume":
+			err = s.resume(name)
+		case "trigger":
+			err = s.trigger(name)
+		default:
+			http.Error(w, "unknown action", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, errUnknownJob) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		j, _ := s.job(name)
+		render.JSON(w, r, j.status())
+	})
+	return r
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestScheduler(t *testing.T) {
+	Convey("When configuring jobs", t, func() {
+		noop := func(stop <-chan struct{}) error { return nil }
+
+		Convey("an interval or a cron schedule is required", func() {
+			_, err := newJob("x", jobConfig{}, noop)
+			So(err, ShouldNotBeNil)
+			_, err = newJob("x", jobConfig{Every: "soon"}, noop)
+			So(err, ShouldNotBeNil)
+			_, err = newJob("x", jobConfig{Cron: "not a cron"}, noop)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("jitter delays the next run by up to the jitter", func() {
+			j, err := newJob("x", jobConfig{Every: "1m", Jitter: "10s"}, noop)
+			So(err, ShouldBeNil)
+			now := time.Now()
+			for i := 0; i < 20; i++ {
+				next := j.nextRun(now)
+				So(next, ShouldHappenOnOrAfter, now.Add(time.Minute))
+				So(next, ShouldHappenBefore, now.Add(time.Minute+10*time.Second))
+			}
+		})
+
+		Convey("cron schedules run at the next matching time", func() {
+			j, err := newJob("x", jobConfig{Cron: "0 * * * *"}, noop)
+			So(err, ShouldBeNil)
+			now := time.Date(2020, 5, 1, 10, 20, 0, 0, time.UTC)
+			So(j.nextRun(now), ShouldEqual, time.Date(2020, 5, 1, 11, 0, 0, 0, time.UTC))
+		})
+
+		Convey("a config file overrides individual jobs", func() {
+			f, _ := ioutil.TempFile("", "farmstats")
+			defer os.Remove(f.Name())
+			f.WriteString(`{"jobs": {"recents": {"cron": "*/5 * * * *"}}}`)
+			f.Close()
+
+			cfg, err := loadConfig(f.Name())
+			So(err, ShouldBeNil)
+			So(cfg.Jobs["recents"].Cron, ShouldEqual, "*/5 * * * *")
+			So(cfg.Jobs["crawl"].Every, ShouldEqual, "10m")
+		})
+	})
+
+	Convey("Given a running scheduler with a paused job", t, func() {
+		s := newScheduler()
+		defer s.shutdown()
+		ran := make(chan struct{})
+		release := make(chan struct{})
+		s.add("slow", jobConfig{Every: "1h", Paused: true}, func(stop <-chan struct{}) error {
+			ran <- struct{}{}
+			<-release
+			return nil
+		})
+		s.start()
+
+		Convey("triggering runs it anyway, and it cannot overlap itself", func() {
+			So(s.trigger("slow"), ShouldBeNil)
+			<-ran
+			So(s.list()[0].Running, ShouldBeTrue)
+			So(s.trigger("slow"), ShouldNotBeNil)
+			close(release)
+
+			for s.list()[0].Runs == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			st := s.list()[0]
+			So(st.Running, ShouldBeFalse)
+			So(st.Paused, ShouldBeTrue)
+			So(st.LastRun.IsZero(), ShouldBeFalse)
+		})
+
+		Convey("unknown jobs are reported", func() {
+			So(s.pause("nope"), ShouldNotBeNil)
+			So(s.trigger("nope"), ShouldNotBeNil)
+		})
+
+		Convey("the HTTP api lists and controls jobs", func() {
+			router := s.jobsRouter()
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", "/slow/resume", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			var statuses []jobStatus
+			So(json.Unmarshal(w.Body.Bytes(), &statuses), ShouldBeNil)
+			So(len(statuses), ShouldEqual, 1)
+			So(statuses[0].Name, ShouldEqual, "slow")
+			So(statuses[0].Paused, ShouldBeFalse)
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", "/nope/pause", nil))
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+		})
+	})
+}
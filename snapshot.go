@@ -0,0 +1,337 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	snapshotFormat  = "farmstats-snapshot"
+	snapshotVersion = 1
+	// snapshotBatch is how many spidered farms go in each record
+	snapshotBatch = 1000
+)
+
+// snapshotStore is the redis state a snapshot covers: the `spidered` set
+// and the backfill cursors.
+type snapshotStore interface {
+	backfillStore
+	zAddNXer
+}
+
+// A snapshot is a gzipped stream of JSON values: a snapshotHeader, then
+// snapshotRecords, each holding one farm's history, a batch of failed
+// scrapes, a batch of the spidered set, or a redis key. Page caches and
+// lookup counts are not included.
+type snapshotHeader struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+}
+
+type snapshotRecord struct {
+	// Farm is every stored version of a farm, oldest first.
+	Farm []svStats `json:"farm,omitempty"`
+	// Failed is farms whose last scrape failed, so their dead letters
+	// survive a restart.
+	Failed   []farmFailure   `json:"failed,omitempty"`
+	Spidered []spideredFarm  `json:"spidered,omitempty"`
+	Key      *snapshotKeyVal `json:"key,omitempty"`
+}
+
+type spideredFarm struct {
+	FarmID string  `json:"farmID"`
+	Score  float64 `json:"score"`
+}
+
+type snapshotKeyVal struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// snapshotCounts is what a snapshot holds, or what a restore loaded.
+type snapshotCounts struct {
+	Farms    int
+	Versions int
+	Failed   int
+	Spidered int
+	Keys     int
+}
+
+func (c snapshotCounts) String() string {
+	return fmt.Sprintf("%d farms (%d versions), %d failed, %d spidered, %d keys", c.Farms, c.Versions, c.Failed, c.Spidered, c.Keys)
+}
+
+// snapshotKeys are the redis keys a snapshot copies.
+var snapshotKeys = []string{backfillCursorKey + "oldest", backfillCursorKey + "newest"}
+
+// versions returns a copy of every stored version of a farm.
+func (s *farmStats) versions(farmID string) []svStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := append([]svStats(nil), s.history[farmID]...)
+	if len(versions) == 0 {
+		if stats, ok := s.stats[farmID]; ok {
+			versions = append(versions, stats)
+		}
+	}
+	return versions
+}
+
+// restore replaces a farm's history, and its current stats with the
+// newest version.
+func (s *farmStats) restore(versions []svStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.history == nil {
+		s.history = make(map[string][]svStats)
+	}
+	latest := versions[len(versions)-1]
+	s.stats[latest.FarmID] = latest
+	s.history[latest.FarmID] = versions
+}
+
+// setLoaded records whether the store holds everything it should.
+func (s *farmStats) setLoaded(loaded bool) {
+	s.mu.Lock()
+	s.loaded = loaded
+	s.mu.Unlock()
+}
+
+func (s *farmStats) isLoaded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loaded
+}
+
+// loadStore restores the snapshot at path, if there is one, and then marks
+// the store loaded. A failed restore leaves it unloaded, so we never report
+// ready with half the farms missing.
+func loadStore(path string, redisdb snapshotStore) error {
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		counts, err := restoreSnapshot(f, redisdb)
+		if err != nil {
+			return fmt.Errorf("restore stopped after %s: %v", counts, err)
+		}
+		log.Infof("restored %s from %s", counts, path)
+	}
+	allFarms.setLoaded(true)
+	return nil
+}
+
+// writeSnapshot writes the stored farms and, if redisdb is set, the redis
+// state to w. Farms are copied out a page at a time so the store stays
+// usable while it runs.
+func writeSnapshot(w io.Writer, redisdb snapshotStore) (snapshotCounts, error) {
+	var counts snapshotCounts
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	err := enc.Encode(snapshotHeader{Format: snapshotFormat, Version: snapshotVersion, Created: time.Now().UTC()})
+	if err != nil {
+		return counts, err
+	}
+
+	pager := allFarms.pager(exportPageSize)
+	for page := pager.next(); page != nil; page = pager.next() {
+		for _, stats := range page {
+			versions := allFarms.versions(stats.FarmID)
+			err = enc.Encode(snapshotRecord{Farm: versions})
+			if err != nil {
+				return counts, err
+			}
+			counts.Farms++
+			counts.Versions += len(versions)
+		}
+	}
+
+	failures := tracker.failures()
+	for len(failures) > 0 {
+		n := len(failures)
+		if n > snapshotBatch {
+			n = snapshotBatch
+		}
+		err = enc.Encode(snapshotRecord{Failed: failures[:n]})
+		if err != nil {
+			return counts, err
+		}
+		counts.Failed += n
+		failures = failures[n:]
+	}
+
+	if redisdb != nil {
+		err = writeSnapshotRedis(enc, redisdb, &counts)
+		if err != nil {
+			return counts, err
+		}
+	}
+	return counts, zw.Close()
+}
+
+func writeSnapshotRedis(enc *json.Encoder, redisdb snapshotStore, counts *snapshotCounts) error {
+	min := "-inf"
+	for {
+		batch, err := redisdb.ZRangeByScoreWithScores("spidered", redis.ZRangeBy{
+			Min: min, Max: "+inf", Count: snapshotBatch,
+		}).Result()
+		if err != nil {
+			return fmt.Errorf("cannot read spidered farms: %v", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		record := snapshotRecord{}
+		for _, z := range batch {
+			farmID, _ := z.Member.(string)
+			record.Spidered = append(record.Spidered, spideredFarm{FarmID: farmID, Score: z.Score})
+		}
+		err = enc.Encode(record)
+		if err != nil {
+			return err
+		}
+		counts.Spidered += len(batch)
+		min = "(" + strconv.FormatFloat(batch[len(batch)-1].Score, 'f', -1, 64)
+	}
+
+	for _, key := range snapshotKeys {
+		value, err := redisdb.Get(key).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %v", key, err)
+		}
+		err = enc.Encode(snapshotRecord{Key: &snapshotKeyVal{Name: key, Value: value}})
+		if err != nil {
+			return err
+		}
+		counts.Keys++
+	}
+	return nil
+}
+
+// restoreSnapshot loads a snapshot into the farm store and, if redisdb is
+// set, redis. Restored farms and failures replace any recorded under the
+// same id; spidered farms are added to the set, and keys overwritten. Farm
+// ids are checked, so a bad snapshot cannot fill the store with junk.
+func restoreSnapshot(r io.Reader, redisdb snapshotStore) (snapshotCounts, error) {
+	var counts snapshotCounts
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return counts, fmt.Errorf("not a snapshot: %v", err)
+	}
+	defer zr.Close()
+	dec := json.NewDecoder(zr)
+
+	var header snapshotHeader
+	err = dec.Decode(&header)
+	if err != nil || header.Format != snapshotFormat {
+		return counts, fmt.Errorf("not a snapshot")
+	}
+	if header.Version > snapshotVersion {
+		return counts, fmt.Errorf("snapshot version %d is newer than this server supports (%d)", header.Version, snapshotVersion)
+	}
+
+	for {
+		var record snapshotRecord
+		err = dec.Decode(&record)
+		if err == io.EOF {
+			return counts, nil
+		}
+		if err != nil {
+			return counts, fmt.Errorf("corrupt snapshot: %v", err)
+		}
+		switch {
+		case len(record.Farm) > 0:
+			farmID := record.Farm[0].FarmID
+			if _, err := ParseFarmID(farmID); err != nil {
+				return counts, fmt.Errorf("snapshot has a bad farm: %v", err)
+			}
+			for _, stats := range record.Farm {
+				if stats.FarmID != farmID {
+					return counts, fmt.Errorf("snapshot mixes farm %s into the history of %s", stats.FarmID, farmID)
+				}
+			}
+			allFarms.restore(record.Farm)
+			counts.Farms++
+			counts.Versions += len(record.Farm)
+		case len(record.Failed) > 0:
+			for _, failure := range record.Failed {
+				if _, err := ParseFarmID(string(failure.FarmID)); err != nil {
+					return counts, fmt.Errorf("snapshot has a bad failed farm: %v", err)
+				}
+			}
+			for _, failure := range record.Failed {
+				tracker.restoreFailure(failure)
+			}
+			counts.Failed += len(record.Failed)
+		case redisdb == nil:
+			// farms only
+		case len(record.Spidered) > 0:
+			zs := make([]redis.Z, len(record.Spidered))
+			for i, farm := range record.Spidered {
+				zs[i] = redis.Z{Score: farm.Score, Member: farm.FarmID}
+			}
+			err = redisdb.ZAddNX("spidered", zs...).Err()
+			if err != nil {
+				return counts, fmt.Errorf("cannot restore spidered farms: %v", err)
+			}
+			counts.Spidered += len(zs)
+		case record.Key != nil:
+			if !strings.HasPrefix(record.Key.Name, backfillCursorKey) {
+				return counts, fmt.Errorf("snapshot has unexpected key [%s]", record.Key.Name)
+			}
+			err = redisdb.Set(record.Key.Name, record.Key.Value, 0).Err()
+			if err != nil {
+				return counts, fmt.Errorf("cannot restore %s: %v", record.Key.Name, err)
+			}
+			counts.Keys++
+		}
+	}
+}
+
+// snapshotHandler streams a snapshot of the whole dataset.
+func snapshotHandler(redisdb snapshotStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := log.WithField("reqID", reqIDFromContext(r.Context()))
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="farmstats-%s.json.gz"`, time.Now().UTC().Format("20060102-150405")))
+		counts, err := writeSnapshot(w, redisdb)
+		if err != nil {
+			logger.WithError(err).Warn("snapshot failed")
+			return
+		}
+		logger.Infof("sent snapshot of %s", counts)
+	}
+}
+
+// restoreHandler loads a snapshot uploaded as the request body.
+func restoreHandler(redisdb snapshotStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := log.WithField("reqID", reqIDFromContext(r.Context()))
+		allFarms.setLoaded(false)
+		counts, err := restoreSnapshot(r.Body, redisdb)
+		if err != nil {
+			logger.WithError(err).Warnf("restore stopped after %s", counts)
+			http.Error(w, fmt.Sprintf("restore stopped after %s: %v", counts, err), http.StatusBadRequest)
+			return
+		}
+		allFarms.setLoaded(true)
+		logger.Infof("restored %s", counts)
+		fmt.Fprintf(w, "restored %s\n", counts)
+	}
+}
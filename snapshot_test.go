@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSnapshot(t *testing.T) {
+	Convey("Given stored farms and redis state", t, func() {
+		now := time.Now().UTC().Truncate(time.Second)
+		allFarms.stats = map[string]svStats{}
+		allFarms.history = map[string][]svStats{}
+		allFarms.store(svStats{FarmID: "1AAAAA", Fetched: now, PageHash: "a", Abigail: 2})
+		allFarms.store(svStats{FarmID: "1AAAAA", Fetched: now.Add(time.Hour), PageHash: "b", Abigail: 4})
+		allFarms.store(svStats{FarmID: "1BBBBB", Fetched: now, PageHash: "c", Sam: 10})
+		var farmIDs []string
+		for i := 0; i < snapshotBatch+5; i++ {
+			farmIDs = append(farmIDs, fmt.Sprintf("1C%04d", i))
+		}
+		src := newFakeSpidered(farmIDs...)
+		src.keys[backfillCursorKey+"oldest"] = "12345"
+		tracker = newFarmTracker()
+		tracker.markFailed("1DDDDD", &httpStatusError{StatusCode: 404, Status: "404 Not Found"})
+		tracker.markFailed("1EEEEE", &parseError{FarmID: "1EEEEE", Reason: "no villagers"})
+		tracker.markFailed("1FFFFF", errors.New("connection reset"))
+
+		var buf bytes.Buffer
+		counts, err := writeSnapshot(&buf, src)
+		So(err, ShouldBeNil)
+		So(counts, ShouldResemble, snapshotCounts{Farms: 2, Versions: 3, Failed: 3, Spidered: snapshotBatch + 5, Keys: 1})
+
+		Convey("it restores into an empty server and redis", func() {
+			allFarms.stats = map[string]svStats{}
+			allFarms.history = map[string][]svStats{}
+			dst := newFakeSpidered()
+			restored, err := restoreSnapshot(bytes.NewReader(buf.Bytes()), dst)
+			So(err, ShouldBeNil)
+			So(restored, ShouldResemble, counts)
+			So(allFarms.stats["1AAAAA"].Abigail, ShouldEqual, 4)
+			So(allFarms.stats["1AAAAA"].Fetched.Equal(now.Add(time.Hour)), ShouldBeTrue)
+			So(allFarms.history["1AAAAA"], ShouldHaveLength, 2)
+			So(allFarms.stats["1BBBBB"].Sam, ShouldEqual, 10)
+			So(dst.members, ShouldResemble, src.members)
+			So(dst.keys, ShouldResemble, src.keys)
+		})
+
+		Convey("failed scrapes are restored with their kind of failure", func() {
+			tracker = newFarmTracker()
+			_, err := restoreSnapshot(bytes.NewReader(buf.Bytes()), nil)
+			So(err, ShouldBeNil)
+			So(tracker.failures(), ShouldHaveLength, 3)
+			_, failure := tracker.state("1DDDDD")
+			So(grpcstatus.Code(failureError(*failure)), ShouldEqual, codes.NotFound)
+			_, failure = tracker.state("1EEEEE")
+			So(grpcstatus.Code(failureError(*failure)), ShouldEqual, codes.Internal)
+			_, failure = tracker.state("1FFFFF")
+			So(failure.Err.Error(), ShouldEqual, "connection reset")
+			So(grpcstatus.Code(failureError(*failure)), ShouldEqual, codes.Unavailable)
+		})
+
+		Convey("records with bad farm ids are refused", func() {
+			bad := func(record string) error {
+				var b bytes.Buffer
+				zw := gzip.NewWriter(&b)
+				zw.Write([]byte(`{"format":"farmstats-snapshot","version":1}` + "\n" + record))
+				zw.Close()
+				_, err := restoreSnapshot(&b, nil)
+				return err
+			}
+			allFarms.stats = map[string]svStats{}
+			So(bad(`{"farm":[{"farmID":""}]}`), ShouldNotBeNil)
+			So(bad(`{"farm":[{"farmID":"1AAAAA"},{"farmID":"1BBBBB"}]}`), ShouldNotBeNil)
+			So(bad(`{"failed":[{"farmID":"bogus","error":"x"}]}`), ShouldNotBeNil)
+			So(allFarms.stats, ShouldBeEmpty)
+			So(bad(`{"farm":[{"farmID":"1AAAAA"}]}`), ShouldBeNil)
+		})
+
+		Convey("without redis only the farms are restored", func() {
+			allFarms.stats = map[string]svStats{}
+			restored, err := restoreSnapshot(bytes.NewReader(buf.Bytes()), nil)
+			So(err, ShouldBeNil)
+			So(restored, ShouldResemble, snapshotCounts{Farms: 2, Versions: 3, Failed: 3})
+		})
+
+		Convey("other files are refused", func() {
+			_, err := restoreSnapshot(strings.NewReader("{}"), nil)
+			So(err, ShouldNotBeNil)
+
+			var other bytes.Buffer
+			zw := gzip.NewWriter(&other)
+			zw.Write([]byte(`{"format":"farmstats-snapshot","version":99}`))
+			zw.Close()
+			_, err = restoreSnapshot(&other, nil)
+			So(err.Error(), ShouldContainSubstring, "version 99")
+		})
+
+		Convey("the store is only loaded once the startup restore succeeds", func() {
+			dir, err := ioutil.TempDir("", "snapshot")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			allFarms.stats = map[string]svStats{}
+			allFarms.setLoaded(false)
+
+			So(loadStore(filepath.Join(dir, "missing"), nil), ShouldNotBeNil)
+			So(allFarms.isLoaded(), ShouldBeFalse)
+
+			path := filepath.Join(dir, "farms.json.gz")
+			So(ioutil.WriteFile(path, buf.Bytes(), 0644), ShouldBeNil)
+			So(loadStore(path, nil), ShouldBeNil)
+			So(allFarms.isLoaded(), ShouldBeTrue)
+			So(allFarms.stats, ShouldHaveLength, 2)
+		})
+
+		Convey("with nothing to restore the store is loaded at once", func() {
+			allFarms.setLoaded(false)
+			So(loadStore("", nil), ShouldBeNil)
+			So(allFarms.isLoaded(), ShouldBeTrue)
+		})
+
+		Convey("it can be taken and restored over HTTP", func() {
+			w := httptest.NewRecorder()
+			snapshotHandler(src)(w, httptest.NewRequest("GET", "/api/v1/snapshot", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Header().Get("Content-Type"), ShouldEqual, "application/gzip")
+
+			dst := newFakeSpidered()
+			w2 := httptest.NewRecorder()
+			restoreHandler(dst)(w2, httptest.NewRequest("POST", "/api/v1/restore", w.Body))
+			So(w2.Code, ShouldEqual, http.StatusOK)
+			So(w2.Body.String(), ShouldStartWith, "restored 2 farms (3 versions)")
+			So(allFarms.isLoaded(), ShouldBeTrue)
+
+			w3 := httptest.NewRecorder()
+			restoreHandler(dst)(w3, httptest.NewRequest("POST", "/api/v1/restore", strings.NewReader("junk")))
+			So(w3.Code, ShouldEqual, http.StatusBadRequest)
+			So(allFarms.isLoaded(), ShouldBeFalse)
+		})
+	})
+}
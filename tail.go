@@ -0,0 +1,115 @@
+package main
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const tailBuffer = 100
+
+// subscription receives events from a hub into its own buffer. Events that
+// arrive while the buffer is full are counted and dropped.
+type subscription struct {
+	events chan interface{}
+	filter func(event interface{}) bool
+
+	mu      sync.Mutex
+	dropped int
+}
+
+// takeDropped returns how many events were dropped since the last call.
+func (s *subscription) takeDropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.dropped
+	s.dropped = 0
+	return n
+}
+
+// hub fans events out to its subscribers without ever waiting on them, so a
+// slow reader cannot hold up the publisher. Publishers must not log while
+// publishing: the log hook publishes too.
+type hub struct {
+	mu   sync.Mutex
+	subs map[*subscription]struct{}
+}
+
+func newHub() *hub {
+	return &hub{subs: make(map[*subscription]struct{})}
+}
+
+// farmEvents carries each farm as the stats consumer stores it, and
+// logEvents each log entry once something is tailing the log.
+var farmEvents = newHub()
+var logEvents = newHub()
+
+// subscribe starts receiving events that pass filter, or all events if
+// filter is nil.
+func (h *hub) subscribe(filter func(event interface{}) bool) *subscription {
+	s := &subscription{events: make(chan interface{}, tailBuffer), filter: filter}
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	return s
+}
+
+// unsubscribe stops delivery and closes the subscription's channel.
+func (h *hub) unsubscribe(s *subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.events)
+	}
+}
+
+func (h *hub) publish(event interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		if s.filter != nil && !s.filter(event) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			s.mu.Lock()
+			s.dropped++
+			s.mu.Unlock()
+		}
+	}
+}
+
+// logEvent is a formatted log line and its level.
+type logEvent struct {
+	Level log.Level
+	Line  string
+}
+
+// logTailHook publishes every log entry to logEvents.
+type logTailHook struct {
+	formatter log.Formatter
+}
+
+func (h *logTailHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *logTailHook) Fire(entry *log.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	logEvents.publish(logEvent{Level: entry.Level, Line: string(line)})
+	return nil
+}
+
+var logTailOnce sync.Once
+
+// ensureLogTail installs the log hook the first time the log is tailed.
+func ensureLogTail() {
+	logTailOnce.Do(func() {
+		log.AddHook(&logTailHook{formatter: &log.TextFormatter{DisableColors: true, FullTimestamp: true}})
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHub(t *testing.T) {
+	Convey("Given a hub", t, func() {
+		h := newHub()
+
+		Convey("events reach every subscriber that wants them", func() {
+			all := h.subscribe(nil)
+			even := h.subscribe(func(event interface{}) bool { return event.(int)%2 == 0 })
+			h.publish(1)
+			h.publish(2)
+			So(<-all.events, ShouldEqual, 1)
+			So(<-all.events, ShouldEqual, 2)
+			So(<-even.events, ShouldEqual, 2)
+			So(even.events, ShouldBeEmpty)
+		})
+
+		Convey("a full subscriber has events dropped instead of blocking", func() {
+			s := h.subscribe(nil)
+			for i := 0; i < tailBuffer+5; i++ {
+				h.publish(i)
+			}
+			So(len(s.events), ShouldEqual, tailBuffer)
+			So(s.takeDropped(), ShouldEqual, 5)
+			So(s.takeDropped(), ShouldEqual, 0)
+		})
+
+		Convey("unsubscribing closes the channel once", func() {
+			s := h.subscribe(nil)
+			h.unsubscribe(s)
+			h.unsubscribe(s)
+			h.publish(1)
+			_, ok := <-s.events
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("The log hook publishes formatted lines", t, func() {
+		s := logEvents.subscribe(nil)
+		defer logEvents.unsubscribe(s)
+		hook := &logTailHook{formatter: &log.TextFormatter{DisableColors: true}}
+		So(hook.Fire(log.WithField("farm", "1AAAAA")), ShouldBeNil)
+		event := (<-s.events).(logEvent)
+		So(event.Level, ShouldEqual, log.PanicLevel)
+		So(event.Line, ShouldContainSubstring, "farm=1AAAAA")
+	})
+}
@@ -0,0 +1,964 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/firstrow/tcp_server"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+const telnetPageLines = 20
+
+// telnetImportRejects is how many rejected entries /import lists.
+const telnetImportRejects = 10
+
+// telnetConn is the part of a tcp_server client that commands use.
+type telnetConn interface {
+	Send(message string) error
+	Close() error
+}
+
+// telnetSession is one connected telnet client.
+type telnetSession struct {
+	conn   telnetConn
+	remote string
+
+	mu        sync.Mutex
+	principal principal
+	more      []string
+	tails     map[string]tail
+}
+
+// tail is one stream a session is following.
+type tail struct {
+	hub *hub
+	sub *subscription
+}
+
+// follow sends the session every event from h that passes filter until
+// unfollow. Events wait in the subscription's buffer while the client is
+// slow, and are dropped, with a note, if it fills.
+func (s *telnetSession) follow(name string, h *hub, filter func(interface{}) bool, format func(interface{}) string) {
+	s.unfollow(name)
+	sub := h.subscribe(filter)
+	s.mu.Lock()
+	if s.tails == nil {
+		s.tails = make(map[string]tail)
+	}
+	s.tails[name] = tail{hub: h, sub: sub}
+	s.mu.Unlock()
+
+	go func() {
+		for event := range sub.events {
+			if n := sub.takeDropped(); n > 0 {
+				s.conn.Send(fmt.Sprintf("-- %d %s events dropped --\n", n, name))
+			}
+			s.conn.Send(format(event))
+		}
+	}()
+}
+
+// unfollow stops the named tails, or all of them, returning the names of
+// those that were running.
+func (s *telnetSession) unfollow(names ...string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(names) == 0 {
+		for name := range s.tails {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	var stopped []string
+	for _, name := range names {
+		if t, ok := s.tails[name]; ok {
+			t.hub.unsubscribe(t.sub)
+			delete(s.tails, name)
+			stopped = append(stopped, name)
+		}
+	}
+	return stopped
+}
+
+// takeMore returns the lines left over from the last paged reply.
+func (s *telnetSession) takeMore() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines := s.more
+	s.more = nil
+	return lines
+}
+
+func (s *telnetSession) user() principal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.principal
+}
+
+func (s *telnetSession) login(p principal) {
+	s.mu.Lock()
+	s.principal = p
+	s.mu.Unlock()
+}
+
+// telnetRequest is one command from a session, with its validated
+// arguments.
+type telnetRequest struct {
+	ctx     context.Context
+	session *telnetSession
+	logger  *log.Entry
+	args    []string
+}
+
+// reply sends a line to the client, adding the newline if it is missing.
+func (req *telnetRequest) reply(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	req.session.conn.Send(msg)
+}
+
+// page sends the first telnetPageLines lines and keeps the rest for /more.
+func (req *telnetRequest) page(lines []string) {
+	rest := []string(nil)
+	if len(lines) > telnetPageLines {
+		lines, rest = lines[:telnetPageLines], lines[telnetPageLines:]
+	}
+	req.session.mu.Lock()
+	req.session.more = rest
+	req.session.mu.Unlock()
+
+	text := strings.Join(lines, "\n")
+	if len(rest) > 0 {
+		text += fmt.Sprintf("\n-- %d more lines, /more to continue --", len(rest))
+	}
+	req.reply("%s", text)
+}
+
+// formatTable lines up rows under their column headers.
+func formatTable(header []string, rows [][]string) []string {
+	widths := make([]int, len(header))
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+	var lines []string
+	for _, row := range append([][]string{header}, rows...) {
+		var b strings.Builder
+		for i, cell := range row {
+			if i == len(row)-1 {
+				b.WriteString(cell)
+				break
+			}
+			fmt.Fprintf(&b, "%-*s  ", widths[i], cell)
+		}
+		lines = append(lines, b.String())
+	}
+	return lines
+}
+
+// arg returns argument i, or "" if an optional argument was left out.
+func (req *telnetRequest) arg(i int) string {
+	if i >= len(req.args) {
+		return ""
+	}
+	return req.args[i]
+}
+
+// intArg returns argument i as a number. Arguments are validated before the
+// command runs, so it cannot fail.
+func (req *telnetRequest) intArg(i int) int {
+	n, _ := strconv.Atoi(req.arg(i))
+	return n
+}
+
+type argKind int
+
+const (
+	argString argKind = iota
+	argInt
+	argChoice
+)
+
+// telnetArg describes one argument of a command.
+type telnetArg struct {
+	name     string
+	kind     argKind
+	choices  []string
+	optional bool
+}
+
+func (a telnetArg) String() string {
+	name := a.name
+	if a.kind == argChoice {
+		name = strings.Join(a.choices, "|")
+	}
+	if a.optional {
+		return "[" + name + "]"
+	}
+	return "<" + name + ">"
+}
+
+func (a telnetArg) validate(value string) error {
+	switch a.kind {
+	case argInt:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%s must be a whole number, not [%s]", a.name, value)
+		}
+	case argChoice:
+		for _, choice := range a.choices {
+			if value == choice {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s must be one of %s, not [%s]", a.name, strings.Join(a.choices, ", "), value)
+	}
+	return nil
+}
+
+// telnetCommand is one /command. role is what it needs to run; argsRole, if
+// higher, is what it needs when given arguments.
+type telnetCommand struct {
+	name        string
+	args        []telnetArg
+	description string
+	role        role
+	argsRole    role
+	run         func(t *telnet, req *telnetRequest)
+}
+
+func (cmd *telnetCommand) usage() string {
+	parts := []string{"/" + cmd.name}
+	for _, arg := range cmd.args {
+		parts = append(parts, arg.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func (cmd *telnetCommand) needs(args []string) role {
+	if len(args) > 0 && cmd.argsRole > cmd.role {
+		return cmd.argsRole
+	}
+	return cmd.role
+}
+
+// parseArgs checks the number and form of the arguments.
+func (cmd *telnetCommand) parseArgs(args []string) error {
+	required := 0
+	for _, arg := range cmd.args {
+		if !arg.optional {
+			required++
+		}
+	}
+	if len(args) < required || len(args) > len(cmd.args) {
+		return fmt.Errorf("usage: %s", cmd.usage())
+	}
+	for i, value := range args {
+		err := cmd.args[i].validate(value)
+		if err != nil {
+			return fmt.Errorf("%v (usage: %s)", err, cmd.usage())
+		}
+	}
+	return nil
+}
+
+// telnet is the telnet control interface: its commands and sessions.
+type telnet struct {
+	queue   *farmQueue
+	redisdb snapshotStore
+
+	commands map[string]*telnetCommand
+
+	mu       sync.Mutex
+	sessions map[*tcp_server.Client]*telnetSession
+}
+
+func newTelnet(queue *farmQueue, redisdb snapshotStore) *telnet {
+	t := &telnet{
+		queue:    queue,
+		redisdb:  redisdb,
+		commands: make(map[string]*telnetCommand),
+		sessions: make(map[*tcp_server.Client]*telnetSession),
+	}
+	for _, cmd := range telnetCommands() {
+		t.commands[cmd.name] = cmd
+	}
+	return t
+}
+
+func telnetServer(telnetPort string, queue *farmQueue, redisdb snapshotStore) {
+	t := newTelnet(queue, redisdb)
+	telnetSvr := tcp_server.New("127.0.0.1:" + telnetPort)
+	telnetSvr.OnNewClient(func(c *tcp_server.Client) {
+		telnetConnections.Inc()
+		telnetClients.Inc()
+		t.mu.Lock()
+		t.sessions[c] = t.newSession(c, c.Conn().RemoteAddr().String())
+		t.mu.Unlock()
+		c.Send("welcome\n")
+	})
+	telnetSvr.OnClientConnectionClosed(func(c *tcp_server.Client, err error) {
+		telnetClients.Dec()
+		t.mu.Lock()
+		s := t.sessions[c]
+		delete(t.sessions, c)
+		t.mu.Unlock()
+		if s != nil {
+			s.unfollow()
+		}
+	})
+	telnetSvr.OnNewMessage(func(c *tcp_server.Client, message string) {
+		t.mu.Lock()
+		s := t.sessions[c]
+		t.mu.Unlock()
+		t.handle(s, message)
+	})
+	telnetSvr.Listen()
+}
+
+func (t *telnet) newSession(conn telnetConn, remote string) *telnetSession {
+	p, _ := auth.authenticate("")
+	return &telnetSession{conn: conn, remote: remote, principal: p}
+}
+
+// handle runs one line from a client: a /command, or a farm id to queue.
+func (t *telnet) handle(s *telnetSession, message string) {
+	message = strings.TrimRight(message, "\r\n")
+	reqID := newReqID("telnet")
+	req := &telnetRequest{
+		ctx:     withReqID(context.Background(), reqID),
+		session: s,
+		logger:  log.WithFields(log.Fields{"reqID": reqID, "remote": s.remote}),
+	}
+	if len(strings.TrimSpace(message)) == 0 {
+		// empty line, so message[0] is not present :-) issue #1
+		req.reply("/help for help")
+		return
+	}
+
+	if message[0] != '/' {
+		if !authorize(reqID, s.user(), "telnet "+message, roleAdmin) {
+			req.reply("%s needs the %s role (/login <token>)", message, roleAdmin)
+			return
+		}
+		farmID, err := parseFarmInput(message)
+		if err != nil {
+			req.reply("%v (/help for help)", err)
+			return
+		}
+		if err := enqueue(req.ctx, t.queue, laneInteractive, farmID); err != nil {
+			req.reply("cannot queue farm id %s: %v", farmID, err)
+			return
+		}
+		req.reply("queued farm id %s", farmID)
+		return
+	}
+
+	fields := strings.Fields(message)
+	cmd, ok := t.commands[strings.TrimPrefix(fields[0], "/")]
+	if !ok {
+		req.reply("unknown command [%s] (/help for help)", fields[0])
+		return
+	}
+	req.args = fields[1:]
+	req.logger.WithField("command", cmd.name).Debug("telnet command")
+
+	if need := cmd.needs(req.args); !authorize(reqID, s.user(), "telnet "+message, need) {
+		req.reply("%s needs the %s role (/login <token>)", message, need)
+		return
+	}
+	err := cmd.parseArgs(req.args)
+	if err != nil {
+		req.reply("%v", err)
+		return
+	}
+	cmd.run(t, req)
+}
+
+// help lists every command, or describes one.
+func (t *telnet) help(req *telnetRequest) {
+	if name := strings.TrimPrefix(req.arg(0), "/"); name != "" {
+		cmd, ok := t.commands[name]
+		if !ok {
+			req.reply("unknown command [/%s] (/help for help)", name)
+			return
+		}
+		text := fmt.Sprintf("%s - %s\n", cmd.usage(), cmd.description)
+		if cmd.role > roleNone {
+			text += fmt.Sprintf("needs the %s role\n", cmd.role)
+		}
+		if cmd.argsRole > cmd.role {
+			text += fmt.Sprintf("needs the %s role when given arguments\n", cmd.argsRole)
+		}
+		req.reply("%s", text)
+		return
+	}
+
+	names := make([]string, 0, len(t.commands))
+	for name := range t.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	text := "usage:\n1F4Tjc - fetch farm 1F4Tjc if it's a valid id\n"
+	for _, name := range names {
+		cmd := t.commands[name]
+		text += fmt.Sprintf("%s - %s\n", cmd.usage(), cmd.description)
+	}
+	text += "/help <command> for more about a command\n"
+	req.reply("%s", text)
+}
+
+func telnetCommands() []*telnetCommand {
+	page := telnetArg{name: "page", kind: argInt}
+	job := telnetArg{name: "job"}
+	return []*telnetCommand{
+		{
+			name:        "ping",
+			description: "check connection, returns 'pong'",
+			run: func(t *telnet, req *telnetRequest) {
+				req.reply("pong")
+			},
+		},
+		{
+			name:        "help",
+			args:        []telnetArg{{name: "command", optional: true}},
+			description: "list commands, or describe one",
+			run: func(t *telnet, req *telnetRequest) {
+				t.help(req)
+			},
+		},
+		{
+			name:        "login",
+			args:        []telnetArg{{name: "token"}},
+			description: "authenticate with an API token",
+			run: func(t *telnet, req *telnetRequest) {
+				p, err := auth.authenticate(req.arg(0))
+				if err != nil {
+					audit(reqIDFromContext(req.ctx), p, "telnet /login", false)
+					req.reply("login failed")
+					return
+				}
+				req.session.login(p)
+				req.logger.WithFields(log.Fields{"principal": p.Name, "role": p.Role}).Info("telnet login")
+				req.reply("logged in as %s (%s)", p.Name, p.Role)
+			},
+		},
+		{
+			name:        "quit",
+			description: "terminate connection",
+			run: func(t *telnet, req *telnetRequest) {
+				req.session.conn.Close()
+			},
+		},
+		{
+			name:        "loglevel",
+			args:        []telnetArg{{name: "level", optional: true}},
+			description: "show the log level, or change it",
+			role:        roleRead,
+			argsRole:    roleAdmin,
+			run: func(t *telnet, req *telnetRequest) {
+				if level := req.arg(0); level != "" {
+					err := setLogLevel(level)
+					if err != nil {
+						req.reply("cannot set log level: %v", err)
+						return
+					}
+				}
+				req.reply("log level is %s", log.GetLevel())
+			},
+		},
+		{
+			name:        "fetch",
+			description: "fetch latest farm list and process new ones",
+			role:        roleAdmin,
+			run: func(t *telnet, req *telnetRequest) {
+				_, err := fetchRecents(req.ctx, t.queue)
+				if err != nil {
+					req.reply("cannot fetch recent farms: %v", err)
+					return
+				}
+				req.reply("fetched recent farms")
+			},
+		},
+		{
+			name:        "import",
+			args:        []telnetArg{{name: "path"}},
+			description: "queue the farm ids or upload.farm URLs in a text, JSON or CSV file on the server",
+			role:        roleAdmin,
+			run: func(t *telnet, req *telnetRequest) {
+				data, err := ioutil.ReadFile(req.arg(0))
+				if err != nil {
+					req.reply("cannot import: %v", err)
+					return
+				}
+				entries, err := parseImport(data)
+				if err != nil {
+					req.reply("cannot import %s: %v", req.arg(0), err)
+					return
+				}
+				req.reply("importing %d entries from %s", len(entries), req.arg(0))
+
+				// the queue may fill up, so import in the background and
+				// report progress as it goes
+				go func() {
+					shown := 0
+					importFarms(req.ctx, t.queue, entries, func(p importProgress, rejected []rejectedEntry, done bool) {
+						for _, r := range rejected {
+							if shown < telnetImportRejects {
+								req.reply("rejected [%s]: %s", r.Entry, r.Reason)
+							}
+							shown++
+						}
+						if done {
+							if shown > telnetImportRejects {
+								req.reply("... and %d more rejected", shown-telnetImportRejects)
+							}
+							req.reply("import done, %s", p)
+							return
+						}
+						req.reply("import %s", p)
+					})
+				}()
+			},
+		},
+		{
+			name:        "snapshot",
+			args:        []telnetArg{{name: "path"}},
+			description: "write the farms, spidered set and backfill cursors to a compressed snapshot file on the server",
+			role:        roleAdmin,
+			run: func(t *telnet, req *telnetRequest) {
+				f, err := os.Create(req.arg(0))
+				if err != nil {
+					req.reply("cannot snapshot: %v", err)
+					return
+				}
+				counts, err := writeSnapshot(f, t.redisdb)
+				if closeErr := f.Close(); err == nil {
+					err = closeErr
+				}
+				if err != nil {
+					req.reply("cannot snapshot to %s: %v", req.arg(0), err)
+					return
+				}
+				req.reply("wrote snapshot of %s to %s", counts, req.arg(0))
+			},
+		},
+		{
+			name:        "qsize",
+			description: "size of farm-fetching queue, by lane",
+			role:        roleRead,
+			run: func(t *telnet, req *telnetRequest) {
+				req.reply("queue size is [%d]: %v", t.queue.len(), t.queue)
+			},
+		},
+		{
+			name:        "workers",
+			args:        []telnetArg{{name: "n|auto", optional: true}},
+			description: "show what each farm worker is doing; /workers 4 pins the pool at 4 workers, /workers auto lets it autoscale again",
+			role:        roleRead,
+			argsRole:    roleAdmin,
+			run: func(t *telnet, req *telnetRequest) {
+				if workers == nil {
+					req.reply("worker pool not running")
+					return
+				}
+				switch arg := req.arg(0); arg {
+				case "":
+				case "auto":
+					workers.autoscale()
+				default:
+					n, err := strconv.Atoi(arg)
+					if err == nil {
+						err = workers.resize(n)
+					}
+					if err != nil {
+						req.reply("cannot resize workers: %v", err)
+						return
+					}
+				}
+				req.reply("%s", workers)
+			},
+		},
+		{
+			name: "show",
+			args: []telnetArg{
+				{name: "farmID|top", optional: true},
+				{name: "villager", optional: true},
+				{name: "n", kind: argInt, optional: true},
+			},
+			description: "list stored farms; /show 1F4Tjc for one farm's villagers; /show top Abigail 5 for the farms where she's happiest",
+			role:        roleRead,
+			run: func(t *telnet, req *telnetRequest) {
+				switch {
+				case req.arg(0) == "":
+					showFarms(req)
+				case req.arg(0) == "top":
+					showTop(req)
+				case len(req.args) == 1:
+					showFarm(req)
+				default:
+					req.reply("usage: /show [farmID | top <villager> [n]]")
+				}
+			},
+		},
+		{
+			name:        "count",
+			description: "number of stored farms",
+			role:        roleRead,
+			run: func(t *telnet, req *telnetRequest) {
+				allFarms.mu.Lock()
+				n := len(allFarms.stats)
+				allFarms.mu.Unlock()
+				req.reply("%d farms stored", n)
+			},
+		},
+		{
+			name:        "more",
+			description: "show the next page of the last long listing",
+			run: func(t *telnet, req *telnetRequest) {
+				lines := req.session.takeMore()
+				if len(lines) == 0 {
+					req.reply("nothing more to show")
+					return
+				}
+				req.page(lines)
+			},
+		},
+		{
+			name: "tail",
+			args: []telnetArg{
+				{name: "stream", kind: argChoice, choices: []string{"farms", "log"}},
+				{name: "level", optional: true},
+			},
+			description: "follow farms as they are stored, or log lines at level and above, until /untail",
+			role:        roleRead,
+			run: func(t *telnet, req *telnetRequest) {
+				if req.arg(0) == "farms" {
+					req.session.follow("farms", farmEvents, nil, func(event interface{}) string {
+						stats := event.(svStats)
+						maxed, average := farmSummary(stats)
+						return fmt.Sprintf("stored %s: %d maxed, average %.1f\n", stats.FarmID, maxed, average)
+					})
+					req.reply("following stored farms (/untail to stop)")
+					return
+				}
+
+				level := log.InfoLevel
+				if req.arg(1) != "" {
+					var err error
+					level, err = log.ParseLevel(req.arg(1))
+					if err != nil {
+						req.reply("%v (usage: %s)", err, t.commands["tail"].usage())
+						return
+					}
+				}
+				ensureLogTail()
+				req.session.follow("log", logEvents, func(event interface{}) bool {
+					return event.(logEvent).Level <= level
+				}, func(event interface{}) string {
+					return event.(logEvent).Line
+				})
+				req.reply("following %s log lines (/untail to stop)", level)
+			},
+		},
+		{
+			name:        "untail",
+			args:        []telnetArg{{name: "stream", kind: argChoice, choices: []string{"farms", "log"}, optional: true}},
+			description: "stop following farms, the log, or both",
+			run: func(t *telnet, req *telnetRequest) {
+				var stopped []string
+				if req.arg(0) != "" {
+					stopped = req.session.unfollow(req.arg(0))
+				} else {
+					stopped = req.session.unfollow()
+				}
+				if len(stopped) == 0 {
+					req.reply("not following anything")
+					return
+				}
+				req.reply("stopped following %s", strings.Join(stopped, " and "))
+			},
+		},
+		{
+			name:        "spiderstatus",
+			description: "show number of running spiders",
+			role:        roleRead,
+			run: func(t *telnet, req *telnetRequest) {
+				numSpiders, _ := status.running()
+				req.reply("status: %d spiders running", numSpiders)
+			},
+		},
+		{
+			name:        "stopspider",
+			description: "tell \"spiderall\" spiders to stop",
+			role:        roleAdmin,
+			run: func(t *telnet, req *telnetRequest) {
+				req.reply("asked %d spiders to stop", status.requestStop())
+			},
+		},
+		{
+			name:        "spider",
+			args:        []telnetArg{{name: "page", kind: argInt, optional: true}},
+			description: "grab the homepage farms, or one page of historical farms, and add to queue",
+			role:        roleAdmin,
+			run: func(t *telnet, req *telnetRequest) {
+				if req.arg(0) == "" {
+					go fetchMany(req.ctx, t.queue, t.redisdb)
+					req.reply("simple spider of homepage farms")
+					return
+				}
+				go spiderPage(req.ctx, t.queue, t.redisdb, req.intArg(0))
+				req.reply("spidering page %d", req.intArg(0))
+			},
+		},
+		{
+			name:        "spiderall",
+			args:        []telnetArg{page},
+			description: "grab from that page back to page 0 of historical farms & add to known farms list in redis",
+			role:        roleAdmin,
+			run: func(t *telnet, req *telnetRequest) {
+				startSpiderAll(req.ctx, t.redisdb, req.intArg(0))
+				req.reply("spidering from page %d", req.intArg(0))
+			},
+		},
+		{
+			name:        "backfill",
+			args:        []telnetArg{{name: "order", kind: argChoice, choices: []string{"newest"}, optional: true}},
+			description: "queue unprocessed farms from redis, oldest first or newest first",
+			role:        roleAdmin,
+			run: func(t *telnet, req *telnetRequest) {
+				err := backfill.start(req.arg(0) == "newest")
+				if err != nil {
+					req.reply("cannot start backfill: %v", err)
+					return
+				}
+				req.reply("started backfill")
+			},
+		},
+		{
+			name:        "stopbackfill",
+			description: "stop the backfill, keeping its place",
+			role:        roleAdmin,
+			run: func(t *telnet, req *telnetRequest) {
+				if !backfill.halt() {
+					req.reply("backfill is not running")
+					return
+				}
+				req.reply("asked backfill to stop")
+			},
+		},
+		{
+			name:        "backfillstatus",
+			description: "show backfill progress",
+			role:        roleRead,
+			run: func(t *telnet, req *telnetRequest) {
+				req.reply("%s", backfill)
+			},
+		},
+		{
+			name:        "refreshstatus",
+			description: "show how many farms have been re-fetched",
+			role:        roleRead,
+			run: func(t *telnet, req *telnetRequest) {
+				req.reply("%s", refresh)
+			},
+		},
+		{
+			name:        "cachestats",
+			description: "show page cache hit ratio",
+			role:        roleRead,
+			run: func(t *telnet, req *telnetRequest) {
+				req.reply("%s", pages)
+			},
+		},
+		{
+			name:        "imagestats",
+			description: "show farm images downloaded and re-uploads found",
+			role:        roleRead,
+			run: func(t *telnet, req *telnetRequest) {
+				req.reply("%s", images)
+			},
+		},
+		{
+			name:        "jobs",
+			description: "list scheduled jobs",
+			role:        roleRead,
+			run: func(t *telnet, req *telnetRequest) {
+				var lines []string
+				for _, st := range jobs.list() {
+					lines = append(lines, st.String())
+				}
+				if len(lines) == 0 {
+					req.reply("no jobs scheduled")
+					return
+				}
+				req.reply("%s", strings.Join(lines, "\n"))
+			},
+		},
+		{
+			name:        "pause",
+			args:        []telnetArg{job},
+			description: "stop running a job on schedule",
+			role:        roleAdmin,
+			run: func(t *telnet, req *telnetRequest) {
+				if err := jobs.pause(req.arg(0)); err != nil {
+					req.reply("cannot pause: %v", err)
+					return
+				}
+				req.reply("paused %s", req.arg(0))
+			},
+		},
+		{
+			name:        "resume",
+			args:        []telnetArg{job},
+			description: "run a paused job on schedule again",
+			role:        roleAdmin,
+			run: func(t *telnet, req *telnetRequest) {
+				if err := jobs.resume(req.arg(0)); err != nil {
+					req.reply("cannot resume: %v", err)
+					return
+				}
+				req.reply("resumed %s", req.arg(0))
+			},
+		},
+		{
+			name:        "trigger",
+			args:        []telnetArg{job},
+			description: "run a job now",
+			role:        roleAdmin,
+			run: func(t *telnet, req *telnetRequest) {
+				if err := jobs.trigger(req.arg(0)); err != nil {
+					req.reply("cannot trigger: %v", err)
+					return
+				}
+				req.reply("triggered %s", req.arg(0))
+			},
+		},
+	}
+}
+
+// storedFarms copies the stored farms, sorted by id, so they can be
+// formatted and sent without holding the store lock.
+func storedFarms() []svStats {
+	allFarms.mu.Lock()
+	farms := make([]svStats, 0, len(allFarms.stats))
+	for _, stats := range allFarms.stats {
+		farms = append(farms, stats)
+	}
+	allFarms.mu.Unlock()
+	sort.Slice(farms, func(i, j int) bool { return farms[i].FarmID < farms[j].FarmID })
+	return farms
+}
+
+func formatFetched(fetched time.Time) string {
+	if fetched.IsZero() {
+		return "-"
+	}
+	return fetched.Format("2006-01-02 15:04")
+}
+
+// farmSummary counts the villagers at 10/10 and averages their levels.
+func farmSummary(stats svStats) (int, float64) {
+	total, maxed := 0, 0
+	for _, name := range villagers {
+		level := stats.villager(name)
+		total += int(level)
+		if level >= 10 {
+			maxed++
+		}
+	}
+	return maxed, float64(total) / float64(len(villagers))
+}
+
+func showFarms(req *telnetRequest) {
+	farms := storedFarms()
+	if len(farms) == 0 {
+		req.reply("no farms stored")
+		return
+	}
+	var rows [][]string
+	for _, stats := range farms {
+		maxed, average := farmSummary(stats)
+		rows = append(rows, []string{
+			stats.FarmID,
+			formatFetched(stats.Fetched),
+			strconv.Itoa(maxed),
+			fmt.Sprintf("%.1f", average),
+		})
+	}
+	req.page(formatTable([]string{"farm", "fetched", "maxed", "average"}, rows))
+}
+
+func showFarm(req *telnetRequest) {
+	farmID, err := parseFarmInput(req.arg(0))
+	if err != nil {
+		req.reply("%v (usage: /show [farmID | top <villager> [n]])", err)
+		return
+	}
+	allFarms.mu.Lock()
+	stats, ok := allFarms.stats[string(farmID)]
+	allFarms.mu.Unlock()
+	if !ok {
+		req.reply("farm %s not found", farmID)
+		return
+	}
+
+	var rows [][]string
+	for _, name := range villagers {
+		rows = append(rows, []string{name, fmt.Sprintf("%d/10", stats.villager(name))})
+	}
+	lines := []string{fmt.Sprintf("farm %s, fetched %s", farmID, formatFetched(stats.Fetched))}
+	req.page(append(lines, formatTable([]string{"villager", "level"}, rows)...))
+}
+
+func showTop(req *telnetRequest) {
+	villager := ""
+	for _, name := range villagers {
+		if strings.EqualFold(name, req.arg(1)) {
+			villager = name
+		}
+	}
+	if villager == "" {
+		req.reply("unknown villager [%s] (usage: /show top <villager> [n])", req.arg(1))
+		return
+	}
+	n := 10
+	if req.arg(2) != "" {
+		n = req.intArg(2)
+	}
+
+	farms := storedFarms()
+	sort.SliceStable(farms, func(i, j int) bool {
+		return farms[i].villager(villager) > farms[j].villager(villager)
+	})
+	if len(farms) > n {
+		farms = farms[:n]
+	}
+	if len(farms) == 0 {
+		req.reply("no farms stored")
+		return
+	}
+
+	var rows [][]string
+	for i, stats := range farms {
+		rows = append(rows, []string{
+			strconv.Itoa(i + 1),
+			stats.FarmID,
+			fmt.Sprintf("%d/10", stats.villager(villager)),
+		})
+	}
+	req.page(formatTable([]string{"rank", "farm", villager}, rows))
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeTelnetConn records what a command sends back.
+// Tails send from their own goroutines, so it is safe for concurrent use.
+type fakeTelnetConn struct {
+	mu     sync.Mutex
+	sent   []string
+	closed bool
+}
+
+func (c *fakeTelnetConn) Send(message string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, message)
+	return nil
+}
+
+func (c *fakeTelnetConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+// take returns everything sent so far and forgets it.
+func (c *fakeTelnetConn) take() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sent := strings.Join(c.sent, "")
+	c.sent = nil
+	return sent
+}
+
+func TestTelnetCommands(t *testing.T) {
+	Convey("Given a telnet session", t, func() {
+		farmQueue := newFarmQueue(10)
+		tn := newTelnet(farmQueue, nil)
+		queue := farmQueue.lane(laneInteractive)
+		conn := &fakeTelnetConn{}
+		s := tn.newSession(conn, "test")
+		send := func(message string) string {
+			conn.take()
+			tn.handle(s, message+"\r\n")
+			return conn.take()
+		}
+
+		Convey("every command has a description, and help lists them all", func() {
+			help := send("/help")
+			for name, cmd := range tn.commands {
+				So(cmd.description, ShouldNotBeEmpty)
+				So(help, ShouldContainSubstring, "/"+name)
+			}
+			So(send("/help spider"), ShouldEqual, "/spider [page] - grab the homepage farms, or one page of historical farms, and add to queue\nneeds the admin role\n")
+			So(send("/help /loglevel"), ShouldContainSubstring, "needs the admin role when given arguments")
+			So(send("/help nope"), ShouldEqual, "unknown command [/nope] (/help for help)\n")
+		})
+
+		Convey("arguments are checked before a command runs", func() {
+			So(send("/spiderall"), ShouldEqual, "usage: /spiderall <page>\n")
+			So(send("/spiderall x"), ShouldEqual, "page must be a whole number, not [x] (usage: /spiderall <page>)\n")
+			So(send("/spider 3 4"), ShouldEqual, "usage: /spider [page]\n")
+			So(send("/backfill oldest"), ShouldEqual, "order must be one of newest, not [oldest] (usage: /backfill [newest])\n")
+			So(send("/frobnicate"), ShouldEqual, "unknown command [/frobnicate] (/help for help)\n")
+		})
+
+		Convey("/ping and empty lines", func() {
+			So(send("/ping"), ShouldEqual, "pong\n")
+			So(send(""), ShouldEqual, "/help for help\n")
+		})
+
+		Convey("/quit closes the connection", func() {
+			send("/quit")
+			So(conn.closed, ShouldBeTrue)
+		})
+
+		Convey("farm ids are queued", func() {
+			So(send("1AAAAA.extra"), ShouldEqual, "queued farm id 1AAAAA\n")
+			So((<-queue).FarmID, ShouldEqual, "1AAAAA")
+			So(send("2AAAAA"), ShouldEqual, "invalid farm id [2AAAAA]: must start with 1 (/help for help)\n")
+			So(send("1FkeU"), ShouldEqual, "invalid farm id [1FkeU]: must be 6 characters (/help for help)\n")
+		})
+
+		Convey("/import queues the farms in a file", func() {
+			dir, err := ioutil.TempDir("", "import")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "farms.txt")
+			So(ioutil.WriteFile(path, []byte("1AAAAA\nbogus\nhttps://upload.farm/1BBBBB\n"), 0644), ShouldBeNil)
+
+			So(send("/import "+filepath.Join(dir, "missing")), ShouldStartWith, "cannot import: ")
+			So(send("/import "+path), ShouldEqual, "importing 3 entries from "+path+"\n")
+			imported := farmQueue.lane(laneBackfill)
+			So((<-imported).FarmID, ShouldEqual, "1AAAAA")
+			So((<-imported).FarmID, ShouldEqual, "1BBBBB")
+			var sent string
+			for i := 0; i < 100 && !strings.Contains(sent, "import done"); i++ {
+				time.Sleep(10 * time.Millisecond)
+				sent += conn.take()
+			}
+			So(sent, ShouldStartWith, "rejected [bogus]: ")
+			So(sent, ShouldEndWith, "import done, 3/3 entries: 2 queued, 0 duplicates, 1 rejected\n")
+		})
+
+		Convey("/snapshot writes a snapshot file", func() {
+			dir, err := ioutil.TempDir("", "snapshot")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			allFarms.stats = map[string]svStats{"1AAAAA": {FarmID: "1AAAAA"}}
+			allFarms.history = map[string][]svStats{}
+			tracker = newFarmTracker()
+			path := filepath.Join(dir, "farms.json.gz")
+
+			So(send("/snapshot "+path), ShouldEqual, "wrote snapshot of 1 farms (1 versions), 0 failed, 0 spidered, 0 keys to "+path+"\n")
+			f, err := os.Open(path)
+			So(err, ShouldBeNil)
+			defer f.Close()
+			counts, err := restoreSnapshot(f, nil)
+			So(err, ShouldBeNil)
+			So(counts.Farms, ShouldEqual, 1)
+			So(send("/snapshot "+filepath.Join(dir, "missing", "x")), ShouldStartWith, "cannot snapshot: ")
+		})
+
+		Convey("/qsize", func() {
+			queue <- farmRequest{FarmID: "1AAAAA"}
+			So(send("/qsize"), ShouldEqual, "queue size is [1]: interactive 1, recents 0, backfill 0, refresh 0\n")
+		})
+
+		Convey("Given some stored farms", func() {
+			allFarms.stats = map[string]svStats{
+				"1AAAAB": {FarmID: "1AAAAB", Abigail: 3, Alex: 10},
+				"1AAAAA": {FarmID: "1AAAAA", Abigail: 7},
+				"1AAAAC": {FarmID: "1AAAAC", Abigail: 9},
+			}
+
+			Convey("/show lists them in a table", func() {
+				So(send("/show"), ShouldEqual, ""+
+					"farm    fetched  maxed  average\n"+
+					"1AAAAA  -        0      0.2\n"+
+					"1AAAAB  -        1      0.4\n"+
+					"1AAAAC  -        0      0.3\n")
+			})
+
+			Convey("/show <farmID> lists every villager", func() {
+				reply := send("/show 1AAAAB")
+				So(reply, ShouldStartWith, "farm 1AAAAB, fetched -\nvillager   level\nAbigail    3/10\nAlex       10/10\n")
+				So(strings.Count(reply, "\n"), ShouldEqual, telnetPageLines+1)
+				So(reply, ShouldContainSubstring, "more lines, /more to continue --")
+
+				Convey("...and /more pages through the rest", func() {
+					reply := send("/more")
+					So(reply, ShouldStartWith, "Leah ")
+					So(reply, ShouldEndWith, "Wizard     0/10\n")
+					So(send("/more"), ShouldEqual, "nothing more to show\n")
+				})
+				So(send("/show 1ZZZZZ"), ShouldEqual, "farm 1ZZZZZ not found\n")
+				So(send("/show https://upload.farm/1ZZZZZ"), ShouldEqual, "farm 1ZZZZZ not found\n")
+				So(send("/show 1ZZ"), ShouldStartWith, "invalid farm id [1ZZ]: must be 6 characters")
+			})
+
+			Convey("/show top ranks farms by a villager", func() {
+				So(send("/show top abigail 2"), ShouldEqual, ""+
+					"rank  farm    Abigail\n"+
+					"1     1AAAAC  9/10\n"+
+					"2     1AAAAA  7/10\n")
+				So(send("/show top Bob"), ShouldStartWith, "unknown villager [Bob]")
+				So(send("/show top Abigail many"), ShouldStartWith, "n must be a whole number")
+			})
+
+			Convey("/count", func() {
+				So(send("/count"), ShouldEqual, "3 farms stored\n")
+			})
+		})
+
+		Convey("/loglevel", func() {
+			So(send("/loglevel"), ShouldStartWith, "log level is ")
+			So(send("/loglevel loud"), ShouldStartWith, "cannot set log level")
+		})
+
+		Convey("spider commands", func() {
+			// the listing answers once released, so the spider is running
+			// until then
+			release := make(chan struct{})
+			listing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-release
+			}))
+			defer listing.Close()
+			defer func() {
+				select {
+				case <-release:
+				default:
+					close(release)
+				}
+			}()
+			farmBaseURL = listing.URL
+			defer func() { farmBaseURL = "https://upload.farm" }()
+			tn.redisdb = newFakeSpidered()
+
+			So(send("/stopspider"), ShouldEqual, "asked 0 spiders to stop\n")
+			So(send("/spiderall 2"), ShouldEqual, "spidering from page 2\n")
+			So(send("/spiderstatus"), ShouldEqual, "status: 1 spiders running\n")
+			So(send("/stopspider"), ShouldEqual, "asked 1 spiders to stop\n")
+			close(release)
+			for i := 0; i < 100 && send("/spiderstatus") != "status: 0 spiders running\n"; i++ {
+				time.Sleep(10 * time.Millisecond)
+			}
+			So(send("/spiderstatus"), ShouldEqual, "status: 0 spiders running\n")
+			_, stop := status.running()
+			So(stop, ShouldBeFalse)
+		})
+
+		Convey("backfill commands", func() {
+			backfill = newBackfiller(newFakeSpidered(), farmQueue)
+			So(send("/stopbackfill"), ShouldEqual, "backfill is not running\n")
+			So(send("/backfillstatus"), ShouldStartWith, "backfill idle (oldest first)")
+		})
+
+		Convey("status commands", func() {
+			refresh = newRefresher(farmQueue)
+			So(send("/refreshstatus"), ShouldStartWith, "refresh: 0 farms re-fetched")
+			So(send("/cachestats"), ShouldEqual, "page cache disabled\n")
+			So(send("/imagestats"), ShouldEqual, "farm images disabled\n")
+		})
+
+		Convey("/workers shows and resizes the pool", func() {
+			pool, _ := newWorkerPool(farmQueue, make(chan farmResult, 1), workersConfig{Min: 1, Max: 2})
+			workers = pool
+			defer func() {
+				pool.mu.Lock()
+				pool.resizeLocked(0)
+				pool.mu.Unlock()
+				workers = nil
+			}()
+			So(send("/workers 3"), ShouldStartWith, "3 workers (pinned), 0 farms in the last minute\nworker 1: idle, 0 processed")
+			So(send("/workers many"), ShouldStartWith, "cannot resize workers: ")
+			So(send("/workers auto"), ShouldStartWith, "2 workers (autoscaling 1-2)")
+			So(send("/workers"), ShouldStartWith, "2 workers (autoscaling 1-2)")
+		})
+
+		Convey("/tail and /untail", func() {
+			// received waits for a tail's goroutine to send something.
+			received := func() string {
+				for i := 0; i < 100; i++ {
+					if sent := conn.take(); sent != "" {
+						return sent
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+				return ""
+			}
+			So(send("/untail"), ShouldEqual, "not following anything\n")
+			So(send("/tail"), ShouldEqual, "usage: /tail <farms|log> [level]\n")
+
+			So(send("/tail farms"), ShouldEqual, "following stored farms (/untail to stop)\n")
+			farmEvents.publish(svStats{FarmID: "1AAAAA", Abigail: 10})
+			So(received(), ShouldStartWith, "stored 1AAAAA: 1 maxed, average ")
+
+			So(send("/tail log bogus"), ShouldStartWith, "not a valid logrus Level")
+			So(send("/tail log warn"), ShouldEqual, "following warning log lines (/untail to stop)\n")
+			log.Info("not tailed")
+			log.Warn("tailed")
+			line := received()
+			So(line, ShouldContainSubstring, "msg=tailed")
+			So(line, ShouldNotContainSubstring, "not tailed")
+
+			So(send("/untail log"), ShouldEqual, "stopped following log\n")
+			So(send("/untail"), ShouldEqual, "stopped following farms\n")
+			farmEvents.publish(svStats{FarmID: "1AAAAB"})
+			time.Sleep(20 * time.Millisecond)
+			So(conn.take(), ShouldBeEmpty)
+		})
+
+		Convey("job commands", func() {
+			jobs = newScheduler()
+			So(send("/jobs"), ShouldEqual, "no jobs scheduled\n")
+			So(send("/pause recents"), ShouldStartWith, "cannot pause: ")
+			So(send("/resume recents"), ShouldStartWith, "cannot resume: ")
+			So(send("/trigger recents"), ShouldStartWith, "cannot trigger: ")
+		})
+	})
+}
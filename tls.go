@@ -0,0 +1,196 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultCertCheck = 30 * time.Second
+
+// tlsConfig turns on TLS for a server. With ClientCA set, clients must also
+// present a certificate signed by it. The files are re-read when they change,
+// so certificates can be renewed without a restart.
+type tlsConfig struct {
+	Cert     string `json:"cert"`
+	Key      string `json:"key"`
+	ClientCA string `json:"clientCA"`
+}
+
+func (c tlsConfig) enabled() bool {
+	return c.Cert != "" || c.Key != ""
+}
+
+// serversTLS configures TLS separately for the gRPC and HTTP servers.
+type serversTLS struct {
+	GRPC tlsConfig `json:"grpc"`
+	HTTP tlsConfig `json:"http"`
+}
+
+// certReloader holds the current certificate and client CA pool, reloading
+// them when the files' modification times change.
+type certReloader struct {
+	cfg tlsConfig
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	clients *x509.CertPool
+	modTime time.Time
+}
+
+func newCertReloader(cfg tlsConfig) (*certReloader, error) {
+	if cfg.Cert == "" || cfg.Key == "" {
+		return nil, fmt.Errorf("tls needs both a cert and a key")
+	}
+	r := &certReloader{cfg: cfg}
+	_, err := r.reload()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// latestModTime is the newest modification time of the configured files.
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.cfg.Cert, r.cfg.Key, r.cfg.ClientCA} {
+		if path == "" {
+			continue
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return latest, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// reload reads the files again if any changed, reporting whether it did.
+// On error the previous certificate stays in use.
+func (r *certReloader) reload() (bool, error) {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	unchanged := r.cert != nil && modTime.Equal(r.modTime)
+	r.mu.Unlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.Cert, r.cfg.Key)
+	if err != nil {
+		return false, err
+	}
+	var clients *x509.CertPool
+	if r.cfg.ClientCA != "" {
+		pem, err := ioutil.ReadFile(r.cfg.ClientCA)
+		if err != nil {
+			return false, err
+		}
+		clients = x509.NewCertPool()
+		if !clients.AppendCertsFromPEM(pem) {
+			return false, fmt.Errorf("no certificates found in %s", r.cfg.ClientCA)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.clients, r.modTime = &cert, clients, modTime
+	r.mu.Unlock()
+	return true, nil
+}
+
+// watch checks for new files every interval until stop is closed.
+func (r *certReloader) watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		reloaded, err := r.reload()
+		if err != nil {
+			log.WithError(err).Warnf("cannot reload certificate %s", r.cfg.Cert)
+			continue
+		}
+		if reloaded {
+			log.Infof("reloaded certificate %s", r.cfg.Cert)
+		}
+	}
+}
+
+// serverConfig is a TLS config that picks up reloaded certificates on each
+// new connection. Only the certificate and client check change, so the
+// servers are free to set NextProtos and negotiate HTTP/2.
+func (r *certReloader) serverConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			return r.cert, nil
+		},
+	}
+	if r.cfg.ClientCA != "" {
+		// crypto/tls would only check clients against a fixed pool, so
+		// verify them ourselves against the current one
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyPeerCertificate = r.verifyClient
+	}
+	return cfg
+}
+
+// verifyClient checks a client's certificate chain against the client CA.
+func (r *certReloader) verifyClient(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	var certs []*x509.Certificate
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return fmt.Errorf("no client certificate")
+	}
+	r.mu.Lock()
+	roots := r.clients
+	r.mu.Unlock()
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(opts)
+	return err
+}
+
+// setupTLS loads the certificates for a server and starts watching them,
+// returning nil when TLS is not configured.
+func setupTLS(name string, cfg tlsConfig) (*tls.Config, error) {
+	if !cfg.enabled() {
+		return nil, nil
+	}
+	r, err := newCertReloader(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s tls: %v", name, err)
+	}
+	go r.watch(defaultCertCheck, nil)
+	log.WithField("mtls", cfg.ClientCA != "").Infof("%s server using tls", name)
+	return r.serverConfig(), nil
+}
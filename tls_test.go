@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+var certSerial int64
+
+// issue makes a certificate for cn signed by ca, or self-signed when ca is
+// nil, and writes it and its key as PEM files in dir.
+func issue(dir, cn string, ca *testCA, isCA bool) (*testCA, string, string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	certSerial++
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(certSerial),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	parent, signer := tmpl, key
+	if ca != nil {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	So(err, ShouldBeNil)
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certFile := filepath.Join(dir, cn+".crt")
+	keyFile := filepath.Join(dir, cn+".key")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}, certFile, keyFile
+}
+
+// negotiated is the application protocol a server picks for a client
+// offering HTTP/2 and HTTP/1.1.
+func negotiated(addr string, cfg *tls.Config) string {
+	cfg.NextProtos = []string{"h2", "http/1.1"}
+	conn, err := tls.Dial("tcp", addr, cfg)
+	So(err, ShouldBeNil)
+	defer conn.Close()
+	return conn.ConnectionState().NegotiatedProtocol
+}
+
+func TestTLS(t *testing.T) {
+	Convey("Given a CA with server and client certificates", t, func() {
+		dir, _ := ioutil.TempDir("", "farmstats-tls")
+		defer os.RemoveAll(dir)
+		ca, caFile, _ := issue(dir, "ca", nil, true)
+		_, serverCert, serverKey := issue(dir, "server", ca, false)
+		_, clientCert, clientKey := issue(dir, "client", ca, false)
+
+		_, err := newCertReloader(tlsConfig{Cert: serverCert})
+		So(err, ShouldNotBeNil)
+
+		r, err := newCertReloader(tlsConfig{Cert: serverCert, Key: serverKey, ClientCA: caFile})
+		So(err, ShouldBeNil)
+
+		Convey("gRPC only accepts clients with a certificate from the CA", func() {
+			lis, _ := net.Listen("tcp", "127.0.0.1:0")
+			srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(r.serverConfig())))
+			healthpb.RegisterHealthServer(srv, health.NewServer())
+			go srv.Serve(lis)
+			defer srv.Stop()
+
+			check := func(cfg *tls.Config) error {
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				defer cancel()
+				conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+				return err
+			}
+
+			So(check(&tls.Config{RootCAs: ca.pool}), ShouldNotBeNil)
+
+			_, strangerCert, strangerKey := issue(dir, "stranger", nil, false)
+			stranger, err := tls.LoadX509KeyPair(strangerCert, strangerKey)
+			So(err, ShouldBeNil)
+			So(check(&tls.Config{RootCAs: ca.pool, Certificates: []tls.Certificate{stranger}}), ShouldNotBeNil)
+
+			cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+			So(err, ShouldBeNil)
+			So(check(&tls.Config{RootCAs: ca.pool, Certificates: []tls.Certificate{cert}}), ShouldBeNil)
+			So(negotiated(lis.Addr().String(), &tls.Config{RootCAs: ca.pool, Certificates: []tls.Certificate{cert}}), ShouldEqual, "h2")
+		})
+
+		Convey("HTTPS negotiates HTTP/2", func() {
+			lis, _ := net.Listen("tcp", "127.0.0.1:0")
+			srv := &http.Server{Handler: http.NotFoundHandler(), TLSConfig: r.serverConfig()}
+			go srv.ServeTLS(lis, "", "")
+			defer srv.Close()
+
+			cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+			So(err, ShouldBeNil)
+			So(negotiated(lis.Addr().String(), &tls.Config{RootCAs: ca.pool, Certificates: []tls.Certificate{cert}}), ShouldEqual, "h2")
+		})
+
+		Convey("a renewed certificate is used without a restart", func() {
+			r, err := newCertReloader(tlsConfig{Cert: serverCert, Key: serverKey})
+			So(err, ShouldBeNil)
+			lis, _ := net.Listen("tcp", "127.0.0.1:0")
+			go http.Serve(tls.NewListener(lis, r.serverConfig()), http.NotFoundHandler())
+			defer lis.Close()
+
+			servedCN := func() string {
+				conn, err := tls.Dial("tcp", lis.Addr().String(), &tls.Config{RootCAs: ca.pool})
+				So(err, ShouldBeNil)
+				defer conn.Close()
+				return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+			}
+			So(servedCN(), ShouldEqual, "server")
+
+			reloaded, err := r.reload()
+			So(err, ShouldBeNil)
+			So(reloaded, ShouldBeFalse)
+
+			_, renewedCert, renewedKey := issue(dir, "renewed", ca, false)
+			os.Rename(renewedCert, serverCert)
+			os.Rename(renewedKey, serverKey)
+			later := time.Now().Add(time.Minute)
+			os.Chtimes(serverCert, later, later)
+
+			reloaded, err = r.reload()
+			So(err, ShouldBeNil)
+			So(reloaded, ShouldBeTrue)
+			So(servedCN(), ShouldEqual, "renewed")
+		})
+	})
+}
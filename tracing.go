@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	traceparentHeader   = "traceparent"
+	defaultTraceBatch   = 100
+	defaultTraceFlush   = 5 * time.Second
+	defaultTraceBacklog = 1000
+	// defaultTraceTimeout bounds each post to the collector, so one that
+	// hangs cannot hold up flushing.
+	defaultTraceTimeout = 10 * time.Second
+)
+
+// traceConfig picks where finished spans go: "stdout", "file" (File) or
+// "otlp" (an OTLP/HTTP JSON Endpoint such as http://collector:4318/v1/traces).
+// An empty Exporter turns tracing off.
+type traceConfig struct {
+	Exporter string `json:"exporter"`
+	File     string `json:"file"`
+	Endpoint string `json:"endpoint"`
+}
+
+// spanContext identifies a span so children, possibly in another process,
+// can point at it.
+type spanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+func (sc spanContext) valid() bool {
+	return len(sc.TraceID) == 32 && len(sc.SpanID) == 16
+}
+
+// traceparent formats sc as a W3C traceparent header.
+func (sc spanContext) traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", sc.TraceID, sc.SpanID)
+}
+
+func parseTraceparent(header string) (spanContext, bool) {
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return spanContext{}, false
+	}
+	sc := spanContext{TraceID: parts[1], SpanID: parts[2]}
+	for _, id := range []string{sc.TraceID, sc.SpanID} {
+		if _, err := hex.DecodeString(id); err != nil {
+			return spanContext{}, false
+		}
+	}
+	return sc, sc.valid()
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type span struct {
+	Name     string            `json:"name"`
+	TraceID  string            `json:"traceID"`
+	SpanID   string            `json:"spanID"`
+	ParentID string            `json:"parentID,omitempty"`
+	Start    time.Time         `json:"start"`
+	End      time.Time         `json:"end"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+type spanKey struct{}
+
+func spanFromContext(ctx context.Context) spanContext {
+	sc, _ := ctx.Value(spanKey{}).(spanContext)
+	return sc
+}
+
+func contextWithSpan(ctx context.Context, sc spanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// startSpan begins a span as a child of any span in ctx, or a new trace.
+func startSpan(ctx context.Context, name string) (context.Context, *span) {
+	return startSpanAt(ctx, name, time.Now())
+}
+
+func startSpanAt(ctx context.Context, name string, start time.Time) (context.Context, *span) {
+	parent := spanFromContext(ctx)
+	s := &span{
+		Name:     name,
+		TraceID:  parent.TraceID,
+		SpanID:   randomID(8),
+		ParentID: parent.SpanID,
+		Start:    start,
+	}
+	if !parent.valid() {
+		s.TraceID = randomID(16)
+		s.ParentID = ""
+	}
+	return contextWithSpan(ctx, s.context()), s
+}
+
+func (s *span) context() spanContext {
+	return spanContext{TraceID: s.TraceID, SpanID: s.SpanID}
+}
+
+func (s *span) setAttr(key, value string) {
+	if s.Attrs == nil {
+		s.Attrs = make(map[string]string)
+	}
+	s.Attrs[key] = value
+}
+
+// finish ends the span, recording err if there was one, and hands it to the
+// exporter.
+func (s *span) finish(err error) {
+	s.End = time.Now()
+	if err != nil {
+		s.Error = err.Error()
+	}
+	tracer.record(s)
+}
+
+type spanExporter interface {
+	export(spans []*span) error
+}
+
+// writerExporter writes one JSON span per line.
+type writerExporter struct {
+	w io.Writer
+}
+
+func (e *writerExporter) export(spans []*span) error {
+	enc := json.NewEncoder(e.w)
+	for _, s := range spans {
+		err := enc.Encode(s)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// otlpExporter posts spans to an OpenTelemetry collector using OTLP/HTTP
+// with JSON encoding.
+type otlpExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newOTLPExporter(endpoint string) *otlpExporter {
+	return &otlpExporter{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: defaultTraceTimeout},
+	}
+}
+
+type otlpAttr struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+func otlpAttrs(attrs map[string]string) []otlpAttr {
+	var out []otlpAttr
+	for k, v := range attrs {
+		a := otlpAttr{Key: k}
+		a.Value.StringValue = v
+		out = append(out, a)
+	}
+	return out
+}
+
+func (e *otlpExporter) export(spans []*span) error {
+	type otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	type otlpSpan struct {
+		TraceID           string     `json:"traceId"`
+		SpanID            string     `json:"spanId"`
+		ParentSpanID      string     `json:"parentSpanId,omitempty"`
+		Name              string     `json:"name"`
+		Kind              int        `json:"kind"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+		Attributes        []otlpAttr `json:"attributes,omitempty"`
+		Status            otlpStatus `json:"status"`
+	}
+
+	var out []otlpSpan
+	for _, s := range spans {
+		o := otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.SpanID,
+			ParentSpanID:      s.ParentID,
+			Name:              s.Name,
+			Kind:              1,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        otlpAttrs(s.Attrs),
+			Status:            otlpStatus{Code: 1},
+		}
+		if s.Error != "" {
+			o.Status = otlpStatus{Code: 2, Message: s.Error}
+		}
+		out = append(out, o)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": otlpAttrs(map[string]string{"service.name": "farmstats"}),
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": "farmstats"},
+				"spans": out,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("collector returned %s", res.Status)
+	}
+	return nil
+}
+
+// spanTracer batches finished spans and sends them to the exporter in the
+// background. With no exporter, spans are still created, so IDs propagate,
+// but are thrown away when finished.
+type spanTracer struct {
+	exporter spanExporter
+	spans    chan *span
+
+	mu      sync.Mutex
+	dropped int
+}
+
+var tracer = &spanTracer{}
+
+func newTracer(exporter spanExporter) *spanTracer {
+	return &spanTracer{
+		exporter: exporter,
+		spans:    make(chan *span, defaultTraceBacklog),
+	}
+}
+
+func setupTracing(cfg traceConfig) error {
+	var exporter spanExporter
+	switch cfg.Exporter {
+	case "":
+		return nil
+	case "stdout":
+		exporter = &writerExporter{w: os.Stdout}
+	case "file":
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		exporter = &writerExporter{w: f}
+	case "otlp":
+		if cfg.Endpoint == "" {
+			return fmt.Errorf("otlp exporter needs an endpoint")
+		}
+		exporter = newOTLPExporter(cfg.Endpoint)
+	default:
+		return fmt.Errorf("unknown trace exporter [%s]", cfg.Exporter)
+	}
+	tracer = newTracer(exporter)
+	go tracer.run()
+	return nil
+}
+
+func (t *spanTracer) record(s *span) {
+	if t.exporter == nil {
+		return
+	}
+	select {
+	case t.spans <- s:
+	default:
+		t.mu.Lock()
+		t.dropped++
+		t.mu.Unlock()
+	}
+}
+
+// flush exports every span waiting to be sent.
+func (t *spanTracer) flush() {
+	var batch []*span
+	for {
+		select {
+		case s := <-t.spans:
+			batch = append(batch, s)
+			if len(batch) < defaultTraceBatch {
+				continue
+			}
+		default:
+		}
+		if len(batch) == 0 {
+			return
+		}
+		err := t.exporter.export(batch)
+		if err != nil {
+			log.Warnf("could not export %d spans: %v", len(batch), err)
+		}
+		batch = nil
+	}
+}
+
+func (t *spanTracer) run() {
+	for range time.Tick(defaultTraceFlush) {
+		t.flush()
+	}
+}
+
+// grpcTracing continues a trace from the client's traceparent metadata.
+func grpcTracing(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, s := startGRPCSpan(ctx, info.FullMethod)
+	grpc.SetHeader(ctx, metadata.Pairs(traceparentHeader, s.context().traceparent()))
+	res, err := handler(ctx, req)
+	s.finish(err)
+	return res, err
+}
+
+// grpcStreamTracing is grpcTracing for streaming calls: the span lasts as
+// long as the stream.
+func grpcStreamTracing(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, s := startGRPCSpan(ss.Context(), info.FullMethod)
+	ss.SetHeader(metadata.Pairs(traceparentHeader, s.context().traceparent()))
+	err := handler(srv, withStreamContext(ss, ctx))
+	s.finish(err)
+	return err
+}
+
+// startGRPCSpan starts the server span for a call, continuing the trace in
+// the caller's traceparent metadata.
+func startGRPCSpan(ctx context.Context, method string) (context.Context, *span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(traceparentHeader); len(values) > 0 {
+			if sc, ok := parseTraceparent(values[0]); ok {
+				ctx = contextWithSpan(ctx, sc)
+			}
+		}
+	}
+	ctx, s := startSpan(ctx, method)
+	s.setAttr("reqID", reqIDFromContext(ctx))
+	return ctx, s
+}
+
+// httpTracing continues a trace from the traceparent header and returns
+// the server span's traceparent to the caller.
+func httpTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if sc, ok := parseTraceparent(r.Header.Get(traceparentHeader)); ok {
+			ctx = contextWithSpan(ctx, sc)
+		}
+		ctx, s := startSpan(ctx, r.Method+" "+r.URL.Path)
+		s.setAttr("reqID", reqIDFromContext(ctx))
+		w.Header().Set(traceparentHeader, s.context().traceparent())
+		next.ServeHTTP(w, r.WithContext(ctx))
+		if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+			s.Name = r.Method + " " + rctx.RoutePattern()
+		}
+		s.finish(nil)
+	})
+}
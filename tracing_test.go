@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// memExporter keeps exported spans for inspection.
+type memExporter struct {
+	mu    sync.Mutex
+	spans []*span
+}
+
+func (e *memExporter) export(spans []*span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *memExporter) named(name string) *span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range e.spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestTracing(t *testing.T) {
+	Convey("traceparent headers round trip", t, func() {
+		_, s := startSpan(context.Background(), "root")
+		sc, ok := parseTraceparent(s.context().traceparent())
+		So(ok, ShouldBeTrue)
+		So(sc, ShouldResemble, s.context())
+
+		_, ok = parseTraceparent("00-nothex-0000000000000001-01")
+		So(ok, ShouldBeFalse)
+		_, ok = parseTraceparent("")
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("Given a tracer exporting to memory", t, func() {
+		exporter := &memExporter{}
+		tracer = newTracer(exporter)
+		defer func() { tracer = &spanTracer{} }()
+
+		Convey("a farm's enqueue, dequeue, fetch, parse and store spans share a trace", func() {
+			abigail := 4
+			srv := farmPage(&abigail)
+			defer srv.Close()
+			farmBaseURL = srv.URL
+			defer func() { farmBaseURL = "https://upload.farm" }()
+			allFarms.stats = map[string]svStats{}
+
+			ctx, root := startSpan(withReqID(context.Background(), "test-1"), "test")
+			queue := newFarmQueue(1)
+			statsQueue := make(chan farmResult, 1)
+			enqueue(ctx, queue, laneInteractive, "1AAAAA")
+			req, _ := queue.pop(nil)
+			processFarmID(context.Background(), req, statsQueue)
+			result := <-statsQueue
+			_, store := startSpan(contextWithSpan(context.Background(), result.Trace), "store")
+			store.finish(nil)
+			root.finish(nil)
+			tracer.flush()
+
+			enq := exporter.named("enqueue")
+			So(enq, ShouldNotBeNil)
+			So(enq.ParentID, ShouldEqual, root.SpanID)
+			process := exporter.named("process")
+			So(exporter.named("dequeue").ParentID, ShouldEqual, enq.SpanID)
+			So(process.ParentID, ShouldEqual, enq.SpanID)
+			So(exporter.named("fetch").ParentID, ShouldEqual, process.SpanID)
+			So(exporter.named("parse").ParentID, ShouldEqual, process.SpanID)
+			So(store.ParentID, ShouldEqual, process.SpanID)
+			for _, s := range exporter.spans {
+				So(s.TraceID, ShouldEqual, root.TraceID)
+			}
+		})
+
+		Convey("gRPC calls continue the caller's trace", func() {
+			_, parent := startSpan(context.Background(), "client")
+			ctx := metadata.NewIncomingContext(context.Background(),
+				metadata.Pairs(traceparentHeader, parent.context().traceparent()))
+			info := &grpc.UnaryServerInfo{FullMethod: "/farmstats.FarmStats/GetStats"}
+			grpcTracing(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			})
+			tracer.flush()
+
+			s := exporter.named(info.FullMethod)
+			So(s, ShouldNotBeNil)
+			So(s.TraceID, ShouldEqual, parent.TraceID)
+			So(s.ParentID, ShouldEqual, parent.SpanID)
+		})
+
+		Convey("HTTP requests continue the caller's trace and return their own", func() {
+			_, parent := startSpan(context.Background(), "client")
+			h := httpTracing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			r := httptest.NewRequest("GET", "/healthz", nil)
+			r.Header.Set(traceparentHeader, parent.context().traceparent())
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			tracer.flush()
+
+			s := exporter.named("GET /healthz")
+			So(s, ShouldNotBeNil)
+			So(s.ParentID, ShouldEqual, parent.SpanID)
+			So(w.Header().Get(traceparentHeader), ShouldEqual, s.context().traceparent())
+		})
+	})
+
+	Convey("Spans are posted to an OTLP collector", t, func() {
+		received := make(chan map[string]interface{}, 1)
+		collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			var payload map[string]interface{}
+			json.Unmarshal(body, &payload)
+			received <- payload
+		}))
+		defer collector.Close()
+
+		So(setupTracing(traceConfig{Exporter: "otlp"}), ShouldNotBeNil)
+		So(setupTracing(traceConfig{Exporter: "zipkin"}), ShouldNotBeNil)
+
+		tracer = newTracer(newOTLPExporter(collector.URL + "/v1/traces"))
+		defer func() { tracer = &spanTracer{} }()
+		_, s := startSpan(context.Background(), "fetch")
+		s.setAttr("url", "https://upload.farm/1AAAAA")
+		s.finish(nil)
+		tracer.flush()
+
+		var payload map[string]interface{}
+		select {
+		case payload = <-received:
+		case <-time.After(time.Second):
+		}
+		So(payload, ShouldNotBeNil)
+		rs := payload["resourceSpans"].([]interface{})[0].(map[string]interface{})
+		spans := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+		So(len(spans), ShouldEqual, 1)
+		got := spans[0].(map[string]interface{})
+		So(got["name"], ShouldEqual, "fetch")
+		So(got["traceId"], ShouldEqual, s.TraceID)
+	})
+
+	Convey("A collector that hangs is given up on", t, func() {
+		release := make(chan struct{})
+		collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer collector.Close()
+		defer close(release)
+
+		e := newOTLPExporter(collector.URL)
+		e.client.Timeout = 20 * time.Millisecond
+		start := time.Now()
+		So(e.export([]*span{{Name: "fetch", Start: start, End: start}}), ShouldNotBeNil)
+		So(time.Since(start), ShouldBeLessThan, time.Second)
+	})
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultMinWorkers = 2
+	defaultMaxWorkers = 8
+	// maxWorkers caps any pool size, so a typo cannot flood upload.farm.
+	maxWorkers           = 64
+	defaultScaleInterval = 5 * time.Second
+	// defaultSlowUpstream is the average scrape time above which the pool
+	// shrinks rather than adding load to a struggling upload.farm.
+	defaultSlowUpstream = 5 * time.Second
+	throughputWindow    = time.Minute
+)
+
+// workersConfig bounds the farm worker pool. The pool starts at Min and
+// grows towards Max while farms are waiting.
+type workersConfig struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// workerState is what a farm worker is doing.
+type workerState int
+
+const (
+	workerIdle workerState = iota
+	workerFetching
+	workerParsing
+)
+
+var workerStateNames = [...]string{"idle", "fetching", "parsing"}
+
+func (s workerState) String() string {
+	return workerStateNames[s]
+}
+
+// worker is one goroutine taking farms from the queue.
+type worker struct {
+	id      int
+	stop    chan struct{}
+	started time.Time
+
+	mu        sync.Mutex
+	state     workerState
+	farmID    FarmID
+	since     time.Time
+	processed int
+	failed    int
+}
+
+// workerInfo is a copy of a worker's state for reporting.
+type workerInfo struct {
+	ID        int
+	State     workerState
+	FarmID    FarmID
+	Since     time.Time
+	Processed int
+	Failed    int
+	// PerMinute is the farms processed per minute since the worker started.
+	PerMinute float64
+}
+
+func (w *worker) setState(state workerState, farmID FarmID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.state = state
+	w.farmID = farmID
+	w.since = time.Now()
+}
+
+// finished records a farm and goes back to idle.
+func (w *worker) finished(err error) {
+	w.mu.Lock()
+	if err != nil {
+		w.failed++
+	} else {
+		w.processed++
+	}
+	w.mu.Unlock()
+	w.setState(workerIdle, "")
+}
+
+func (w *worker) info() workerInfo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	info := workerInfo{
+		ID:        w.id,
+		State:     w.state,
+		FarmID:    w.farmID,
+		Since:     w.since,
+		Processed: w.processed,
+		Failed:    w.failed,
+	}
+	if minutes := time.Since(w.started).Minutes(); minutes > 0 {
+		info.PerMinute = float64(w.processed) / minutes
+	}
+	return info
+}
+
+func (i workerInfo) String() string {
+	s := fmt.Sprintf("worker %d: %s", i.ID, i.State)
+	if i.FarmID != "" {
+		s += fmt.Sprintf(" %s for %v", i.FarmID, time.Since(i.Since).Round(time.Millisecond))
+	}
+	return s + fmt.Sprintf(", %d processed, %d failed, %.1f/min", i.Processed, i.Failed, i.PerMinute)
+}
+
+type workerKey struct{}
+
+// withWorker lets the code processing a farm report the worker's state.
+func withWorker(ctx context.Context, w *worker) context.Context {
+	return context.WithValue(ctx, workerKey{}, w)
+}
+
+// setWorkerState updates the state of the worker processing ctx, if any.
+func setWorkerState(ctx context.Context, state workerState, farmID FarmID) {
+	if w, ok := ctx.Value(workerKey{}).(*worker); ok {
+		w.setState(state, farmID)
+	}
+}
+
+// workerPool runs the goroutines that scrape queued farms. Unless its size
+// is pinned, it adds a worker while every worker is busy and farms are
+// waiting, and drops one when workers sit idle or upload.farm slows down,
+// staying between min and max.
+type workerPool struct {
+	queue        *farmQueue
+	statsQueue   chan farmResult
+	min, max     int
+	interval     time.Duration
+	slowUpstream time.Duration
+
+	mu      sync.Mutex
+	workers []*worker
+	nextID  int
+	pinned  bool
+	recent  []time.Time
+}
+
+func newWorkerPool(queue *farmQueue, statsQueue chan farmResult, cfg workersConfig) (*workerPool, error) {
+	if cfg.Min < 1 || cfg.Max < cfg.Min || cfg.Max > maxWorkers {
+		return nil, fmt.Errorf("workers: need 1 <= min <= max <= %d, got min %d, max %d", maxWorkers, cfg.Min, cfg.Max)
+	}
+	return &workerPool{
+		queue:        queue,
+		statsQueue:   statsQueue,
+		min:          cfg.Min,
+		max:          cfg.Max,
+		interval:     defaultScaleInterval,
+		slowUpstream: defaultSlowUpstream,
+	}, nil
+}
+
+// start runs min workers and scales them until stop is closed.
+func (p *workerPool) start(stop <-chan struct{}) {
+	p.mu.Lock()
+	p.resizeLocked(p.min)
+	p.mu.Unlock()
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				p.mu.Lock()
+				p.resizeLocked(0)
+				p.mu.Unlock()
+				return
+			case <-ticker.C:
+				p.scale()
+			}
+		}
+	}()
+}
+
+// size is the number of workers running.
+func (p *workerPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.workers)
+}
+
+// bounds returns the configured size range and whether the size is pinned.
+func (p *workerPool) bounds() (int, int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.min, p.max, p.pinned
+}
+
+// resize pins the pool at n workers until autoscale is called.
+func (p *workerPool) resize(n int) error {
+	if n < 1 || n > maxWorkers {
+		return fmt.Errorf("pool size must be between 1 and %d", maxWorkers)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pinned = true
+	p.resizeLocked(n)
+	p.expectLocked(n)
+	log.Infof("worker pool pinned at %d", n)
+	return nil
+}
+
+// autoscale lets the pool size itself again.
+func (p *workerPool) autoscale() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pinned = false
+	switch {
+	case len(p.workers) < p.min:
+		p.resizeLocked(p.min)
+	case len(p.workers) > p.max:
+		p.resizeLocked(p.max)
+	}
+	p.expectLocked(p.min)
+	log.Info("worker pool autoscaling")
+}
+
+// scale moves the pool one worker towards the size it wants.
+func (p *workerPool) scale() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pinned {
+		return
+	}
+	busy := 0
+	for _, w := range p.workers {
+		if w.info().State != workerIdle {
+			busy++
+		}
+	}
+	want := p.desired(p.queue.len(), busy, tracker.average())
+	if want != len(p.workers) {
+		log.Debugf("scaling worker pool from %d to %d", len(p.workers), want)
+		p.resizeLocked(want)
+	}
+}
+
+// desired is the pool size to move to, given the farms waiting, the
+// workers busy and the average scrape time.
+func (p *workerPool) desired(depth, busy int, latency time.Duration) int {
+	size := len(p.workers)
+	want := size
+	switch {
+	case latency > p.slowUpstream:
+		want = size - 1
+	case depth > 0 && busy >= size:
+		want = size + 1
+	case depth == 0 && busy < size-1:
+		want = size - 1
+	}
+	if want < p.min {
+		want = p.min
+	}
+	if want > p.max {
+		want = p.max
+	}
+	return want
+}
+
+// expectLocked tells the health checker the fewest workers that should be
+// running, plus the stats consumer.
+func (p *workerPool) expectLocked(n int) {
+	if probes != nil {
+		probes.expectWorkers(n + 1)
+	}
+}
+
+// resizeLocked starts or stops workers until n are running. Stopped workers
+// finish the farm they are on first.
+func (p *workerPool) resizeLocked(n int) {
+	for len(p.workers) < n {
+		p.nextID++
+		w := &worker{id: p.nextID, stop: make(chan struct{}), started: time.Now(), since: time.Now()}
+		p.workers = append(p.workers, w)
+		go p.run(w)
+	}
+	for len(p.workers) > n {
+		last := len(p.workers) - 1
+		close(p.workers[last].stop)
+		p.workers = p.workers[:last]
+	}
+}
+
+func (p *workerPool) run(w *worker) {
+	if probes != nil {
+		probes.workerStarted()
+		defer probes.workerStopped()
+	}
+	for {
+		req, ok := p.queue.pop(w.stop)
+		if !ok {
+			return
+		}
+		w.setState(workerFetching, req.FarmID)
+		err := processFarmID(withWorker(context.Background(), w), req, p.statsQueue)
+		w.finished(err)
+		p.record(time.Now())
+	}
+}
+
+// record counts a processed farm towards the pool's throughput.
+func (p *workerPool) record(at time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recent = append(p.recent, at)
+	p.trimLocked(at)
+}
+
+func (p *workerPool) trimLocked(now time.Time) {
+	i := 0
+	for i < len(p.recent) && now.Sub(p.recent[i]) > throughputWindow {
+		i++
+	}
+	p.recent = p.recent[i:]
+}
+
+// throughput is the farms processed in the last minute.
+func (p *workerPool) throughput() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.trimLocked(time.Now())
+	return len(p.recent)
+}
+
+// infos reports every running worker.
+func (p *workerPool) infos() []workerInfo {
+	p.mu.Lock()
+	workers := append([]*worker(nil), p.workers...)
+	p.mu.Unlock()
+	infos := make([]workerInfo, len(workers))
+	for i, w := range workers {
+		infos[i] = w.info()
+	}
+	return infos
+}
+
+func (p *workerPool) String() string {
+	min, max, pinned := p.bounds()
+	mode := fmt.Sprintf("autoscaling %d-%d", min, max)
+	if pinned {
+		mode = "pinned"
+	}
+	infos := p.infos()
+	lines := []string{fmt.Sprintf("%d workers (%s), %d farms in the last minute", len(infos), mode, p.throughput())}
+	for _, info := range infos {
+		lines = append(lines, info.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// activeWorkers is how many workers are taking farms from the queue.
+func activeWorkers() int {
+	if workers == nil {
+		return defaultMinWorkers
+	}
+	if n := workers.size(); n > 0 {
+		return n
+	}
+	return 1
+}
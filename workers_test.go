@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWorkerPool(t *testing.T) {
+	Convey("Pool bounds are checked", t, func() {
+		_, err := newWorkerPool(newFarmQueue(1), nil, workersConfig{Min: 0, Max: 2})
+		So(err, ShouldNotBeNil)
+		_, err = newWorkerPool(newFarmQueue(1), nil, workersConfig{Min: 3, Max: 2})
+		So(err, ShouldNotBeNil)
+		_, err = newWorkerPool(newFarmQueue(1), nil, workersConfig{Min: 1, Max: maxWorkers + 1})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Given a pool of 2 to 4 workers", t, func() {
+		p, err := newWorkerPool(newFarmQueue(10), make(chan farmResult, 10), workersConfig{Min: 2, Max: 4})
+		So(err, ShouldBeNil)
+		p.mu.Lock()
+		p.resizeLocked(2)
+		p.mu.Unlock()
+		defer func() {
+			p.mu.Lock()
+			p.resizeLocked(0)
+			p.mu.Unlock()
+		}()
+
+		Convey("it grows while every worker is busy and farms wait", func() {
+			So(p.desired(5, 2, time.Second), ShouldEqual, 3)
+			So(p.desired(5, 1, time.Second), ShouldEqual, 2)
+		})
+
+		Convey("it shrinks when upload.farm is slow, but not below min", func() {
+			p.mu.Lock()
+			p.resizeLocked(3)
+			p.mu.Unlock()
+			So(p.desired(5, 3, 10*time.Second), ShouldEqual, 2)
+			So(p.desired(0, 0, time.Second), ShouldEqual, 2)
+		})
+
+		Convey("it never grows past max", func() {
+			p.mu.Lock()
+			p.resizeLocked(4)
+			p.mu.Unlock()
+			So(p.desired(50, 4, time.Second), ShouldEqual, 4)
+		})
+
+		Convey("a pinned pool keeps its size until it autoscales again", func() {
+			So(p.resize(6), ShouldBeNil)
+			So(p.size(), ShouldEqual, 6)
+			p.scale()
+			So(p.size(), ShouldEqual, 6)
+			So(p.String(), ShouldStartWith, "6 workers (pinned)")
+			So(p.resize(0), ShouldNotBeNil)
+
+			p.autoscale()
+			So(p.size(), ShouldEqual, 4)
+			So(p.String(), ShouldStartWith, "4 workers (autoscaling 2-4)")
+		})
+
+		Convey("workers scrape queued farms and report what they did", func() {
+			abigail := 5
+			srv := farmPage(&abigail)
+			defer srv.Close()
+			farmBaseURL = srv.URL
+			defer func() { farmBaseURL = "https://upload.farm" }()
+			allFarms.stats = map[string]svStats{}
+
+			enqueue(context.Background(), p.queue, laneInteractive, "1AAAAA")
+			select {
+			case result := <-p.statsQueue:
+				So(result.Stats.Abigail, ShouldEqual, 5)
+			case <-time.After(time.Second):
+				So("no farm scraped", ShouldBeEmpty)
+			}
+
+			var processed int
+			for i := 0; i < 100; i++ {
+				processed = 0
+				for _, info := range p.infos() {
+					processed += info.Processed
+				}
+				if processed == 1 {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			So(processed, ShouldEqual, 1)
+			So(p.throughput(), ShouldEqual, 1)
+			So(p.infos()[0].State, ShouldEqual, workerIdle)
+		})
+	})
+
+	Convey("Code processing a farm reports its worker's state", t, func() {
+		w := &worker{id: 1}
+		ctx := withWorker(context.Background(), w)
+		setWorkerState(ctx, workerParsing, "1AAAAA")
+		So(w.info().State, ShouldEqual, workerParsing)
+		So(w.info().String(), ShouldStartWith, "worker 1: parsing 1AAAAA for ")
+		w.finished(nil)
+		So(w.info().State, ShouldEqual, workerIdle)
+		So(w.info().Processed, ShouldEqual, 1)
+
+		// without a worker there is nothing to report to
+		setWorkerState(context.Background(), workerFetching, "1AAAAA")
+	})
+}